@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/settings"
+	"os"
+)
+
+// loadAppServices builds the same services as the start command for one-shot commands
+func loadAppServices() (*settings.AppSettings, *services.AppServices, error) {
+	os.Setenv("TZ", "Asia/Ho_Chi_Minh")
+	appSettings := settings.NewAppSettings()
+	if appSettings == nil {
+		return nil, nil, errors.New("error config")
+	}
+	mysqlDB := settings.NewSQLDB(appSettings.Cfgs)
+	repositories := repositories.NewRepositories(mysqlDB, settings.NewLogger())
+	rdbCache := settings.NewConnectRedis(appSettings.Cfgs.RedisAddr, "", 0)
+	return appSettings, services.NewAppServices(appSettings, repositories, rdbCache), nil
+}
+
+func printJSON(data interface{}) {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		fmt.Println(data)
+		return
+	}
+	fmt.Println(string(content))
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var collectionsCmd = &cobra.Command{
+	Use:   "collections",
+	Short: "Manage Typesense collections from the schema registry",
+	Long:  `collections list|describe|create|update|diff|drop`,
+}
+
+var collectionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered and existing collections",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		result, err := appSvcs.EventCollectionService.ListCollections()
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+var collectionsDescribeCmd = &cobra.Command{
+	Use:   "describe [name]",
+	Short: "Show a collection as Typesense has it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		result, err := appSvcs.EventCollectionService.DescribeCollection(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+var collectionsCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a registered collection on Typesense",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		result, err := appSvcs.EventCollectionService.CreateCollection(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+var collectionsUpdateCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Patch the fields of a collection to match the registry",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		result, err := appSvcs.EventCollectionService.UpdateCollection(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+var collectionsDiffCmd = &cobra.Command{
+	Use:   "diff [name]",
+	Short: "Compare a registered collection with Typesense",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		result, err := appSvcs.EventCollectionService.DiffCollection(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+var collectionsDropCmd = &cobra.Command{
+	Use:   "drop [name]",
+	Short: "Drop a collection and all of its documents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			return errors.New("drop deletes every document of the collection, run again with --yes")
+		}
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		if err := appSvcs.EventCollectionService.DropCollection(args[0], force); err != nil {
+			return err
+		}
+		fmt.Println("Dropped", args[0])
+		return nil
+	},
+}
+
+func init() {
+	collectionsDropCmd.Flags().Bool("yes", false, "Confirm the drop")
+	collectionsDropCmd.Flags().Bool("force", false, "Drop an alias, a live collection or a version kept for rollback")
+	collectionsCmd.AddCommand(collectionsListCmd, collectionsDescribeCmd, collectionsCreateCmd,
+		collectionsUpdateCmd, collectionsDiffCmd, collectionsDropCmd)
+	rootCmd.AddCommand(collectionsCmd)
+}
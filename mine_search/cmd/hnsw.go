@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/embedding"
+	"mine/internal/engines/hnsw"
+	"mine/internal/models"
+	"mine/internal/services/backend"
+	"mine/internal/settings"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var hnswCmd = &cobra.Command{
+	Use:   "hnsw",
+	Short: "Build, query and tune the in-process HNSW graphs of the hnsw backend",
+	Long:  `hnsw build|search|recall`,
+}
+
+// loadHNSWBackend needs the settings and the embedder only, the hnsw backend runs without Typesense
+func loadHNSWBackend() (*backend.HNSWBackend, error) {
+	os.Setenv("TZ", "Asia/Ho_Chi_Minh")
+	appSettings := settings.NewAppSettings()
+	if appSettings == nil {
+		return nil, errors.New("error config")
+	}
+	embedder, err := embedding.New(appSettings)
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewHNSWBackend(appSettings, embedder), nil
+}
+
+var hnswBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Index the jsonl file of a collection and write the graph HNSW_DATA_DIR loads at startup",
+	Long:  `hnsw build --collection books --dir ./data, reads ./data/books.jsonl and writes ./data/books.hnsw`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		dir, _ := cmd.Flags().GetString("dir")
+		vectors, err := loadHNSWBackend()
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		if err := vectors.LoadDir(dir); err != nil {
+			return err
+		}
+		graph, err := vectors.Graph(collection)
+		if err != nil {
+			return err
+		}
+		built := time.Since(start)
+		path, err := vectors.SaveGraph(dir, collection)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d vectors indexed in %s, written to %s\n", graph.Len(), built.Round(time.Millisecond), path)
+		return nil
+	},
+}
+
+var hnswSearchCmd = &cobra.Command{
+	Use:   "search [text]",
+	Short: "Search the graphs loaded from a data directory, as the hnsw backend would",
+	Long:  `hnsw search "wizard school" --collection books --dir ./data [--k 10] [--page 1] [--per-page 10]`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		dir, _ := cmd.Flags().GetString("dir")
+		k, _ := cmd.Flags().GetInt("k")
+		page, _ := cmd.Flags().GetInt("page")
+		perPage, _ := cmd.Flags().GetInt("per-page")
+		vectors, err := loadHNSWBackend()
+		if err != nil {
+			return err
+		}
+		if err := vectors.LoadDir(dir); err != nil {
+			return err
+		}
+		result, err := vectors.SearchVector(models.VectorSearch{Collection: collection, Text: args[0], K: k, Page: page, PerPage: perPage})
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+var hnswRecallCmd = &cobra.Command{
+	Use:   "recall",
+	Short: "Measure the recall of a graph against brute force on a generated dataset",
+	Long:  `hnsw recall [--vectors 10000] [--dim 64] [--clusters 50] [--queries 200] [--k 10] [--m 16] [--ef-construction 200] [--ef-search 16,32,64,128] [--metric cosine] [--seed 1]`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		count, _ := cmd.Flags().GetInt("vectors")
+		dimensions, _ := cmd.Flags().GetInt("dim")
+		clusters, _ := cmd.Flags().GetInt("clusters")
+		queries, _ := cmd.Flags().GetInt("queries")
+		k, _ := cmd.Flags().GetInt("k")
+		m, _ := cmd.Flags().GetInt("m")
+		efConstruction, _ := cmd.Flags().GetInt("ef-construction")
+		efSearchList, _ := cmd.Flags().GetString("ef-search")
+		metric, _ := cmd.Flags().GetString("metric")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		efSearch := []int{}
+		for _, item := range strings.Split(efSearchList, ",") {
+			ef, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil || ef <= 0 {
+				return fmt.Errorf("ef-search must list positive numbers, got %q", item)
+			}
+			efSearch = append(efSearch, ef)
+		}
+		config := hnsw.Config{Dimensions: dimensions, M: m, EfConstruction: efConstruction, Metric: metric, Seed: seed}
+		start := time.Now()
+		graph, err := hnsw.Build(config, hnsw.GenerateVectors(count, dimensions, clusters, seed))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d vectors of %d dimensions indexed in %s\n", graph.Len(), dimensions, time.Since(start).Round(time.Millisecond))
+		// the queries are drawn from the same clusters with another seed, they are not in the graph
+		reports, err := graph.MeasureRecall(hnsw.GenerateVectors(queries, dimensions, clusters, seed+1), k, efSearch)
+		if err != nil {
+			return err
+		}
+		printJSON(reports)
+		return nil
+	},
+}
+
+func init() {
+	hnswBuildCmd.Flags().String("collection", "", "Registered collection name")
+	hnswBuildCmd.Flags().String("dir", ".", "Directory holding the jsonl file, the graph is written next to it")
+	hnswBuildCmd.MarkFlagRequired("collection")
+	hnswSearchCmd.Flags().String("collection", "", "Registered collection name, books by default")
+	hnswSearchCmd.Flags().String("dir", ".", "Directory holding the graph and jsonl files")
+	hnswSearchCmd.Flags().Int("k", 0, "Neighbors searched, page·per-page by default")
+	hnswSearchCmd.Flags().Int("page", 0, "Page to return")
+	hnswSearchCmd.Flags().Int("per-page", 0, "Hits per page")
+	hnswRecallCmd.Flags().Int("vectors", 10000, "Vectors indexed")
+	hnswRecallCmd.Flags().Int("dim", 64, "Dimensions of the vectors")
+	hnswRecallCmd.Flags().Int("clusters", 50, "Clusters the vectors are drawn around")
+	hnswRecallCmd.Flags().Int("queries", 200, "Queries compared to brute force")
+	hnswRecallCmd.Flags().Int("k", 10, "Neighbors per query")
+	hnswRecallCmd.Flags().Int("m", hnsw.DefaultM, "Neighbors per node")
+	hnswRecallCmd.Flags().Int("ef-construction", hnsw.DefaultEfConstruction, "Candidate list while inserting")
+	hnswRecallCmd.Flags().String("ef-search", "16,32,64,128", "Comma separated candidate lists measured while searching")
+	hnswRecallCmd.Flags().String("metric", hnsw.DefaultMetric, "cosine, dot or l2")
+	hnswRecallCmd.Flags().Int64("seed", 1, "Seed of the dataset and of the graph")
+	hnswCmd.AddCommand(hnswBuildCmd, hnswSearchCmd, hnswRecallCmd)
+	rootCmd.AddCommand(hnswCmd)
+}
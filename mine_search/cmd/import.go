@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/services/importer"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// importState is saved after every batch so an interrupted import can continue with --resume
+type importState struct {
+	File      string              `json:"file"`
+	Report    models.ImportReport `json:"report"`
+	UpdatedAt string              `json:"updated_at"`
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Stream a jsonl file into a Typesense collection",
+	Long:  `import --collection books --file ../typesense/books.jsonl [--action upsert] [--batch-size 500] [--resume]`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		filePath, _ := cmd.Flags().GetString("file")
+		action, _ := cmd.Flags().GetString("action")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		resume, _ := cmd.Flags().GetBool("resume")
+		statePath, _ := cmd.Flags().GetString("state")
+		errorsPath, _ := cmd.Flags().GetString("errors")
+		if statePath == "" {
+			statePath = filePath + ".import-state.json"
+		}
+		if errorsPath == "" {
+			errorsPath = filePath + ".import-errors.jsonl"
+		}
+
+		previous := models.ImportReport{}
+		if resume {
+			state, err := readImportState(statePath)
+			if err != nil {
+				return err
+			}
+			if state.Report.Collection != collection || state.File != filePath {
+				return fmt.Errorf("state %s belongs to %s -> %s", statePath, state.File, state.Report.Collection)
+			}
+			if state.Report.Done {
+				fmt.Println("Import already done:", statePath)
+				return nil
+			}
+			previous = state.Report
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		errorFlags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if resume {
+			errorFlags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		errorFile, err := os.OpenFile(errorsPath, errorFlags, 0644)
+		if err != nil {
+			return err
+		}
+		defer errorFile.Close()
+
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		options := models.ImportOptions{
+			Collection: collection,
+			Action:     action,
+			BatchSize:  batchSize,
+			SkipLines:  previous.LastLine,
+		}
+		startTime := time.Now()
+		progress := func(report models.ImportReport) {
+			report = mergeImportReport(previous, report)
+			fmt.Printf("\r%s line %d: %d succeeded, %d failed (%.1fs)", collection, report.LastLine, report.Succeeded, report.Failed, time.Since(startTime).Seconds())
+			if err := writeImportState(statePath, filePath, report); err != nil {
+				fmt.Println("\nCannot save import state:", err)
+			}
+		}
+		report, err := appSvcs.EventImportService.ImportJSONL(file, options, errorFile, progress)
+		fmt.Println()
+		lastLine := options.SkipLines
+		if report != nil {
+			lastLine = report.LastLine
+			merged := mergeImportReport(previous, *report)
+			if errState := writeImportState(statePath, filePath, merged); errState != nil {
+				fmt.Println("Cannot save import state:", errState)
+			}
+			merged.Errors = nil
+			printJSON(merged)
+			if merged.Failed > 0 {
+				fmt.Println("Failed lines are written to", errorsPath)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%w, run again with --resume to continue after line %d", err, lastLine)
+		}
+		return nil
+	},
+}
+
+func mergeImportReport(previous, current models.ImportReport) models.ImportReport {
+	current.Succeeded += previous.Succeeded
+	current.Failed += previous.Failed
+	current.Chunks += previous.Chunks
+	current.ChunksFailed += previous.ChunksFailed
+	current.Skipped = previous.Skipped
+	return current
+}
+
+func readImportState(path string) (*importState, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no import state at %s, nothing to resume", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &importState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func writeImportState(path, filePath string, report models.ImportReport) error {
+	report.Errors = nil
+	content, err := json.MarshalIndent(importState{
+		File:      filePath,
+		Report:    report,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+func init() {
+	importCmd.Flags().String("collection", "", "Target collection or alias")
+	importCmd.Flags().String("file", "", "jsonl file, one document per line")
+	importCmd.Flags().String("action", models.ImportActionUpsert, "create, upsert or update")
+	importCmd.Flags().Int("batch-size", importer.DefaultBatchSize, "Documents per import call")
+	importCmd.Flags().Bool("resume", false, "Continue after the last line of the saved state")
+	importCmd.Flags().String("state", "", "State file, default <file>.import-state.json")
+	importCmd.Flags().String("errors", "", "Error report, default <file>.import-errors.jsonl")
+	importCmd.MarkFlagRequired("collection")
+	importCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(importCmd)
+}
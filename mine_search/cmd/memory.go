@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/engines/bm25"
+	"mine/internal/models"
+	"mine/internal/services/backend"
+	"mine/internal/settings"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Build and query the in-process BM25 indexes of the memory backend",
+	Long:  `memory snapshot|search`,
+}
+
+// loadMemoryBackend needs the settings only, the memory backend runs without MySQL, Typesense or Redis
+func loadMemoryBackend() (*backend.MemoryBackend, error) {
+	os.Setenv("TZ", "Asia/Ho_Chi_Minh")
+	appSettings := settings.NewAppSettings()
+	if appSettings == nil {
+		return nil, errors.New("error config")
+	}
+	return backend.NewMemoryBackend(appSettings), nil
+}
+
+var memorySnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Index a jsonl file and write the snapshot MEMORY_DATA_DIR loads at startup",
+	Long:  `memory snapshot --collection books --file ../typesense/books.jsonl --dir ./data`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		filePath, _ := cmd.Flags().GetString("file")
+		dir, _ := cmd.Flags().GetString("dir")
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		memory, err := loadMemoryBackend()
+		if err != nil {
+			return err
+		}
+		index, err := memory.Collection(collection)
+		if err != nil {
+			return err
+		}
+		report, err := index.LoadJSONL(file)
+		printJSON(report)
+		if err != nil {
+			return err
+		}
+		path, err := memory.SaveSnapshot(dir, collection)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d documents written to %s\n", index.Len(), path)
+		return nil
+	},
+}
+
+var memorySearchCmd = &cobra.Command{
+	Use:   "search [text]",
+	Short: "Search the collections loaded from a data directory, as the memory backend would",
+	Long:  `memory search "harry potter" --collection books --dir ./data [--page 1] [--per-page 10]`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		dir, _ := cmd.Flags().GetString("dir")
+		page, _ := cmd.Flags().GetInt("page")
+		perPage, _ := cmd.Flags().GetInt("per-page")
+		memory, err := loadMemoryBackend()
+		if err != nil {
+			return err
+		}
+		if err := memory.LoadDir(dir); err != nil {
+			return err
+		}
+		result, err := memory.Search(models.Search{Collection: collection, Text: args[0], Page: page, PerPage: perPage})
+		if err != nil {
+			return err
+		}
+		printJSON(result)
+		return nil
+	},
+}
+
+func init() {
+	memorySnapshotCmd.Flags().String("collection", "", "Registered collection name")
+	memorySnapshotCmd.Flags().String("file", "", "Path of the jsonl file")
+	memorySnapshotCmd.Flags().String("dir", ".", "Directory the snapshot is written to, "+bm25.SnapshotSuffix+" is appended to the collection")
+	memorySnapshotCmd.MarkFlagRequired("collection")
+	memorySnapshotCmd.MarkFlagRequired("file")
+	memorySearchCmd.Flags().String("collection", "", "Registered collection name, books by default")
+	memorySearchCmd.Flags().String("dir", ".", "Directory holding the snapshot or jsonl files")
+	memorySearchCmd.Flags().Int("page", 0, "Page to return")
+	memorySearchCmd.Flags().Int("per-page", 0, "Hits per page")
+	memoryCmd.AddCommand(memorySnapshotCmd, memorySearchCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
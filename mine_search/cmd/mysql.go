@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/query_builder"
+	"mine/internal/repositories"
+	"mine/internal/services/backend"
+	"mine/internal/settings"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var mysqlCmd = &cobra.Command{
+	Use:   "mysql",
+	Short: "Manage the MySQL tables searched by the mysql backend",
+	Long:  `mysql create-index`,
+}
+
+var mysqlCreateIndexCmd = &cobra.Command{
+	Use:   "create-index [name]",
+	Short: "Add the FULLTEXT index on the query_by columns of the table named like a registered collection",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		os.Setenv("TZ", "Asia/Ho_Chi_Minh")
+		appSettings := settings.NewAppSettings()
+		if appSettings == nil {
+			return errors.New("error config")
+		}
+		repo := repositories.NewRepositories(settings.NewSQLDB(appSettings.Cfgs), settings.NewLogger())
+		created, err := backend.NewMySQLBackend(appSettings, repo).CreateFullTextIndex(args[0])
+		if err != nil {
+			return err
+		}
+		if !created {
+			fmt.Printf("index %s already exists\n", query_builder.MySQLFullTextIndexName(args[0]))
+			return nil
+		}
+		fmt.Printf("index %s created\n", query_builder.MySQLFullTextIndexName(args[0]))
+		return nil
+	},
+}
+
+func init() {
+	mysqlCmd.AddCommand(mysqlCreateIndexCmd)
+	rootCmd.AddCommand(mysqlCmd)
+}
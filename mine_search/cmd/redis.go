@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var redisCmd = &cobra.Command{
+	Use:   "redis",
+	Short: "Manage the RediSearch indexes and geo sets behind the redis routes",
+	Long:  `redis create-index|drop-index|import|delete`,
+}
+
+var redisCreateIndexCmd = &cobra.Command{
+	Use:   "create-index [name]",
+	Short: "Create the RediSearch index of a registered collection",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		return appSvcs.EventRediSearchService.CreateRedisIndex(args[0])
+	},
+}
+
+var redisDropIndexCmd = &cobra.Command{
+	Use:   "drop-index [name]",
+	Short: "Drop a RediSearch index, --delete-documents removes the hashes too",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			return errors.New("dropping an index needs --yes")
+		}
+		deleteDocuments, _ := cmd.Flags().GetBool("delete-documents")
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		return appSvcs.EventRediSearchService.DropRedisIndex(args[0], deleteDocuments)
+	},
+}
+
+var redisImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Write a jsonl file as hashes of a RediSearch index and into the geo sets",
+	Long:  `redis import --collection books --file ../typesense/books.jsonl [--batch-size 500]`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		filePath, _ := cmd.Flags().GetString("file")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		report, err := appSvcs.EventRediSearchService.ImportRedisJSONL(file, collection, batchSize)
+		if report != nil {
+			printJSON(report)
+		}
+		return err
+	},
+}
+
+var redisDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Remove a document hash and its geo set members",
+	Long:  `redis delete --collection stores --id 42`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, _ := cmd.Flags().GetString("collection")
+		id, _ := cmd.Flags().GetString("id")
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		return appSvcs.EventRediSearchService.DeleteRedisDocument(collection, id)
+	},
+}
+
+func init() {
+	redisDropIndexCmd.Flags().Bool("yes", false, "Confirm the drop")
+	redisDropIndexCmd.Flags().Bool("delete-documents", false, "Delete the document hashes with the index")
+	redisImportCmd.Flags().String("collection", "", "Registered collection name")
+	redisImportCmd.Flags().String("file", "", "Path of the jsonl file")
+	redisImportCmd.Flags().Int("batch-size", 500, "Documents per pipeline")
+	redisImportCmd.MarkFlagRequired("collection")
+	redisImportCmd.MarkFlagRequired("file")
+	redisDeleteCmd.Flags().String("collection", "", "Registered collection name")
+	redisDeleteCmd.Flags().String("id", "", "Document id")
+	redisDeleteCmd.MarkFlagRequired("collection")
+	redisDeleteCmd.MarkFlagRequired("id")
+	redisCmd.AddCommand(redisCreateIndexCmd, redisDropIndexCmd, redisImportCmd, redisDeleteCmd)
+	rootCmd.AddCommand(redisCmd)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"mine/internal/models"
+	"mine/internal/services/importer"
+	"mine/internal/services/reindex"
+
+	"github.com/spf13/cobra"
+)
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Build a new version of a collection and move its alias",
+	Long:  `reindex --collection books --source jsonl|mysql|collection [--file books.jsonl] [--table books] [--sample-query harry]`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := models.ReindexOptions{}
+		options.Collection, _ = cmd.Flags().GetString("collection")
+		options.Source, _ = cmd.Flags().GetString("source")
+		options.File, _ = cmd.Flags().GetString("file")
+		options.Table, _ = cmd.Flags().GetString("table")
+		options.BatchSize, _ = cmd.Flags().GetInt("batch-size")
+		options.MinDocumentRatio, _ = cmd.Flags().GetFloat64("min-ratio")
+		options.SampleQueries, _ = cmd.Flags().GetStringSlice("sample-query")
+		options.Adopt, _ = cmd.Flags().GetBool("adopt")
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		if err := models.Validate.Struct(&options); err != nil {
+			return err
+		}
+		report, err := appSvcs.EventReindexService.Reindex(options)
+		if report != nil {
+			if report.Import != nil {
+				report.Import.Errors = nil
+			}
+			printJSON(report)
+		}
+		return err
+	},
+}
+
+var reindexRollbackCmd = &cobra.Command{
+	Use:   "rollback [collection]",
+	Short: "Point the alias back at the previous version",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		alias, err := appSvcs.EventReindexService.Rollback(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(alias)
+		return nil
+	},
+}
+
+var reindexAdoptCmd = &cobra.Command{
+	Use:   "adopt [collection]",
+	Short: "Replace a plain collection by an alias of its newest version, search fails until the alias is set",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		alias, err := appSvcs.EventReindexService.Adopt(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(alias)
+		return nil
+	},
+}
+
+var reindexVersionsCmd = &cobra.Command{
+	Use:   "versions [collection]",
+	Short: "List the versions of a collection and the live one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, appSvcs, err := loadAppServices()
+		if err != nil {
+			return err
+		}
+		versions, err := appSvcs.EventReindexService.ListVersions(args[0])
+		if err != nil {
+			return err
+		}
+		printJSON(versions)
+		return nil
+	},
+}
+
+func init() {
+	reindexCmd.Flags().String("collection", "", "Registered collection, served through an alias of the same name")
+	reindexCmd.Flags().String("source", models.ReindexSourceJSONL, "jsonl, mysql or collection (copy of the live version)")
+	reindexCmd.Flags().String("file", "", "jsonl file of the jsonl source")
+	reindexCmd.Flags().String("table", "", "Table of the mysql source")
+	reindexCmd.Flags().Int("batch-size", importer.DefaultBatchSize, "Documents per import call")
+	reindexCmd.Flags().Float64("min-ratio", reindex.DefaultMinDocumentRatio, "Lowest accepted document count compared to the live version")
+	reindexCmd.Flags().StringSlice("sample-query", nil, "Query that must find documents in the new version, repeatable")
+	reindexCmd.Flags().Bool("adopt", false, "Build the first version of a plain collection named like the alias, see reindex adopt")
+	reindexCmd.MarkFlagRequired("collection")
+	reindexCmd.AddCommand(reindexAdoptCmd, reindexRollbackCmd, reindexVersionsCmd)
+	rootCmd.AddCommand(reindexCmd)
+}
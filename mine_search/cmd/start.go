@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"mine/delivery"
 	"mine/internal"
+	"mine/internal/engines/bloom"
 	"mine/internal/repositories"
 	"mine/internal/services"
 	"mine/internal/settings"
@@ -38,10 +39,10 @@ var startCmd = &cobra.Command{
 		os.Setenv("TZ", "Asia/Ho_Chi_Minh")
 		// app setting
 		appSettings := settings.NewAppSettings()
-		mysqlDB := settings.NewSQLDB(appSettings.Cfgs)
-		repositories := repositories.NewRepositories(mysqlDB, settings.NewLogger())
-		rdbCache := settings.NewConnectRedis("", "", 0)
 		if appSettings != nil {
+			mysqlDB := settings.NewSQLDB(appSettings.Cfgs)
+			repositories := repositories.NewRepositories(mysqlDB, settings.NewLogger())
+			rdbCache := settings.NewConnectRedis(appSettings.Cfgs.RedisAddr, "", 0)
 			fmt.Println("INIT SERVICE...")
 			// Todo: Init service
 			// Todo: Compose into one app service
@@ -49,7 +50,12 @@ var startCmd = &cobra.Command{
 			// Todo: Init handler
 			appHandler := delivery.NewAppHandlers(appSettings, appSvcs, rdbCache, repositories)
 			// ticketHandler := delivery.NewTicketHandlers(appSettings, appSvcs, repositories)
-			AppServer := fiber.New()
+			AppServer := fiber.New(fiber.Config{
+				// bodies over the 4MB default are streamed for the jsonl imports, LimitBody rejects them elsewhere.
+				// The multipart forms are parsed by the handlers, spilling their files to disk
+				StreamRequestBody:            true,
+				DisablePreParseMultipartForm: true,
+			})
 			AppServer.Use(cors.New(cors.Config{
 				AllowOrigins: "*",
 			}))
@@ -57,21 +63,64 @@ var startCmd = &cobra.Command{
 				Format:     "${method} - ${path} - header:${reqHeaders} - body:${body} - resp-status:${status} - resp_body:${resBody}\n\n",
 				TimeFormat: "2006-01-02 15:04:05",
 				TimeZone:   "Asia/Ho_Chi_Minh",
+				CustomTags: map[string]logger.LogFunc{
+					// an import body still streamed is not read back to be logged
+					logger.TagBody: func(output logger.Buffer, c *fiber.Ctx, data *logger.Data, extraParam string) (int, error) {
+						if c.Request().IsBodyStream() {
+							return output.WriteString("-")
+						}
+						return output.Write(c.Body())
+					},
+				},
 			}))
 			fmt.Println("Start Schedule...")
 			Schedule(appSettings, appSvcs)
 			fmt.Println("INIT ROUTE")
+			// the import is routed before LimitBody, it reads its body as a stream
+			AppServer.Post("/mine/v1/local/typesense/collections/:name/import", appHandler.RequireTokenLocal, appHandler.ImportDocumentsHandler)
+			AppServer.Use(appHandler.LimitBody(fiber.DefaultBodyLimit))
 			AppServer.Get("/mine/health", appHandler.Health)
 			AppServer.Post("/mine/v1/public/sample", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.SampleGroupAPIs)
-			// search text
-			AppServer.Post("/mine/v1/public/typesense/text-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.SampleGroupAPIs)
-			AppServer.Post("/mine/v1/public/redis/text-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.SampleGroupAPIs)
+			// search text, the generic route reads the engine from the backend field
+			AppServer.Post("/mine/v1/public/text-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.TextSearchHandler)
+			AppServer.Post("/mine/v1/public/typesense/text-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.TypeSenseTextSearchHandler)
+			AppServer.Post("/mine/v1/public/redis/text-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.RedisTextSearchHandler)
 			// search location
-			AppServer.Post("/mine/v1/public/typesense/location-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.SampleGroupAPIs)
-			AppServer.Post("/mine/v1/public/redis/location-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.SampleGroupAPIs)
+			AppServer.Post("/mine/v1/public/location-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.LocationSearchHandler)
+			AppServer.Post("/mine/v1/public/typesense/location-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.TypeSenseLocationSearchHandler)
+			AppServer.Post("/mine/v1/public/redis/location-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.RedisLocationSearchHandler)
+			AppServer.Get("/mine/v1/local/backends", appHandler.RequireTokenLocal, appHandler.ListBackendsHandler)
+			// collections
+			AppServer.Get("/mine/v1/local/typesense/collections", appHandler.RequireTokenLocal, appHandler.ListCollectionsHandler)
+			AppServer.Get("/mine/v1/local/typesense/collections/:name", appHandler.RequireTokenLocal, appHandler.DescribeCollectionHandler)
+			AppServer.Get("/mine/v1/local/typesense/collections/:name/diff", appHandler.RequireTokenLocal, appHandler.DiffCollectionHandler)
+			AppServer.Post("/mine/v1/local/typesense/collections/:name", appHandler.RequireTokenLocal, appHandler.CreateCollectionHandler)
+			AppServer.Patch("/mine/v1/local/typesense/collections/:name", appHandler.RequireTokenLocal, appHandler.UpdateCollectionHandler)
+			AppServer.Delete("/mine/v1/local/typesense/collections/:name", appHandler.RequireTokenLocal, appHandler.DropCollectionHandler)
+			AppServer.Get("/mine/v1/local/typesense/collections/:name/versions", appHandler.RequireTokenLocal, appHandler.ListVersionsHandler)
+			AppServer.Post("/mine/v1/local/typesense/collections/:name/reindex", appHandler.RequireTokenLocal, appHandler.ReindexHandler)
+			AppServer.Post("/mine/v1/local/typesense/collections/:name/adopt", appHandler.RequireTokenLocal, appHandler.AdoptHandler)
+			AppServer.Post("/mine/v1/local/typesense/collections/:name/rollback", appHandler.RequireTokenLocal, appHandler.RollbackHandler)
 			// blooming filter
+			AppServer.Get("/mine/v1/local/bloom", appHandler.RequireTokenLocal, appHandler.ListBloomsHandler)
+			AppServer.Get("/mine/v1/local/bloom/:name", appHandler.RequireTokenLocal, appHandler.BloomStatsHandler)
+			AppServer.Post("/mine/v1/local/bloom/:name", appHandler.RequireTokenLocal, appHandler.CreateBloomHandler)
+			AppServer.Delete("/mine/v1/local/bloom/:name", appHandler.RequireTokenLocal, appHandler.DropBloomHandler)
+			AppServer.Post("/mine/v1/local/bloom/:name/add", appHandler.RequireTokenLocal, appHandler.AddBloomHandler)
+			AppServer.Post("/mine/v1/local/bloom/:name/add-batch", appHandler.RequireTokenLocal, appHandler.AddBatchBloomHandler)
+			AppServer.Post("/mine/v1/local/bloom/:name/might-contain", appHandler.RequireTokenLocal, appHandler.MightContainHandler)
+			AppServer.Post("/mine/v1/local/bloom/:name/delete", appHandler.RequireTokenLocal, appHandler.DeleteBloomItemsHandler)
+			AppServer.Post("/mine/v1/local/bloom/:name/rebuild", appHandler.RequireTokenLocal, appHandler.RebuildBloomHandler)
 
 			// vector + advanced RAG
+			AppServer.Post("/mine/v1/public/vector-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.VectorSearchHandler)
+			AppServer.Post("/mine/v1/public/hybrid-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.HybridSearchHandler)
+			AppServer.Post("/mine/v1/public/chunk-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.ChunkSearchHandler)
+			AppServer.Post("/mine/v1/public/rerank-search", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.RerankSearchHandler)
+			AppServer.Post("/mine/v1/public/ask", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.AskHandler)
+			AppServer.Post("/mine/v1/public/conversation", appHandler.RequireTokenWeb, appHandler.RateLimit, appHandler.ConverseHandler)
+			AppServer.Post("/mine/v1/public/conversation/history", appHandler.RequireTokenWeb, appHandler.ConversationHistoryHandler)
+			AppServer.Post("/mine/v1/public/conversation/clear", appHandler.RequireTokenWeb, appHandler.ClearConversationHandler)
 
 			fmt.Println("INIT ROUTE SUCCESS")
 			if err := AppServer.Listen(":8386"); err != nil {
@@ -91,6 +140,17 @@ func Schedule(appSettings *settings.AppSettings, appSvcs *services.AppServices)
 	loc, _ := time.LoadLocation("Asia/Ho_Chi_Minh")
 	s := gocron.NewScheduler(loc)
 	// s.Every(30).Seconds().Do(appSvcCallJobOrderHomeService, 30)
+	if appSettings.Cfgs.BloomSnapshotStore != "" {
+		interval := bloom.DefaultSnapshotInterval
+		if appSettings.Cfgs.BloomSnapshotInterval > 0 {
+			interval = time.Duration(appSettings.Cfgs.BloomSnapshotInterval) * time.Second
+		}
+		s.Every(interval).WaitForSchedule().Do(appSvcs.SnapshotBlooms)
+	}
+	if interval := appSettings.Cfgs.BloomRebuildInterval; interval > 0 {
+		// a rebuild slower than the interval is not started twice
+		s.Every(interval).Seconds().WaitForSchedule().SingletonMode().Do(appSvcs.RebuildBlooms)
+	}
 	s.StartAsync()
 	fmt.Println("Start Schedule SUCCESS")
 }
@@ -18,18 +18,33 @@ type AppHandlers interface {
 	// MiddleWare
 	RateLimit(*fiber.Ctx) error
 	CustomRateLimit(max int, expiration time.Duration, typeResponse string) fiber.Handler
+	LimitBody(limit int) fiber.Handler
 	RequireTokenWeb(*fiber.Ctx) error
 	RequireTokenLocal(*fiber.Ctx) error
 	Health(*fiber.Ctx) error
 	FreeOSMemory(*fiber.Ctx) error
 	ReadMemStat(*fiber.Ctx) error
 	EventSampleHandlers
+	EventTypeSenseHandlers
+	EventCollectionHandlers
+	EventRedisHandlers
+	EventSearchHandlers
+	EventBloomHandlers
+	EventRAGHandlers
+	EventConversationHandlers
 }
 type appHandlers struct {
 	stt *settings.AppSettings
 	svc *services.AppServices
 	rdb *redis.Client
 	EventSampleHandlers
+	EventTypeSenseHandlers
+	EventCollectionHandlers
+	EventRedisHandlers
+	EventSearchHandlers
+	EventBloomHandlers
+	EventRAGHandlers
+	EventConversationHandlers
 }
 
 func NewAppHandlers(
@@ -43,6 +58,13 @@ func NewAppHandlers(
 		appService,
 		rdb,
 		NewEventSampleHandlers(appSettings, appService, repo),
+		NewEventTypeSenseHandlers(appSettings, appService, repo),
+		NewEventCollectionHandlers(appSettings, appService, repo),
+		NewEventRedisHandlers(appSettings, appService, repo),
+		NewEventSearchHandlers(appSettings, appService, repo),
+		NewEventBloomHandlers(appSettings, appService, repo),
+		NewEventRAGHandlers(appSettings, appService, repo),
+		NewEventConversationHandlers(appSettings, appService, repo),
 	}
 }
 
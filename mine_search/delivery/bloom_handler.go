@@ -0,0 +1,151 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/engines/bloom"
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/settings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type EventBloomHandlers interface {
+	ListBloomsHandler(*fiber.Ctx) error
+	CreateBloomHandler(*fiber.Ctx) error
+	BloomStatsHandler(*fiber.Ctx) error
+	DropBloomHandler(*fiber.Ctx) error
+	AddBloomHandler(*fiber.Ctx) error
+	AddBatchBloomHandler(*fiber.Ctx) error
+	MightContainHandler(*fiber.Ctx) error
+	DeleteBloomItemsHandler(*fiber.Ctx) error
+	RebuildBloomHandler(*fiber.Ctx) error
+}
+
+type eventBloomHandlers struct {
+	stt  *settings.AppSettings
+	svc  *services.AppServices
+	repo *repositories.Repositories
+}
+
+func NewEventBloomHandlers(
+	appSettings *settings.AppSettings,
+	appService *services.AppServices,
+	repo *repositories.Repositories,
+) EventBloomHandlers {
+	return &eventBloomHandlers{
+		stt:  appSettings,
+		svc:  appService,
+		repo: repo,
+	}
+}
+
+func (tk *eventBloomHandlers) ListBloomsHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventBloomService.ListBlooms()
+	return tk.respond(ctx, "ListBlooms", result, err)
+}
+
+// CreateBloomHandler takes capacity, error_rate and storage (redis by default) in the body
+func (tk *eventBloomHandlers) CreateBloomHandler(ctx *fiber.Ctx) error {
+	dataInput := models.BloomCreate{}
+	if err := tk.parse(ctx, &dataInput, func() { dataInput.Name = ctx.Params("name") }); err != nil {
+		return tk.respond(ctx, "CreateBloom", nil, err)
+	}
+	result, err := tk.svc.EventBloomService.CreateBloom(dataInput)
+	return tk.respond(ctx, "CreateBloom", result, err)
+}
+
+func (tk *eventBloomHandlers) BloomStatsHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventBloomService.BloomStats(ctx.Params("name"))
+	return tk.respond(ctx, "BloomStats", result, err)
+}
+
+func (tk *eventBloomHandlers) DropBloomHandler(ctx *fiber.Ctx) error {
+	err := tk.svc.EventBloomService.DropBloom(ctx.Params("name"))
+	return tk.respond(ctx, "DropBloom", nil, err)
+}
+
+func (tk *eventBloomHandlers) AddBloomHandler(ctx *fiber.Ctx) error {
+	dataInput := models.BloomItem{}
+	if err := tk.parse(ctx, &dataInput, nil); err != nil {
+		return tk.respond(ctx, "AddBloom", nil, err)
+	}
+	result, err := tk.svc.EventBloomService.AddBloom(ctx.Params("name"), []string{dataInput.Item})
+	return tk.respond(ctx, "AddBloom", result, err)
+}
+
+func (tk *eventBloomHandlers) AddBatchBloomHandler(ctx *fiber.Ctx) error {
+	dataInput := models.BloomItems{}
+	if err := tk.parse(ctx, &dataInput, nil); err != nil {
+		return tk.respond(ctx, "AddBatchBloom", nil, err)
+	}
+	result, err := tk.svc.EventBloomService.AddBloom(ctx.Params("name"), dataInput.Items)
+	return tk.respond(ctx, "AddBatchBloom", result, err)
+}
+
+// MightContainHandler answers every item of the body, might_contain false means never added
+func (tk *eventBloomHandlers) MightContainHandler(ctx *fiber.Ctx) error {
+	dataInput := models.BloomItems{}
+	if err := tk.parse(ctx, &dataInput, nil); err != nil {
+		return tk.respond(ctx, "MightContain", nil, err)
+	}
+	result, err := tk.svc.EventBloomService.MightContain(ctx.Params("name"), dataInput.Items)
+	return tk.respond(ctx, "MightContain", result, err)
+}
+
+// DeleteBloomItemsHandler removes items from a cuckoo filter
+func (tk *eventBloomHandlers) DeleteBloomItemsHandler(ctx *fiber.Ctx) error {
+	dataInput := models.BloomItems{}
+	if err := tk.parse(ctx, &dataInput, nil); err != nil {
+		return tk.respond(ctx, "DeleteBloomItems", nil, err)
+	}
+	result, err := tk.svc.EventBloomService.DeleteBloom(ctx.Params("name"), dataInput.Items)
+	return tk.respond(ctx, "DeleteBloomItems", result, err)
+}
+
+// RebuildBloomHandler refills the filter from the export of the collection it was created with
+func (tk *eventBloomHandlers) RebuildBloomHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventBloomService.RebuildBloom(ctx.Params("name"))
+	return tk.respond(ctx, "RebuildBloom", result, err)
+}
+
+// parse reads and validates the body, fill sets the fields taken from the path before validation
+func (tk *eventBloomHandlers) parse(ctx *fiber.Ctx, dataInput interface{}, fill func()) error {
+	err := ctx.BodyParser(dataInput)
+	if fill != nil {
+		fill()
+	}
+	if err == nil {
+		err = models.Validate.Struct(dataInput)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", bloom.ErrInvalid, err)
+	}
+	return nil
+}
+
+func (tk *eventBloomHandlers) respond(ctx *fiber.Ctx, funcName string, data interface{}, err error) error {
+	if err == nil {
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: 1,
+			Message:    "Ok",
+			Data:       data,
+		})
+	}
+	tk.stt.Log.Error(funcName, zap.Any("name", ctx.Params("name")), zap.Error(err))
+	if errors.Is(err, bloom.ErrInvalid) || errors.Is(err, bloom.ErrNotFound) || errors.Is(err, bloom.ErrExists) || errors.Is(err, bloom.ErrNoDelete) {
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: tk.stt.ErrMsgs.Params.Code,
+			Message:    tk.stt.ErrMsgs.Params.Msg,
+			Data:       err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+		StatusCode: tk.stt.ErrMsgs.CallFail.Code,
+		Message:    tk.stt.ErrMsgs.CallFail.Msg,
+		Data:       err.Error(),
+	})
+}
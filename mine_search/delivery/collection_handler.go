@@ -0,0 +1,189 @@
+package delivery
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/services/collection"
+	"mine/internal/services/importer"
+	"mine/internal/settings"
+	utilsCall "mine/internal/utils_call"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type EventCollectionHandlers interface {
+	ListCollectionsHandler(*fiber.Ctx) error
+	DescribeCollectionHandler(*fiber.Ctx) error
+	CreateCollectionHandler(*fiber.Ctx) error
+	UpdateCollectionHandler(*fiber.Ctx) error
+	DiffCollectionHandler(*fiber.Ctx) error
+	DropCollectionHandler(*fiber.Ctx) error
+	ImportDocumentsHandler(*fiber.Ctx) error
+	ReindexHandler(*fiber.Ctx) error
+	AdoptHandler(*fiber.Ctx) error
+	RollbackHandler(*fiber.Ctx) error
+	ListVersionsHandler(*fiber.Ctx) error
+}
+
+type eventCollectionHandlers struct {
+	stt  *settings.AppSettings
+	svc  *services.AppServices
+	repo *repositories.Repositories
+}
+
+func NewEventCollectionHandlers(
+	appSettings *settings.AppSettings,
+	appService *services.AppServices,
+	repo *repositories.Repositories,
+) EventCollectionHandlers {
+	return &eventCollectionHandlers{
+		stt:  appSettings,
+		svc:  appService,
+		repo: repo,
+	}
+}
+
+func (tk *eventCollectionHandlers) ListCollectionsHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventCollectionService.ListCollections()
+	return tk.respond(ctx, "ListCollections", result, err)
+}
+
+func (tk *eventCollectionHandlers) DescribeCollectionHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventCollectionService.DescribeCollection(ctx.Params("name"))
+	return tk.respond(ctx, "DescribeCollection", result, err)
+}
+
+func (tk *eventCollectionHandlers) CreateCollectionHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventCollectionService.CreateCollection(ctx.Params("name"))
+	return tk.respond(ctx, "CreateCollection", result, err)
+}
+
+func (tk *eventCollectionHandlers) UpdateCollectionHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventCollectionService.UpdateCollection(ctx.Params("name"))
+	return tk.respond(ctx, "UpdateCollection", result, err)
+}
+
+func (tk *eventCollectionHandlers) DiffCollectionHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventCollectionService.DiffCollection(ctx.Params("name"))
+	return tk.respond(ctx, "DiffCollection", result, err)
+}
+
+// DropCollectionHandler drops a collection, query param force=true drops an alias, a live collection
+// or a version kept for rollback
+func (tk *eventCollectionHandlers) DropCollectionHandler(ctx *fiber.Ctx) error {
+	err := tk.svc.EventCollectionService.DropCollection(ctx.Params("name"), ctx.QueryBool("force", false))
+	return tk.respond(ctx, "DropCollection", nil, err)
+}
+
+// ImportDocumentsHandler imports the jsonl of the multipart "file" or of the raw body,
+// query params: action, batch_size, skip_lines
+func (tk *eventCollectionHandlers) ImportDocumentsHandler(ctx *fiber.Ctx) error {
+	options := models.ImportOptions{
+		Collection: ctx.Params("name"),
+		Action:     ctx.Query("action", models.ImportActionUpsert),
+		BatchSize:  ctx.QueryInt("batch_size", importer.DefaultBatchSize),
+		SkipLines:  ctx.QueryInt("skip_lines", 0),
+	}
+	// the body is streamed, the import never holds it whole
+	var reader io.Reader
+	if fileHeader, err := ctx.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return tk.respond(ctx, "ImportDocuments", nil, err)
+		}
+		defer file.Close()
+		reader = file
+	} else if stream := ctx.Request().BodyStream(); stream != nil {
+		reader = stream
+	} else {
+		reader = bytes.NewReader(ctx.Body())
+	}
+	result, err := tk.svc.EventImportService.ImportJSONL(reader, options, nil, nil)
+	if err != nil && result != nil {
+		// the partial report tells where to resume with skip_lines
+		tk.stt.Log.Error("ImportDocuments", zap.Any("report", result), zap.Error(err))
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: tk.stt.ErrMsgs.CallFail.Code,
+			Message:    err.Error(),
+			Data:       result,
+		})
+	}
+	return tk.respond(ctx, "ImportDocuments", result, err)
+}
+
+func (tk *eventCollectionHandlers) ReindexHandler(ctx *fiber.Ctx) error {
+	options := models.ReindexOptions{}
+	err := ctx.BodyParser(&options)
+	options.Collection = ctx.Params("name")
+	if err == nil {
+		err = models.Validate.Struct(&options)
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: tk.stt.ErrMsgs.Params.Code,
+			Message:    tk.stt.ErrMsgs.Params.Msg,
+			Data:       err.Error(),
+		})
+	}
+	result, err := tk.svc.EventReindexService.Reindex(options)
+	if err != nil && result != nil {
+		// checks and import counts explain why the alias did not move
+		tk.stt.Log.Error("Reindex", zap.Any("report", result), zap.Error(err))
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: tk.stt.ErrMsgs.CallFail.Code,
+			Message:    err.Error(),
+			Data:       result,
+		})
+	}
+	return tk.respond(ctx, "Reindex", result, err)
+}
+
+func (tk *eventCollectionHandlers) AdoptHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventReindexService.Adopt(ctx.Params("name"))
+	return tk.respond(ctx, "Adopt", result, err)
+}
+
+func (tk *eventCollectionHandlers) RollbackHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventReindexService.Rollback(ctx.Params("name"))
+	return tk.respond(ctx, "Rollback", result, err)
+}
+
+func (tk *eventCollectionHandlers) ListVersionsHandler(ctx *fiber.Ctx) error {
+	result, err := tk.svc.EventReindexService.ListVersions(ctx.Params("name"))
+	return tk.respond(ctx, "ListVersions", result, err)
+}
+
+func (tk *eventCollectionHandlers) respond(ctx *fiber.Ctx, funcName string, data interface{}, err error) error {
+	tk.stt.Log.Info(funcName, zap.Any("name", ctx.Params("name")), zap.Any("data", data), zap.Error(err))
+	if err == nil {
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: 1,
+			Message:    "Ok",
+			Data:       data,
+		})
+	}
+	var tsErr *utilsCall.TypeSenseError
+	switch {
+	case errors.Is(err, collection.ErrUnknownCollection), errors.Is(err, collection.ErrProtectedCollection):
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: tk.stt.ErrMsgs.Params.Code,
+			Message:    err.Error(),
+		})
+	case errors.As(err, &tsErr):
+		return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+			StatusCode: tk.stt.ErrMsgs.CallFail.Code,
+			Message:    tk.stt.ErrMsgs.CallFail.Msg,
+			Data:       tsErr,
+		})
+	}
+	return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+		StatusCode: tk.stt.ErrMsgs.CallFail.Code,
+		Message:    tk.stt.ErrMsgs.CallFail.Msg,
+		Data:       err.Error(),
+	})
+}
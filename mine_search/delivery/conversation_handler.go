@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/settings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EventConversationHandlers interface {
+	ConverseHandler(*fiber.Ctx) error
+	ConversationHistoryHandler(*fiber.Ctx) error
+	ClearConversationHandler(*fiber.Ctx) error
+}
+
+type eventConversationHandlers struct {
+	stt  *settings.AppSettings
+	svc  *services.AppServices
+	repo *repositories.Repositories
+}
+
+func NewEventConversationHandlers(
+	appSettings *settings.AppSettings,
+	appService *services.AppServices,
+	repo *repositories.Repositories,
+) EventConversationHandlers {
+	return &eventConversationHandlers{
+		stt:  appSettings,
+		svc:  appService,
+		repo: repo,
+	}
+}
+
+// ConverseHandler searches a message of the conversation, rewritten into a standalone query when it follows up
+func (tk *eventConversationHandlers) ConverseHandler(ctx *fiber.Ctx) error {
+	var dataInput models.ConversationMessage
+	customerID := tokenCustomerID(ctx)
+	return handleRequest(tk.stt, ctx, &dataInput, func() (interface{}, error) {
+		return tk.svc.EventConversationService.Converse(customerID, dataInput)
+	})
+}
+
+func (tk *eventConversationHandlers) ConversationHistoryHandler(ctx *fiber.Ctx) error {
+	var dataInput models.ConversationSessionRequest
+	customerID := tokenCustomerID(ctx)
+	return handleRequest(tk.stt, ctx, &dataInput, func() (interface{}, error) {
+		return tk.svc.EventConversationService.ConversationHistory(customerID, dataInput.SessionID)
+	})
+}
+
+func (tk *eventConversationHandlers) ClearConversationHandler(ctx *fiber.Ctx) error {
+	var dataInput models.ConversationSessionRequest
+	customerID := tokenCustomerID(ctx)
+	return handleRequest(tk.stt, ctx, &dataInput, func() (interface{}, error) {
+		return tk.svc.EventConversationService.ClearConversation(customerID, dataInput)
+	})
+}
+
+// tokenCustomerID is the customerId claim of the web token, empty when the token has none
+func tokenCustomerID(ctx *fiber.Ctx) string {
+	customerID, _ := ctx.Locals("customer_id").(string)
+	return customerID
+}
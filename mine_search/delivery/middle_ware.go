@@ -1,6 +1,7 @@
 package delivery
 
 import (
+	"io"
 	"mine/internal"
 	"mine/internal/models"
 	"mine/internal/utils"
@@ -16,6 +17,28 @@ func (s *appHandlers) RateLimit(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
+// LimitBody reads the streamed body of a request whole, answering 413 when it is over limit bytes
+func (s *appHandlers) LimitBody(limit int) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		request := ctx.Request()
+		if request.Header.ContentLength() > limit {
+			return fiber.ErrRequestEntityTooLarge
+		}
+		if !request.IsBodyStream() {
+			return ctx.Next()
+		}
+		body, err := io.ReadAll(io.LimitReader(request.BodyStream(), int64(limit)+1))
+		if err != nil {
+			return fiber.ErrBadRequest
+		}
+		if len(body) > limit {
+			return fiber.ErrRequestEntityTooLarge
+		}
+		request.SetBody(body)
+		return ctx.Next()
+	}
+}
+
 func (s *appHandlers) CustomRateLimit(max int, expiration time.Duration, typeResponse string) fiber.Handler {
 	return limiter.New(limiter.Config{
 		Max:        max,
@@ -93,8 +116,6 @@ func (s *appHandlers) RequireTokenLocal(ctx *fiber.Ctx) error {
 	token := string(ctx.Request().Header.Peek("TOKEN"))
 	resultFe := models.RespLocal{}
 	funcName := "RequireTokenLocal"
-	var body interface{}
-	ctx.BodyParser(&body)
 	defer func() {
 		internal.Log.Info(funcName, zap.Any("url", ctx.Context().URI()), zap.Any("authen", token), zap.Any("result", resultFe))
 	}()
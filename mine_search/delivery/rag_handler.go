@@ -0,0 +1,40 @@
+package delivery
+
+import (
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/settings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EventRAGHandlers interface {
+	AskHandler(*fiber.Ctx) error
+}
+
+type eventRAGHandlers struct {
+	stt  *settings.AppSettings
+	svc  *services.AppServices
+	repo *repositories.Repositories
+}
+
+func NewEventRAGHandlers(
+	appSettings *settings.AppSettings,
+	appService *services.AppServices,
+	repo *repositories.Repositories,
+) EventRAGHandlers {
+	return &eventRAGHandlers{
+		stt:  appSettings,
+		svc:  appService,
+		repo: repo,
+	}
+}
+
+// AskHandler answers a question from the catalog with the ids of the documents the answer cites
+func (tk *eventRAGHandlers) AskHandler(ctx *fiber.Ctx) error {
+	var dataInput models.RAGAsk
+	return handleRequest(tk.stt, ctx, &dataInput, func() (interface{}, error) {
+		return tk.svc.EventRAGService.Ask(dataInput)
+	})
+}
@@ -0,0 +1,49 @@
+package delivery
+
+import (
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/settings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EventRedisHandlers interface {
+	RedisTextSearchHandler(*fiber.Ctx) error
+	RedisLocationSearchHandler(*fiber.Ctx) error
+}
+
+type eventRedisHandlers struct {
+	stt  *settings.AppSettings
+	svc  *services.AppServices
+	repo *repositories.Repositories
+}
+
+func NewEventRedisHandlers(
+	appSettings *settings.AppSettings,
+	appService *services.AppServices,
+	repo *repositories.Repositories,
+) EventRedisHandlers {
+	return &eventRedisHandlers{
+		stt:  appSettings,
+		svc:  appService,
+		repo: repo,
+	}
+}
+
+// RedisTextSearchHandler takes the body of the Typesense text search and answers in the same shape
+func (tk *eventRedisHandlers) RedisTextSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.Search
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.Search(models.BackendRedis, dataInput)
+	})
+}
+
+// RedisLocationSearchHandler answers radius and box requests from the Redis geo sets, without text or conditions
+func (tk *eventRedisHandlers) RedisLocationSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.LocationSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.SearchLocation(models.BackendRedisGeo, dataInput)
+	})
+}
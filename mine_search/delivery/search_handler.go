@@ -0,0 +1,58 @@
+package delivery
+
+import (
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services"
+	"mine/internal/settings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EventSearchHandlers serves the routes where the request picks the engine with its backend field
+type EventSearchHandlers interface {
+	TextSearchHandler(*fiber.Ctx) error
+	LocationSearchHandler(*fiber.Ctx) error
+	ListBackendsHandler(*fiber.Ctx) error
+}
+
+type eventSearchHandlers struct {
+	stt  *settings.AppSettings
+	svc  *services.AppServices
+	repo *repositories.Repositories
+}
+
+func NewEventSearchHandlers(
+	appSettings *settings.AppSettings,
+	appService *services.AppServices,
+	repo *repositories.Repositories,
+) EventSearchHandlers {
+	return &eventSearchHandlers{
+		stt:  appSettings,
+		svc:  appService,
+		repo: repo,
+	}
+}
+
+func (tk *eventSearchHandlers) TextSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.Search
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.Search("", dataInput)
+	})
+}
+
+func (tk *eventSearchHandlers) LocationSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.LocationSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.SearchLocation("", dataInput)
+	})
+}
+
+// ListBackendsHandler reports the health and capabilities of every engine
+func (tk *eventSearchHandlers) ListBackendsHandler(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(models.RespLocal{
+		StatusCode: 1,
+		Message:    "Ok",
+		Data:       tk.svc.Backends.Statuses(),
+	})
+}
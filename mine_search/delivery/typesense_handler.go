@@ -2,9 +2,11 @@ package delivery
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mine/internal"
 	"mine/internal/models"
+	"mine/internal/query_builder"
 	"mine/internal/repositories"
 	"mine/internal/services"
 	"mine/internal/settings"
@@ -17,7 +19,11 @@ import (
 
 type EventTypeSenseHandlers interface {
 	TypeSenseTextSearchHandler(*fiber.Ctx) error
-	// TypeSenseLocationSearch(*fiber.Ctx) error
+	TypeSenseLocationSearchHandler(*fiber.Ctx) error
+	VectorSearchHandler(*fiber.Ctx) error
+	HybridSearchHandler(*fiber.Ctx) error
+	ChunkSearchHandler(*fiber.Ctx) error
+	RerankSearchHandler(*fiber.Ctx) error
 }
 
 type eventTypeSenseHandlers struct {
@@ -39,6 +45,61 @@ func NewEventTypeSenseHandlers(
 }
 
 func (tk *eventTypeSenseHandlers) TypeSenseTextSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.Search
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.Search(models.BackendTypeSense, dataInput)
+	})
+}
+
+func (tk *eventTypeSenseHandlers) TypeSenseLocationSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.LocationSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.SearchLocation(models.BackendTypeSense, dataInput)
+	})
+}
+
+// VectorSearchHandler answers nearest neighbor queries on an embedding field with Typesense vector_query,
+// or with the in-process graphs when the request picks the hnsw backend
+func (tk *eventTypeSenseHandlers) VectorSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.VectorSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.Backends.SearchVector("", dataInput)
+	})
+}
+
+// HybridSearchHandler fuses the keyword search of the backend engine with the vector search of the vector_backend engine
+func (tk *eventTypeSenseHandlers) HybridSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.HybridSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.EventHybridService.SearchHybrid(dataInput)
+	})
+}
+
+// ChunkSearchHandler searches the chunks of a chunked collection and answers their parent documents
+func (tk *eventTypeSenseHandlers) ChunkSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.ChunkSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.EventChunkService.SearchChunks(dataInput)
+	})
+}
+
+// RerankSearchHandler rescores the first hits of a search with the rerank pipeline
+func (tk *eventTypeSenseHandlers) RerankSearchHandler(ctx *fiber.Ctx) error {
+	var dataInput models.RerankSearch
+	return handleSearch(tk.stt, ctx, &dataInput, func() (*models.SearchResult, error) {
+		return tk.svc.EventRerankService.SearchReranked(dataInput)
+	})
+}
+
+// handleSearch decodes the body into dataInput, validates it, then runs search and logs the call to Kibana
+func handleSearch(stt *settings.AppSettings, ctx *fiber.Ctx, dataInput interface{}, search func() (*models.SearchResult, error)) error {
+	return handleRequest(stt, ctx, dataInput, func() (interface{}, error) {
+		return search()
+	})
+}
+
+// handleRequest is handleSearch for any public route answering with run's result
+func handleRequest(stt *settings.AppSettings, ctx *fiber.Ctx, dataInput interface{}, run func() (interface{}, error)) error {
 	startTime := time.Now()
 	status, msg := internal.SysStatus.SystemBusy.Status, internal.SysStatus.SystemBusy.Msg
 
@@ -65,8 +126,7 @@ func (tk *eventTypeSenseHandlers) TypeSenseTextSearchHandler(ctx *fiber.Ctx) err
 		})
 	}
 
-	var dataInput models.Search
-	if err := json.Unmarshal(jsonBody, &dataInput); err != nil {
+	if err := json.Unmarshal(jsonBody, dataInput); err != nil {
 		internal.Log.Error("Failed to unmarshal request data", zap.Error(err))
 		return ctx.Status(fiber.StatusBadRequest).JSON(models.Resp{
 			Status: internal.SysStatus.WrongParams.Status,
@@ -74,7 +134,7 @@ func (tk *eventTypeSenseHandlers) TypeSenseTextSearchHandler(ctx *fiber.Ctx) err
 		})
 	}
 
-	if err := models.Validate.Struct(&dataInput); err != nil {
+	if err := models.Validate.Struct(dataInput); err != nil {
 		internal.Log.Error("Validation error", zap.Any("input", dataInput), zap.Error(err))
 		return ctx.Status(fiber.StatusBadRequest).JSON(models.Resp{
 			Status: internal.SysStatus.WrongParams.Status,
@@ -82,20 +142,51 @@ func (tk *eventTypeSenseHandlers) TypeSenseTextSearchHandler(ctx *fiber.Ctx) err
 		})
 	}
 
-	result, err := tk.svc.EventTypeSenseService.SearchText(dataInput)
+	result, err := run()
+	if errors.Is(err, query_builder.ErrInvalidCursor) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(models.Resp{
+			Status: internal.SysStatus.InvalidCursor.Status,
+			Msg:    internal.SysStatus.InvalidCursor.Msg,
+		})
+	}
+	if errors.Is(err, query_builder.ErrCursorMismatch) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(models.Resp{
+			Status: internal.SysStatus.CursorMismatch.Status,
+			Msg:    internal.SysStatus.CursorMismatch.Msg,
+		})
+	}
+	if errors.Is(err, query_builder.ErrCursorDrift) {
+		return ctx.Status(fiber.StatusConflict).JSON(models.Resp{
+			Status: internal.SysStatus.CursorDrift.Status,
+			Msg:    internal.SysStatus.CursorDrift.Msg,
+		})
+	}
+	if errors.Is(err, query_builder.ErrInvalidQuery) {
+		var detail interface{} = err.Error()
+		var fieldErrs query_builder.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			detail = fieldErrs
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(models.Resp{
+			Status: internal.SysStatus.WrongParams.Status,
+			Msg:    internal.SysStatus.WrongParams.Msg,
+			Detail: detail,
+		})
+	}
 	if err != nil {
-		tk.stt.Log.Error("Service error", zap.Error(err))
+		stt.Log.Error("Service error", zap.Error(err))
 		return ctx.Status(fiber.StatusInternalServerError).JSON(models.Resp{
 			Status: status,
 			Msg:    err.Error(),
 		})
 	}
+	status, msg = 1, "Ok"
 
 	defer func() {
 		// Log details to Kibana
 		utils.Pool.Execute(&utils.SendLogToKibanaTask{
-			BootstrapServer: tk.stt.Brokers,
-			TopicName:       tk.stt.KafkaTopicName,
+			BootstrapServer: stt.Brokers,
+			TopicName:       stt.KafkaTopicName,
 			Message: utils.KibanaMessage{
 				ServiceName: internal.ServiceName,
 				UserAgent:   string(ctx.Context().UserAgent()),
@@ -111,8 +202,8 @@ func (tk *eventTypeSenseHandlers) TypeSenseTextSearchHandler(ctx *fiber.Ctx) err
 		})
 		// Log to Kibana (all servers)
 		if errPoolAll := utils.Pool.Execute(&utils.SendLogToKibanaAllTask{
-			BootstrapServer: tk.stt.Brokers,
-			TopicName:       tk.stt.KafkaTopicNameAll,
+			BootstrapServer: stt.Brokers,
+			TopicName:       stt.KafkaTopicNameAll,
 			Message: utils.KibanaMessageAll{
 				Phone:        infoUser.CustomerPhone,
 				CustomerId:   infoUser.CustomerId,
@@ -127,13 +218,13 @@ func (tk *eventTypeSenseHandlers) TypeSenseTextSearchHandler(ctx *fiber.Ctx) err
 				ServiceName:  internal.ServiceName,
 			},
 		}); errPoolAll != nil {
-			tk.stt.Log.Error("Failed to log to Kibana (all)", zap.Error(errPoolAll))
+			stt.Log.Error("Failed to log to Kibana (all)", zap.Error(errPoolAll))
 		}
 	}()
 
 	return ctx.Status(fiber.StatusOK).JSON(models.Resp{
-		Status: 1,
-		Msg:    "Ok",
+		Status: status,
+		Msg:    msg,
 		Detail: result,
 	})
 }
@@ -0,0 +1,113 @@
+// Package chunking cuts long texts into overlapping chunks that are embedded and retrieved on their own
+package chunking
+
+import (
+	"mine/internal/models"
+	"regexp"
+	"strings"
+)
+
+// sentenceEnd splits after ., ! or ? followed by spaces, and on blank lines
+var sentenceEnd = regexp.MustCompile(`([.!?…]+["')\]]*)\s+|\n\s*\n`)
+
+// Split cuts text into chunks of at most config.Size tokens, a token being a word between spaces.
+// Consecutive chunks share config.Overlap tokens, or the whole sentences that fit in it
+func Split(text string, config models.ChunkingConfig) []string {
+	size := config.Size
+	if size <= 0 {
+		size = models.DefaultChunkSize
+	}
+	overlap := config.Overlap
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	if config.Strategy == models.ChunkStrategySentences {
+		return splitSentences(text, size, overlap)
+	}
+	return splitTokens(strings.Fields(text), size, overlap)
+}
+
+// splitTokens slides a window of size words moving by size-overlap
+func splitTokens(words []string, size, overlap int) []string {
+	chunks := []string{}
+	for start := 0; start < len(words); start += size - overlap {
+		end := start + size
+		if end > len(words) {
+			end = len(words)
+		}
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+	}
+	return chunks
+}
+
+// splitSentences packs whole sentences up to size words, a sentence longer than size is cut by words.
+// The next chunk starts again with the last sentences of the previous one that fit in overlap
+func splitSentences(text string, size, overlap int) []string {
+	sentences := [][]string{}
+	for _, sentence := range Sentences(text) {
+		words := strings.Fields(sentence)
+		if len(words) <= size {
+			sentences = append(sentences, words)
+			continue
+		}
+		for _, part := range splitTokens(words, size, 0) {
+			sentences = append(sentences, strings.Fields(part))
+		}
+	}
+	chunks := []string{}
+	current, count := [][]string{}, 0
+	flush := func() {
+		words := []string{}
+		for _, sentence := range current {
+			words = append(words, sentence...)
+		}
+		chunks = append(chunks, strings.Join(words, " "))
+		kept, keptCount := [][]string{}, 0
+		// the first sentence is never kept, so every chunk moves past the previous one
+		for idx := len(current) - 1; idx > 0; idx-- {
+			if keptCount+len(current[idx]) > overlap {
+				break
+			}
+			kept = append([][]string{current[idx]}, kept...)
+			keptCount += len(current[idx])
+		}
+		current, count = kept, keptCount
+	}
+	for _, sentence := range sentences {
+		if count+len(sentence) > size && count > 0 {
+			flush()
+			if count+len(sentence) > size {
+				current, count = [][]string{}, 0
+			}
+		}
+		current = append(current, sentence)
+		count += len(sentence)
+	}
+	if len(current) > 0 {
+		flush()
+	}
+	return chunks
+}
+
+// Sentences splits text on sentence ends and blank lines, the punctuation stays with its sentence
+func Sentences(text string) []string {
+	sentences := []string{}
+	last := 0
+	for _, match := range sentenceEnd.FindAllStringSubmatchIndex(text, -1) {
+		end := match[1]
+		if match[2] >= 0 {
+			end = match[3]
+		}
+		if sentence := strings.TrimSpace(text[last:end]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		last = match[1]
+	}
+	if sentence := strings.TrimSpace(text[last:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
@@ -0,0 +1,52 @@
+package chunking
+
+import (
+	"mine/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		config models.ChunkingConfig
+		want   []string
+	}{
+		{"tokens", "a b c d e f g", models.ChunkingConfig{Size: 3}, []string{"a b c", "d e f", "g"}},
+		{"tokens overlap", "a b c d e f g", models.ChunkingConfig{Size: 3, Overlap: 1}, []string{"a b c", "c d e", "e f g"}},
+		{"overlap not below size", "a b c d", models.ChunkingConfig{Size: 2, Overlap: 2}, []string{"a b", "c d"}},
+		{"empty", "  ", models.ChunkingConfig{Size: 3}, []string{}},
+		{
+			"sentences",
+			"One two. Three four five! Six seven? Eight.",
+			models.ChunkingConfig{Strategy: models.ChunkStrategySentences, Size: 5},
+			[]string{"One two. Three four five!", "Six seven? Eight."},
+		},
+		{
+			"sentences overlap",
+			"One two. Three four. Five six. Seven.",
+			models.ChunkingConfig{Strategy: models.ChunkStrategySentences, Size: 4, Overlap: 2},
+			[]string{"One two. Three four.", "Three four. Five six.", "Five six. Seven."},
+		},
+		{
+			"long sentence cut by words",
+			"a b c d e f. g.",
+			models.ChunkingConfig{Strategy: models.ChunkStrategySentences, Size: 4},
+			[]string{"a b c d", "e f. g."},
+		},
+	}
+	for _, test := range tests {
+		if got := Split(test.text, test.config); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Split = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSentences(t *testing.T) {
+	got := Sentences("He said \"stop.\" Then left!\n\nNew paragraph")
+	want := []string{"He said \"stop.\"", "Then left!", "New paragraph"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sentences = %q, want %q", got, want)
+	}
+}
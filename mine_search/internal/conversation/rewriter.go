@@ -0,0 +1,174 @@
+// Package conversation keeps the turns of search conversations and rewrites follow-up messages into
+// standalone queries
+package conversation
+
+import (
+	"mine/internal"
+	"mine/internal/llm"
+	"mine/internal/models"
+	"mine/internal/utils"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// Rewriter turns message into a query that can be searched without the history, the previous turns
+// oldest first. rewritten is false when message is already standalone
+type Rewriter interface {
+	Name() string
+	Rewrite(message string, history []models.ConversationTurn) (query string, rewritten bool, err error)
+}
+
+var (
+	// followUpStarts open a message that refines the previous query
+	followUpStarts = []string{"what about", "how about", "and", "also", "only", "but", "with", "without", "not",
+		"from", "by", "in", "after", "before", "under", "over", "cheaper", "newer", "older", "more", "same"}
+	// references point back to what the previous turns searched
+	references = map[string]bool{"it": true, "its": true, "that": true, "those": true, "them": true, "this": true,
+		"these": true, "they": true, "their": true, "one": true, "ones": true, "he": true, "she": true,
+		"his": true, "her": true, "him": true, "same": true}
+	// fillers carry no search term
+	fillers = map[string]bool{"what": true, "about": true, "how": true, "and": true, "also": true, "only": true,
+		"but": true, "with": true, "show": true, "me": true, "the": true, "a": true, "an": true, "any": true,
+		"some": true, "is": true, "are": true, "of": true, "do": true, "you": true, "have": true, "please": true,
+		"which": true, "by": true, "from": true, "in": true, "more": true, "other": true, "else": true}
+)
+
+// maxFollowUpWords is the length under which a message is a follow-up even without marker, e.g. "paperback"
+const maxFollowUpWords = 3
+
+// RuleRewriter rewrites a follow-up by adding its new terms to the previous query: a message starting with a
+// follow-up marker, referring back with a pronoun or of at most 3 words. "without X" and "not X" add -X, see
+// WithoutExclusions
+type RuleRewriter struct{}
+
+func (r RuleRewriter) Name() string {
+	return models.RewriterRule
+}
+
+func (r RuleRewriter) Rewrite(message string, history []models.ConversationTurn) (string, bool, error) {
+	message = strings.TrimSpace(message)
+	if len(history) == 0 {
+		return message, false, nil
+	}
+	words := strings.FieldsFunc(strings.ToLower(message), func(char rune) bool {
+		return !unicode.IsLetter(char) && !unicode.IsNumber(char) && char != '-' && char != '\''
+	})
+	if !isFollowUp(words) {
+		return message, false, nil
+	}
+	previous := history[len(history)-1].Query
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(previous) {
+		seen[utils.Fold(strings.ToLower(strings.TrimPrefix(word, "-")))] = true
+	}
+	terms := []string{previous}
+	for idx := 0; idx < len(words); idx++ {
+		word := words[idx]
+		if word == "without" || word == "not" {
+			// the excluded term is the next word carrying one, "without the illustrated" excludes illustrated
+			for idx+1 < len(words) && (fillers[words[idx+1]] || references[words[idx+1]]) {
+				idx++
+			}
+			if idx+1 < len(words) {
+				idx++
+				terms = append(terms, "-"+words[idx])
+			}
+			continue
+		}
+		if references[word] || fillers[word] || seen[utils.Fold(word)] {
+			continue
+		}
+		seen[utils.Fold(word)] = true
+		terms = append(terms, word)
+	}
+	return strings.Join(terms, " "), true, nil
+}
+
+// WithoutExclusions removes the -X terms of query. Only the Typesense keyword search reads them as exclusions,
+// the other engines and the embedders would search X itself
+func WithoutExclusions(query string) string {
+	terms := []string{}
+	for _, term := range strings.Fields(query) {
+		if len(term) > 1 && strings.HasPrefix(term, "-") {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+func isFollowUp(words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	if len(words) <= maxFollowUpWords {
+		return true
+	}
+	joined := strings.Join(words, " ")
+	for _, start := range followUpStarts {
+		if joined == start || strings.HasPrefix(joined, start+" ") {
+			return true
+		}
+	}
+	for _, word := range words {
+		if references[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// rewritePrompt asks for the query alone so the answer can be searched as is
+const rewritePrompt = "You rewrite the last message of a catalog search conversation into a standalone search query, " +
+	"using the previous queries to resolve what it refers to. Answer with the query only, on one line, without quotes."
+
+// LLMRewriter asks a model to rewrite follow-ups, the rule rewriter answers when the model fails
+type LLMRewriter struct {
+	chatter  llm.Chatter
+	fallback Rewriter
+}
+
+func NewLLMRewriter(chatter llm.Chatter) *LLMRewriter {
+	return &LLMRewriter{chatter: chatter, fallback: RuleRewriter{}}
+}
+
+func (r *LLMRewriter) Name() string {
+	return models.RewriterLLM
+}
+
+func (r *LLMRewriter) Rewrite(message string, history []models.ConversationTurn) (string, bool, error) {
+	message = strings.TrimSpace(message)
+	if len(history) == 0 {
+		return message, false, nil
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Previous queries:\n")
+	for _, turn := range history {
+		builder.WriteString("- " + turn.Query + "\n")
+	}
+	builder.WriteString("Last message: " + message)
+	completion, err := r.chatter.Chat(rewritePrompt, builder.String())
+	if err != nil {
+		internal.Log.Error("LLMRewriter.Rewrite -> Chat", zap.Any("message", message), zap.Error(err))
+		return r.fallback.Rewrite(message, history)
+	}
+	query := strings.Trim(strings.TrimSpace(strings.SplitN(strings.TrimSpace(completion.Text), "\n", 2)[0]), "\"'`")
+	if query == "" {
+		return r.fallback.Rewrite(message, history)
+	}
+	return query, query != message, nil
+}
+
+// NewRewriter builds the rewriter named by CONVERSATION_REWRITER, the rule rewriter when it is empty or
+// when the llm provider cannot take instructions
+func NewRewriter(name string, provider llm.LLMProvider) Rewriter {
+	if name == models.RewriterLLM {
+		if chatter, ok := provider.(llm.Chatter); ok {
+			return NewLLMRewriter(chatter)
+		}
+		internal.Log.Error("NewRewriter", zap.Any("rewriter", name), zap.Any("reason", "LLM_PROVIDER is not http"))
+	}
+	return RuleRewriter{}
+}
@@ -0,0 +1,147 @@
+package conversation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// KeyPrefix starts the keys of every session: conversation:<customer_id>:<session_id> is the list of its turns
+const KeyPrefix = "conversation:"
+
+const (
+	// DefaultTTL drops a session left alone for 30 minutes
+	DefaultTTL      = 30 * time.Minute
+	DefaultMaxTurns = 10
+)
+
+// ErrInvalidSession is returned for a session id that cannot be part of a key
+var ErrInvalidSession = errors.New("invalid conversation session")
+
+var sessionRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// globEscaper keeps a customer id literal in a SCAN pattern
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Store keeps the last turns of every session in a Redis list shared by every replica
+type Store struct {
+	rdb      *redis.Client
+	ttl      time.Duration
+	maxTurns int64
+}
+
+func NewStore(rdb *redis.Client, ttl time.Duration, maxTurns int) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxTurns <= 0 {
+		maxTurns = DefaultMaxTurns
+	}
+	return &Store{rdb: rdb, ttl: ttl, maxTurns: int64(maxTurns)}
+}
+
+// ValidSession reports whether sessionID can be part of a key
+func ValidSession(sessionID string) bool {
+	return sessionRegex.MatchString(sessionID)
+}
+
+func (s *Store) key(customerID, sessionID string) (string, error) {
+	if !ValidSession(sessionID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSession, sessionID)
+	}
+	return KeyPrefix + customerID + ":" + sessionID, nil
+}
+
+// Turns returns the turns of a session oldest first, none for an expired session
+func (s *Store) Turns(customerID, sessionID string) ([]models.ConversationTurn, error) {
+	key, err := s.key(customerID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := s.rdb.LRange(key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	turns := make([]models.ConversationTurn, 0, len(values))
+	for _, value := range values {
+		turn := models.ConversationTurn{}
+		if err := json.Unmarshal([]byte(value), &turn); err != nil {
+			return nil, err
+		}
+		turns = append(turns, turn)
+	}
+	return turns, nil
+}
+
+// Append adds turn, keeps the last turns and starts the TTL again, it returns the number of turns kept
+func (s *Store) Append(customerID, sessionID string, turn models.ConversationTurn) (int, error) {
+	key, err := s.key(customerID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	content, err := json.Marshal(turn)
+	if err != nil {
+		return 0, err
+	}
+	pipe := s.rdb.TxPipeline()
+	length := pipe.RPush(key, content)
+	pipe.LTrim(key, -s.maxTurns, -1)
+	pipe.Expire(key, s.ttl)
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+	if length.Val() > s.maxTurns {
+		return int(s.maxTurns), nil
+	}
+	return int(length.Val()), nil
+}
+
+// TTL is the time left before the session expires, 0 for a missing session
+func (s *Store) TTL(customerID, sessionID string) (time.Duration, error) {
+	key, err := s.key(customerID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	ttl, err := s.rdb.TTL(key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+// Clear deletes a session, it returns the number of sessions deleted
+func (s *Store) Clear(customerID, sessionID string) (int, error) {
+	key, err := s.key(customerID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := s.rdb.Del(key).Result()
+	return int(deleted), err
+}
+
+// ClearAll deletes every session of the customer
+func (s *Store) ClearAll(customerID string) (int, error) {
+	keys := []string{}
+	cursor := uint64(0)
+	for {
+		page, next, err := s.rdb.Scan(cursor, KeyPrefix+globEscaper.Replace(customerID)+":*", 1000).Result()
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, page...)
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	deleted, err := s.rdb.Del(keys...).Result()
+	return int(deleted), err
+}
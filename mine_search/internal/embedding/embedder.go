@@ -0,0 +1,68 @@
+// Package embedding turns text into the vectors stored in the embedding fields of a collection
+// and sent with vector queries
+package embedding
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/settings"
+	"strings"
+)
+
+// Providers of EMBEDDING_PROVIDER
+const (
+	ProviderHash = "hash"
+	ProviderHTTP = "http"
+	// DefaultDimensions is the size of the hash vectors when EMBEDDING_DIM is not set
+	DefaultDimensions = 256
+)
+
+// ErrEmptyText is returned for a text without any word, it has no direction to compare
+var ErrEmptyText = errors.New("text has no word to embed")
+
+// Embedder returns one vector of Dimensions values per text, in the order of texts
+type Embedder interface {
+	Name() string
+	Dimensions() int
+	Embed(texts []string) ([][]float32, error)
+}
+
+// New builds the embedder named by EMBEDDING_PROVIDER, the hash embedder when it is empty
+func New(appSettings *settings.AppSettings) (Embedder, error) {
+	cfgs := appSettings.Cfgs
+	dimensions := cfgs.EmbeddingDim
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	switch cfgs.EmbeddingProvider {
+	case "", ProviderHash:
+		return NewHashEmbedder(dimensions), nil
+	case ProviderHTTP:
+		if cfgs.EmbeddingURL == "" {
+			return nil, errors.New("the http embedding provider needs EMBEDDING_URL")
+		}
+		return NewHTTPEmbedder(cfgs.EmbeddingURL, cfgs.EmbeddingAPIKey, cfgs.EmbeddingModel, dimensions), nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q", cfgs.EmbeddingProvider)
+}
+
+// DocumentText joins the values of fields in document, the text embedded for it.
+// ok is false when the document has none of the fields
+func DocumentText(document map[string]interface{}, fields []string) (string, bool) {
+	parts := []string{}
+	for _, field := range fields {
+		switch value := document[field].(type) {
+		case string:
+			parts = append(parts, value)
+		case []interface{}:
+			for _, item := range value {
+				if text, ok := item.(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		case []string:
+			parts = append(parts, value...)
+		}
+	}
+	return strings.Join(parts, " "), len(parts) > 0
+}
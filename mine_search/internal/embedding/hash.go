@@ -0,0 +1,82 @@
+package embedding
+
+import (
+	"hash/fnv"
+	"math"
+	"mine/internal/utils"
+)
+
+// trigramWeight keeps words ahead of the character trigrams that make "harry" close to "hary"
+const trigramWeight = 0.5
+
+// HashEmbedder is a deterministic embedder needing no model: the words and character trigrams of the folded
+// text are hashed into the dimensions with a sign (feature hashing), the vector is then normalized.
+// Texts sharing words or spellings are close, synonyms are not
+type HashEmbedder struct {
+	dimensions int
+}
+
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+func (e *HashEmbedder) Name() string {
+	return ProviderHash
+}
+
+func (e *HashEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+func (e *HashEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, err := e.embed(text)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) embed(text string) ([]float32, error) {
+	tokens := utils.Tokenize(text)
+	if len(tokens) == 0 {
+		return nil, ErrEmptyText
+	}
+	values := make([]float64, e.dimensions)
+	for _, token := range tokens {
+		token = utils.Fold(token)
+		e.add(values, "w:"+token, 1)
+		runes := []rune("#" + token + "#")
+		trigrams := len(runes) - 2
+		for start := 0; start < trigrams; start++ {
+			e.add(values, "t:"+string(runes[start:start+3]), trigramWeight/float64(trigrams))
+		}
+	}
+	norm := 0.0
+	for _, value := range values {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+	vector := make([]float32, e.dimensions)
+	if norm == 0 {
+		return vector, nil
+	}
+	for idx, value := range values {
+		vector[idx] = float32(value / norm)
+	}
+	return vector, nil
+}
+
+// add puts weight in the dimension of feature, the sign bit spreads collisions around 0
+func (e *HashEmbedder) add(values []float64, feature string, weight float64) {
+	hash := fnv.New64a()
+	hash.Write([]byte(feature))
+	sum := hash.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	values[sum%uint64(e.dimensions)] += weight
+}
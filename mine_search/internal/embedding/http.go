@@ -0,0 +1,83 @@
+package embedding
+
+import (
+	"encoding/json"
+	"fmt"
+	"mine/internal"
+	"mine/internal/utils"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// httpTimeout is in seconds, a batch of the importer is embedded in one call
+const httpTimeout = 60
+
+// HTTPEmbedder calls an OpenAI compatible /embeddings endpoint: {"model", "input": [texts]} answered by
+// {"data": [{"index", "embedding"}]}
+type HTTPEmbedder struct {
+	url        string
+	apiKey     string
+	model      string
+	dimensions int
+}
+
+func NewHTTPEmbedder(url, apiKey, model string, dimensions int) *HTTPEmbedder {
+	return &HTTPEmbedder{url: url, apiKey: apiKey, model: model, dimensions: dimensions}
+}
+
+func (e *HTTPEmbedder) Name() string {
+	return ProviderHTTP
+}
+
+func (e *HTTPEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+type httpEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *HTTPEmbedder) Embed(texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+	headers := map[string]string{}
+	if e.apiKey != "" {
+		headers["Authorization"] = "Bearer " + e.apiKey
+	}
+	body := map[string]interface{}{"input": texts}
+	if e.model != "" {
+		body["model"] = e.model
+	}
+	resp, err := utils.RequestWithMethod(http.MethodPost, e.url, headers, nil, body, httpTimeout, false)
+	if err != nil {
+		internal.Log.Error("HTTPEmbedder.Embed -> RequestWithMethod", zap.Any("url", e.url), zap.Any("texts", len(texts)), zap.Error(err))
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		internal.Log.Error("HTTPEmbedder.Embed", zap.Any("url", e.url), zap.Any("status", resp.StatusCode()), zap.Any("response", resp.String()))
+		return nil, fmt.Errorf("embedding provider http status %d", resp.StatusCode())
+	}
+	content := httpEmbeddingResponse{}
+	if err := json.Unmarshal(resp.Body(), &content); err != nil {
+		return nil, err
+	}
+	if len(content.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding provider answered %d vectors for %d texts", len(content.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range content.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding provider answered index %d for %d texts", item.Index, len(texts))
+		}
+		if len(item.Embedding) != e.dimensions {
+			return nil, fmt.Errorf("embedding provider answered %d dimensions, EMBEDDING_DIM is %d", len(item.Embedding), e.dimensions)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
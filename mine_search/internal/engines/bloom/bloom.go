@@ -0,0 +1,141 @@
+// Package bloom holds Bloom filters sized from the expected item count and false positive rate,
+// with the bits in process memory or in a Redis string shared by every replica, and cuckoo filters
+// that also delete items
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"mine/internal/models"
+	"regexp"
+)
+
+// MaxBits is the size of the largest Redis string, 512MB, the memory filter keeps the same bound
+const MaxBits = uint64(1) << 32
+
+var (
+	ErrNotFound = errors.New("bloom filter not found")
+	ErrExists   = errors.New("bloom filter already exists")
+	ErrInvalid  = errors.New("invalid bloom filter request")
+	ErrNoDelete = errors.New("a bloom filter cannot delete items, use a cuckoo filter")
+	// ErrFull is returned by a cuckoo filter that found no slot for an item, items added before it are kept
+	ErrFull = errors.New("cuckoo filter is full")
+)
+
+// nameRegex keeps names usable in Redis keys and url paths
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Filter answers "maybe" or "no" for an item, an added item is never reported missing
+type Filter interface {
+	Meta() models.BloomMeta
+	// Add sets the bits of items, it returns which of them were not in the filter before
+	Add(items []string) ([]bool, error)
+	MightContain(items []string) ([]bool, error)
+	// Delete removes items added before, it returns which of them were found
+	Delete(items []string) ([]bool, error)
+	Stats() (*models.BloomStats, error)
+	Drop() error
+}
+
+// NewMeta checks the request and sizes the filter: bits = -n·ln(p)/ln(2)², hashes = bits/n·ln(2) for a Bloom
+// filter, buckets of 4 fingerprints filled up to 95% for a cuckoo filter
+func NewMeta(dataInput models.BloomCreate) (models.BloomMeta, error) {
+	if !nameRegex.MatchString(dataInput.Name) {
+		return models.BloomMeta{}, fmt.Errorf("%w: name %q", ErrInvalid, dataInput.Name)
+	}
+	if dataInput.Capacity == 0 || dataInput.ErrorRate <= 0 || dataInput.ErrorRate >= 1 {
+		return models.BloomMeta{}, fmt.Errorf("%w: capacity %d, error_rate %v", ErrInvalid, dataInput.Capacity, dataInput.ErrorRate)
+	}
+	field := dataInput.Field
+	if dataInput.Collection != "" && field == "" {
+		field = "id"
+	}
+	if dataInput.Type == models.BloomTypeCuckoo {
+		if dataInput.Storage == models.BloomStorageRedis {
+			return models.BloomMeta{}, fmt.Errorf("%w: a cuckoo filter is kept in memory, snapshots persist it", ErrInvalid)
+		}
+		meta, err := cuckooMeta(dataInput.Capacity, dataInput.ErrorRate)
+		if err != nil {
+			return models.BloomMeta{}, err
+		}
+		meta.Name, meta.Collection, meta.Field = dataInput.Name, dataInput.Collection, field
+		return meta, nil
+	}
+	storage := dataInput.Storage
+	if storage == "" {
+		storage = models.BloomStorageRedis
+	}
+	capacity := float64(dataInput.Capacity)
+	bits := math.Ceil(-capacity * math.Log(dataInput.ErrorRate) / (math.Ln2 * math.Ln2))
+	if bits > float64(MaxBits) {
+		return models.BloomMeta{}, fmt.Errorf("%w: %.0f bits needed, at most %d", ErrInvalid, bits, MaxBits)
+	}
+	hashes := int(math.Round(bits / capacity * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return models.BloomMeta{
+		Name:       dataInput.Name,
+		Type:       models.BloomTypeBloom,
+		Storage:    storage,
+		Capacity:   dataInput.Capacity,
+		ErrorRate:  dataInput.ErrorRate,
+		Bits:       uint64(bits),
+		Hashes:     hashes,
+		Collection: dataInput.Collection,
+		Field:      field,
+	}, nil
+}
+
+// NewMemory returns an empty in-process filter of the type of meta
+func NewMemory(meta models.BloomMeta) Filter {
+	if meta.Type == models.BloomTypeCuckoo {
+		return NewCuckooFilter(meta)
+	}
+	return NewMemoryFilter(meta)
+}
+
+// hash128 is a 128 bit FNV-1a hash of item split in two halves, each one mixed since the high bits
+// of FNV barely change between short items
+func hash128(item string) (uint64, uint64) {
+	hash := fnv.New128a()
+	hash.Write([]byte(item))
+	sum := hash.Sum(nil)
+	return mix64(binary.BigEndian.Uint64(sum[:8])), mix64(binary.BigEndian.Uint64(sum[8:]))
+}
+
+// mix64 is the finalizer of MurmurHash3
+func mix64(value uint64) uint64 {
+	value ^= value >> 33
+	value *= 0xff51afd7ed558ccd
+	value ^= value >> 33
+	value *= 0xc4ceb9fe1a85ec53
+	value ^= value >> 33
+	return value
+}
+
+// locations are the bits of item, derived from the two halves of its hash (double hashing)
+func locations(meta models.BloomMeta, item string) []uint64 {
+	h1, h2 := hash128(item)
+	h2 |= 1
+	positions := make([]uint64, meta.Hashes)
+	for idx := range positions {
+		positions[idx] = (h1 + uint64(idx)*h2) % meta.Bits
+	}
+	return positions
+}
+
+// stats fills the ratios from the counters, the error estimate is fill^hashes
+func stats(meta models.BloomMeta, count, bitsSet uint64) *models.BloomStats {
+	fill := float64(bitsSet) / float64(meta.Bits)
+	return &models.BloomStats{
+		BloomMeta:          meta,
+		Count:              count,
+		BitsSet:            bitsSet,
+		FillRatio:          fill,
+		EstimatedErrorRate: math.Pow(fill, float64(meta.Hashes)),
+	}
+}
@@ -0,0 +1,151 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"testing"
+)
+
+func newFilter(t *testing.T, filterType string, capacity uint64, errorRate float64) Filter {
+	t.Helper()
+	meta, err := NewMeta(models.BloomCreate{Name: "test", Type: filterType, Capacity: capacity, ErrorRate: errorRate, Storage: models.BloomStorageMemory})
+	if err != nil {
+		t.Fatalf("NewMeta: %v", err)
+	}
+	return NewMemory(meta)
+}
+
+func items(prefix string, n int) []string {
+	result := make([]string, n)
+	for idx := range result {
+		result[idx] = fmt.Sprintf("%s-%d", prefix, idx)
+	}
+	return result
+}
+
+func TestNewMeta(t *testing.T) {
+	meta, err := NewMeta(models.BloomCreate{Name: "phones", Capacity: 1000, ErrorRate: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ⌈-1000·ln(0.01)/ln(2)²⌉ and 9586/1000·ln(2)
+	if meta.Bits != 9586 || meta.Hashes != 7 || meta.Storage != models.BloomStorageRedis {
+		t.Errorf("NewMeta = %d bits, %d hashes, %s", meta.Bits, meta.Hashes, meta.Storage)
+	}
+	for _, dataInput := range []models.BloomCreate{
+		{Name: "bad name", Capacity: 10, ErrorRate: 0.1},
+		{Name: "zero", ErrorRate: 0.1},
+		{Name: "rate", Capacity: 10, ErrorRate: 1},
+		{Name: "cuckoo", Type: models.BloomTypeCuckoo, Capacity: 10, ErrorRate: 0.1, Storage: models.BloomStorageRedis},
+	} {
+		if _, err := NewMeta(dataInput); !errors.Is(err, ErrInvalid) {
+			t.Errorf("NewMeta(%+v) = %v, want ErrInvalid", dataInput, err)
+		}
+	}
+}
+
+func TestFiltersKeepAddedItems(t *testing.T) {
+	for _, filterType := range []string{models.BloomTypeBloom, models.BloomTypeCuckoo} {
+		filter := newFilter(t, filterType, 2000, 0.01)
+		added := items("in", 2000)
+		if _, err := filter.Add(added); err != nil {
+			t.Fatalf("%s: Add: %v", filterType, err)
+		}
+		found, _ := filter.MightContain(added)
+		for idx, ok := range found {
+			if !ok {
+				t.Fatalf("%s: %s was added and is reported missing", filterType, added[idx])
+			}
+		}
+		// at capacity the false positives stay around the error rate
+		found, _ = filter.MightContain(items("out", 10000))
+		positives := 0
+		for _, ok := range found {
+			if ok {
+				positives++
+			}
+		}
+		if rate := float64(positives) / 10000; rate > 0.03 {
+			t.Errorf("%s: false positive rate %.4f, want about 0.01", filterType, rate)
+		}
+	}
+}
+
+func TestBloomCannotDelete(t *testing.T) {
+	filter := newFilter(t, models.BloomTypeBloom, 100, 0.01)
+	if _, err := filter.Delete([]string{"a"}); !errors.Is(err, ErrNoDelete) {
+		t.Errorf("Delete = %v, want ErrNoDelete", err)
+	}
+}
+
+func TestCuckooDelete(t *testing.T) {
+	filter := newFilter(t, models.BloomTypeCuckoo, 1000, 0.01)
+	filter.Add([]string{"a", "b"})
+	deleted, err := filter.Delete([]string{"a", "never"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted[0] || deleted[1] {
+		t.Errorf("Delete = %v, want [true false]", deleted)
+	}
+	found, _ := filter.MightContain([]string{"a", "b"})
+	if found[0] || !found[1] {
+		t.Errorf("MightContain after Delete = %v, want [false true]", found)
+	}
+	stats, _ := filter.Stats()
+	if stats.Count != 1 {
+		t.Errorf("Count = %d, want 1", stats.Count)
+	}
+}
+
+// sharedFingerprint finds two items with the same fingerprint and buckets, the filter cannot tell them apart
+func sharedFingerprint(t *testing.T, filter *CuckooFilter) (string, string) {
+	t.Helper()
+	seen := map[[2]uint64]string{}
+	for idx := 0; idx < 1000000; idx++ {
+		item := fmt.Sprintf("item-%d", idx)
+		fingerprint, first, second := filter.position(item)
+		if second < first {
+			first = second
+		}
+		key := [2]uint64{uint64(fingerprint), first}
+		if other, ok := seen[key]; ok {
+			return other, item
+		}
+		seen[key] = item
+	}
+	t.Fatal("no items sharing a fingerprint")
+	return "", ""
+}
+
+func TestCuckooSharedFingerprint(t *testing.T) {
+	filter := newFilter(t, models.BloomTypeCuckoo, 100, 0.01).(*CuckooFilter)
+	a, b := sharedFingerprint(t, filter)
+	added, err := filter.Add([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !added[0] || added[1] {
+		t.Errorf("Add = %v, want [true false], b looks already present", added)
+	}
+	if _, err := filter.Delete([]string{a}); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := filter.MightContain([]string{b}); !found[0] {
+		t.Error("deleting an item removed another one sharing its fingerprint")
+	}
+	filter.Delete([]string{b})
+	if found, _ := filter.MightContain([]string{a}); found[0] {
+		t.Error("both copies are deleted and the fingerprint is still found")
+	}
+	// copies stop at two full buckets
+	for idx := 0; idx < 3*filter.meta.BucketSize; idx++ {
+		if _, err := filter.Add([]string{a}); err != nil {
+			t.Fatalf("Add copy %d: %v", idx, err)
+		}
+	}
+	if stats, _ := filter.Stats(); stats.Count != uint64(2*filter.meta.BucketSize) {
+		t.Errorf("Count = %d, want %d copies", stats.Count, 2*filter.meta.BucketSize)
+	}
+}
@@ -0,0 +1,242 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"mine/internal/models"
+	"sync"
+)
+
+const (
+	cuckooBucketSize = 4
+	cuckooLoadFactor = 0.95
+	cuckooMaxKicks   = 500
+	// cuckooFingerprintBits bounds the error rate to 2·bucket size/2^16
+	cuckooFingerprintBits = 16
+)
+
+// CuckooFilter keeps a 16 bit fingerprint of every item in one of its two candidate buckets, so an item
+// can be deleted. Every Add stores a copy of the fingerprint, up to 2·bucket size copies, so two items
+// sharing a fingerprint each keep their own and deleting one leaves the other present. Deleting an item
+// never added may remove the fingerprint of another one
+type CuckooFilter struct {
+	meta models.BloomMeta
+	mu   sync.RWMutex
+	// slots holds Buckets·BucketSize fingerprints, 0 is an empty slot
+	slots []uint16
+	count uint64
+	// victim is the fingerprint left without a slot by the last kick chain, the filter is full while it is set
+	victim cuckooVictim
+	random *rand.Rand
+}
+
+// cuckooVictim fields are exported for encoding/binary
+type cuckooVictim struct {
+	Used        bool
+	Bucket      uint64
+	Fingerprint uint16
+}
+
+// cuckooMeta sizes the buckets, a power of two so the alternate bucket is found with a xor
+func cuckooMeta(capacity uint64, errorRate float64) (models.BloomMeta, error) {
+	minErrorRate := 2 * cuckooBucketSize / math.Pow(2, cuckooFingerprintBits)
+	if errorRate < minErrorRate {
+		return models.BloomMeta{}, fmt.Errorf("%w: a cuckoo filter has an error rate of %.6f at least", ErrInvalid, minErrorRate)
+	}
+	buckets := uint64(1)
+	for float64(buckets*cuckooBucketSize)*cuckooLoadFactor < float64(capacity) {
+		buckets <<= 1
+	}
+	if buckets*cuckooBucketSize*cuckooFingerprintBits > MaxBits {
+		return models.BloomMeta{}, fmt.Errorf("%w: %d buckets needed, at most %d bits", ErrInvalid, buckets, MaxBits)
+	}
+	return models.BloomMeta{
+		Type:       models.BloomTypeCuckoo,
+		Storage:    models.BloomStorageMemory,
+		Capacity:   capacity,
+		ErrorRate:  errorRate,
+		Bits:       buckets * cuckooBucketSize * cuckooFingerprintBits,
+		Buckets:    buckets,
+		BucketSize: cuckooBucketSize,
+	}, nil
+}
+
+func NewCuckooFilter(meta models.BloomMeta) *CuckooFilter {
+	meta.Storage = models.BloomStorageMemory
+	return &CuckooFilter{
+		meta:   meta,
+		slots:  make([]uint16, meta.Buckets*uint64(meta.BucketSize)),
+		random: rand.New(rand.NewSource(int64(meta.Buckets))),
+	}
+}
+
+func (f *CuckooFilter) Meta() models.BloomMeta {
+	return f.meta
+}
+
+// position returns the fingerprint of item and its two buckets
+func (f *CuckooFilter) position(item string) (uint16, uint64, uint64) {
+	h1, h2 := hash128(item)
+	fingerprint := uint16(h2 >> 48)
+	if fingerprint == 0 {
+		fingerprint = 1
+	}
+	bucket := h1 & (f.meta.Buckets - 1)
+	return fingerprint, bucket, f.alternate(bucket, fingerprint)
+}
+
+// alternate is its own inverse, a kicked fingerprint finds its other bucket without the item
+func (f *CuckooFilter) alternate(bucket uint64, fingerprint uint16) uint64 {
+	return (bucket ^ (uint64(fingerprint) * 0x5bd1e995)) & (f.meta.Buckets - 1)
+}
+
+func (f *CuckooFilter) bucket(index uint64) []uint16 {
+	size := uint64(f.meta.BucketSize)
+	return f.slots[index*size : (index+1)*size]
+}
+
+func (f *CuckooFilter) insertInto(index uint64, fingerprint uint16) bool {
+	bucket := f.bucket(index)
+	for slot := range bucket {
+		if bucket[slot] == 0 {
+			bucket[slot] = fingerprint
+			return true
+		}
+	}
+	return false
+}
+
+func (f *CuckooFilter) removeFrom(index uint64, fingerprint uint16) bool {
+	bucket := f.bucket(index)
+	for slot := range bucket {
+		if bucket[slot] == fingerprint {
+			bucket[slot] = 0
+			return true
+		}
+	}
+	return false
+}
+
+func (f *CuckooFilter) contains(fingerprint uint16, first, second uint64) bool {
+	return f.copies(fingerprint, first, second) > 0
+}
+
+// copies counts the fingerprint in the two buckets of an item and in the victim
+func (f *CuckooFilter) copies(fingerprint uint16, first, second uint64) int {
+	count := 0
+	indexes := []uint64{first}
+	if second != first {
+		indexes = append(indexes, second)
+	}
+	for _, index := range indexes {
+		for _, stored := range f.bucket(index) {
+			if stored == fingerprint {
+				count++
+			}
+		}
+	}
+	if f.victim.Used && f.victim.Fingerprint == fingerprint && (f.victim.Bucket == first || f.victim.Bucket == second) {
+		count++
+	}
+	return count
+}
+
+// insert moves fingerprints to their other bucket until one finds a free slot, the last one moved
+// becomes the victim when none does
+func (f *CuckooFilter) insert(fingerprint uint16, first, second uint64) {
+	if f.insertInto(first, fingerprint) || f.insertInto(second, fingerprint) {
+		return
+	}
+	index := first
+	if f.random.Intn(2) == 1 {
+		index = second
+	}
+	for kick := 0; kick < cuckooMaxKicks; kick++ {
+		bucket := f.bucket(index)
+		slot := f.random.Intn(len(bucket))
+		fingerprint, bucket[slot] = bucket[slot], fingerprint
+		index = f.alternate(index, fingerprint)
+		if f.insertInto(index, fingerprint) {
+			return
+		}
+	}
+	f.victim = cuckooVictim{Used: true, Bucket: index, Fingerprint: fingerprint}
+}
+
+func (f *CuckooFilter) Add(items []string) ([]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	added := make([]bool, len(items))
+	for idx, item := range items {
+		fingerprint, first, second := f.position(item)
+		copies := f.copies(fingerprint, first, second)
+		if copies >= 2*f.meta.BucketSize {
+			// both buckets hold only this fingerprint, the item is reported present without another copy
+			continue
+		}
+		if f.victim.Used {
+			return added, fmt.Errorf("%w: %q holds %d items", ErrFull, f.meta.Name, f.count)
+		}
+		f.insert(fingerprint, first, second)
+		f.count++
+		added[idx] = copies == 0
+	}
+	return added, nil
+}
+
+func (f *CuckooFilter) MightContain(items []string) ([]bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	found := make([]bool, len(items))
+	for idx, item := range items {
+		found[idx] = f.contains(f.position(item))
+	}
+	return found, nil
+}
+
+// Delete frees one slot holding the fingerprint of each item found, the victim then gets another chance at a slot
+func (f *CuckooFilter) Delete(items []string) ([]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deleted := make([]bool, len(items))
+	for idx, item := range items {
+		fingerprint, first, second := f.position(item)
+		switch {
+		case f.removeFrom(first, fingerprint) || f.removeFrom(second, fingerprint):
+		case f.victim.Used && f.victim.Fingerprint == fingerprint && (f.victim.Bucket == first || f.victim.Bucket == second):
+			f.victim = cuckooVictim{}
+		default:
+			continue
+		}
+		deleted[idx] = true
+		f.count--
+		if f.victim.Used {
+			victim := f.victim
+			f.victim = cuckooVictim{}
+			f.insert(victim.Fingerprint, victim.Bucket, f.alternate(victim.Bucket, victim.Fingerprint))
+		}
+	}
+	return deleted, nil
+}
+
+// Stats reports the load of the slots, the error rate grows with it up to 2·bucket size/2^16
+func (f *CuckooFilter) Stats() (*models.BloomStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	load := float64(f.count) / float64(len(f.slots))
+	return &models.BloomStats{
+		BloomMeta:          f.meta,
+		Count:              f.count,
+		BitsSet:            f.count * cuckooFingerprintBits,
+		FillRatio:          load,
+		EstimatedErrorRate: 1 - math.Pow(1-1/math.Pow(2, cuckooFingerprintBits), 2*float64(f.meta.BucketSize)*load),
+	}, nil
+}
+
+func (f *CuckooFilter) Drop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.slots, f.count, f.victim = make([]uint16, len(f.slots)), 0, cuckooVictim{}
+	return nil
+}
@@ -0,0 +1,80 @@
+package bloom
+
+import (
+	"math/bits"
+	"mine/internal/models"
+	"sync"
+)
+
+// MemoryFilter keeps the bits in process, it is lost on restart and not shared between replicas
+type MemoryFilter struct {
+	meta  models.BloomMeta
+	mu    sync.RWMutex
+	words []uint64
+	count uint64
+}
+
+func NewMemoryFilter(meta models.BloomMeta) *MemoryFilter {
+	meta.Storage = models.BloomStorageMemory
+	return &MemoryFilter{meta: meta, words: make([]uint64, (meta.Bits+63)/64)}
+}
+
+func (f *MemoryFilter) Meta() models.BloomMeta {
+	return f.meta
+}
+
+func (f *MemoryFilter) Add(items []string) ([]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	added := make([]bool, len(items))
+	for idx, item := range items {
+		for _, position := range locations(f.meta, item) {
+			word, mask := position/64, uint64(1)<<(position%64)
+			if f.words[word]&mask == 0 {
+				f.words[word] |= mask
+				added[idx] = true
+			}
+		}
+		if added[idx] {
+			f.count++
+		}
+	}
+	return added, nil
+}
+
+func (f *MemoryFilter) MightContain(items []string) ([]bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	found := make([]bool, len(items))
+	for idx, item := range items {
+		found[idx] = true
+		for _, position := range locations(f.meta, item) {
+			if f.words[position/64]&(uint64(1)<<(position%64)) == 0 {
+				found[idx] = false
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (f *MemoryFilter) Delete(items []string) ([]bool, error) {
+	return nil, ErrNoDelete
+}
+
+func (f *MemoryFilter) Stats() (*models.BloomStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	bitsSet := uint64(0)
+	for _, word := range f.words {
+		bitsSet += uint64(bits.OnesCount64(word))
+	}
+	return stats(f.meta, f.count, bitsSet), nil
+}
+
+func (f *MemoryFilter) Drop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.words, f.count = make([]uint64, len(f.words)), 0
+	return nil
+}
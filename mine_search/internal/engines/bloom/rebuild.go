@@ -0,0 +1,89 @@
+package bloom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// rebuildBatchSize is the number of items sent to the filter at once
+const rebuildBatchSize = 1000
+
+// AddJSONL adds the field value of every document of a jsonl export to filter, each element of an array field
+// is an item. It returns the number of items read, documents without the field are skipped
+func AddJSONL(filter Filter, reader io.Reader, field string) (int, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	batch := make([]string, 0, rebuildBatchSize)
+	total, lineNumber := 0, 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := filter.Add(batch)
+		total += len(batch)
+		batch = batch[:0]
+		return err
+	}
+	for scanner.Scan() {
+		lineNumber++
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+		document := map[string]interface{}{}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		// ids keep their digits, a float64 would round long ones
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return total, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		values, ok := document[field].([]interface{})
+		if !ok {
+			values = []interface{}{document[field]}
+		}
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+			batch = append(batch, fmt.Sprint(value))
+		}
+		if len(batch) >= rebuildBatchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return total, err
+	}
+	return total, flush()
+}
+
+// Replace overwrites the bits and the count with those of source, built with the same meta,
+// in one transaction so readers see the old filter or the new one
+func (f *RedisFilter) Replace(source *MemoryFilter) error {
+	if source.meta.Bits != f.meta.Bits || source.meta.Hashes != f.meta.Hashes {
+		return fmt.Errorf("%w: %q is not sized like the rebuilt filter", ErrInvalid, f.meta.Name)
+	}
+	source.mu.RLock()
+	// Redis numbers the bits of a byte from the most significant one
+	content := make([]byte, (f.meta.Bits+7)/8)
+	for idx, word := range source.words {
+		for word != 0 {
+			position := uint64(idx)*64 + uint64(bits.TrailingZeros64(word))
+			content[position/8] |= 0x80 >> (position % 8)
+			word &= word - 1
+		}
+	}
+	count := source.count
+	source.mu.RUnlock()
+	pipe := f.rdb.TxPipeline()
+	pipe.Set(f.key(), content, 0)
+	pipe.Set(f.key()+countSuffix, count, 0)
+	_, err := pipe.Exec()
+	return err
+}
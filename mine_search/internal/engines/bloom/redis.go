@@ -0,0 +1,185 @@
+package bloom
+
+import (
+	"encoding/json"
+	"fmt"
+	"mine/internal/models"
+
+	"github.com/go-redis/redis"
+)
+
+// KeyPrefix starts the keys of every filter: bloom:<name> holds the bits, bloom:<name>:meta the sizing
+// and bloom:<name>:count the number of added items
+const KeyPrefix = "bloom:"
+
+const (
+	metaSuffix  = ":meta"
+	countSuffix = ":count"
+)
+
+// RedisFilter keeps the bits in a Redis string with SETBIT and GETBIT, replicas opening the same name share it
+type RedisFilter struct {
+	rdb  *redis.Client
+	meta models.BloomMeta
+}
+
+// CreateRedisFilter writes the sizing with SETNX so two replicas creating the same name agree on it,
+// it returns ErrExists with the filter already there
+func CreateRedisFilter(rdb *redis.Client, meta models.BloomMeta) (*RedisFilter, error) {
+	meta.Type, meta.Storage = models.BloomTypeBloom, models.BloomStorageRedis
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	created, err := rdb.SetNX(KeyPrefix+meta.Name+metaSuffix, content, 0).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: %q", ErrExists, meta.Name)
+	}
+	return &RedisFilter{rdb: rdb, meta: meta}, nil
+}
+
+// OpenRedisFilter reads the sizing of a filter created by any replica
+func OpenRedisFilter(rdb *redis.Client, name string) (*RedisFilter, error) {
+	content, err := rdb.Get(KeyPrefix + name + metaSuffix).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := models.BloomMeta{}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return nil, err
+	}
+	// filters created before cuckoo filters existed have no type
+	meta.Type = models.BloomTypeBloom
+	return &RedisFilter{rdb: rdb, meta: meta}, nil
+}
+
+// ListRedisFilters returns the sizing of every filter in Redis
+func ListRedisFilters(rdb *redis.Client) ([]models.BloomMeta, error) {
+	keys := []string{}
+	cursor := uint64(0)
+	for {
+		page, next, err := rdb.Scan(cursor, KeyPrefix+"*"+metaSuffix, 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	metas := make([]models.BloomMeta, 0, len(keys))
+	if len(keys) == 0 {
+		return metas, nil
+	}
+	values, err := rdb.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for idx, value := range values {
+		content, ok := value.(string)
+		if !ok {
+			// dropped between SCAN and MGET
+			continue
+		}
+		meta := models.BloomMeta{}
+		if err := json.Unmarshal([]byte(content), &meta); err != nil {
+			return nil, fmt.Errorf("%s: %w", keys[idx], err)
+		}
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+func (f *RedisFilter) Meta() models.BloomMeta {
+	return f.meta
+}
+
+func (f *RedisFilter) key() string {
+	return KeyPrefix + f.meta.Name
+}
+
+// Add sends every SETBIT in one pipeline, an item is new when one of its bits was 0 before. Two replicas adding
+// the same new item at once may both count it
+func (f *RedisFilter) Add(items []string) ([]bool, error) {
+	pipe := f.rdb.Pipeline()
+	cmds := make([][]*redis.IntCmd, len(items))
+	for idx, item := range items {
+		for _, position := range locations(f.meta, item) {
+			cmds[idx] = append(cmds[idx], pipe.SetBit(f.key(), int64(position), 1))
+		}
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+	added := make([]bool, len(items))
+	count := int64(0)
+	for idx := range items {
+		for _, cmd := range cmds[idx] {
+			if cmd.Val() == 0 {
+				added[idx] = true
+			}
+		}
+		if added[idx] {
+			count++
+		}
+	}
+	if count > 0 {
+		if err := f.rdb.IncrBy(f.key()+countSuffix, count).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+func (f *RedisFilter) MightContain(items []string) ([]bool, error) {
+	pipe := f.rdb.Pipeline()
+	cmds := make([][]*redis.IntCmd, len(items))
+	for idx, item := range items {
+		for _, position := range locations(f.meta, item) {
+			cmds[idx] = append(cmds[idx], pipe.GetBit(f.key(), int64(position)))
+		}
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+	found := make([]bool, len(items))
+	for idx := range items {
+		found[idx] = true
+		for _, cmd := range cmds[idx] {
+			if cmd.Val() == 0 {
+				found[idx] = false
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (f *RedisFilter) Delete(items []string) ([]bool, error) {
+	return nil, ErrNoDelete
+}
+
+func (f *RedisFilter) Stats() (*models.BloomStats, error) {
+	pipe := f.rdb.Pipeline()
+	count := pipe.Get(f.key() + countSuffix)
+	bitsSet := pipe.BitCount(f.key(), nil)
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	// the count key is missing until the first item is added
+	added, err := count.Uint64()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return stats(f.meta, added, uint64(bitsSet.Val())), nil
+}
+
+func (f *RedisFilter) Drop() error {
+	return f.rdb.Del(f.key(), f.key()+metaSuffix, f.key()+countSuffix).Err()
+}
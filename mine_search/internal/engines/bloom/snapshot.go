@@ -0,0 +1,243 @@
+package bloom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mine/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// snapshotMagic starts every snapshot, the version follows it
+const (
+	snapshotMagic   = "MFLT"
+	snapshotVersion = 1
+)
+
+// Snapshot stores
+const (
+	SnapshotStoreFile  = "file"
+	SnapshotStoreRedis = "redis"
+	// SnapshotSuffix ends the snapshot files, SnapshotKeyPrefix starts the Redis keys holding them
+	SnapshotSuffix    = ".filter"
+	SnapshotKeyPrefix = "bloom-snapshot:"
+	// DefaultSnapshotInterval is how often the scheduler saves the in-process filters
+	DefaultSnapshotInterval = 5 * time.Minute
+)
+
+// Snapshotter is an in-process filter, a Redis filter needs no snapshot
+type Snapshotter interface {
+	Filter
+	WriteSnapshot(writer io.Writer) error
+}
+
+// WriteSnapshot writes magic, version, the meta as length prefixed json, the count and the bit words, little endian
+func (f *MemoryFilter) WriteSnapshot(writer io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return writeSnapshot(writer, f.meta, f.count, f.words)
+}
+
+// WriteSnapshot writes the header of MemoryFilter.WriteSnapshot, then the victim and the fingerprints
+func (f *CuckooFilter) WriteSnapshot(writer io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return writeSnapshot(writer, f.meta, f.count, f.victim, f.slots)
+}
+
+func writeSnapshot(writer io.Writer, meta models.BloomMeta, count uint64, payload ...interface{}) error {
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(snapshotMagic)
+	for _, value := range append([]interface{}{uint8(snapshotVersion), uint32(len(content)), content, count}, payload...) {
+		if err := binary.Write(buffered, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// ReadSnapshot rebuilds the filter written by WriteSnapshot
+func ReadSnapshot(reader io.Reader) (Snapshotter, error) {
+	buffered := bufio.NewReader(reader)
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(buffered, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic || header[len(snapshotMagic)] != snapshotVersion {
+		return nil, errors.New("not a filter snapshot")
+	}
+	length := uint32(0)
+	if err := binary.Read(buffered, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(buffered, content); err != nil {
+		return nil, err
+	}
+	meta := models.BloomMeta{}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Type == models.BloomTypeCuckoo {
+		filter := NewCuckooFilter(meta)
+		for _, value := range []interface{}{&filter.count, &filter.victim, filter.slots} {
+			if err := binary.Read(buffered, binary.LittleEndian, value); err != nil {
+				return nil, err
+			}
+		}
+		return filter, nil
+	}
+	filter := NewMemoryFilter(meta)
+	for _, value := range []interface{}{&filter.count, filter.words} {
+		if err := binary.Read(buffered, binary.LittleEndian, value); err != nil {
+			return nil, err
+		}
+	}
+	return filter, nil
+}
+
+// SnapshotStore keeps one snapshot per filter name
+type SnapshotStore interface {
+	Save(filter Snapshotter) error
+	// Load reads every snapshot, a snapshot that cannot be read is reported by name and skipped
+	Load() ([]Snapshotter, map[string]error, error)
+	Remove(name string) error
+}
+
+// NewSnapshotStore returns nil when store is empty, snapshots are then off
+func NewSnapshotStore(store, dir string, rdb *redis.Client) (SnapshotStore, error) {
+	switch store {
+	case "":
+		return nil, nil
+	case SnapshotStoreFile:
+		if dir == "" {
+			return nil, errors.New("the file snapshot store needs a directory")
+		}
+		return &fileStore{dir: dir}, nil
+	case SnapshotStoreRedis:
+		return &redisStore{rdb: rdb}, nil
+	}
+	return nil, fmt.Errorf("unknown snapshot store %q", store)
+}
+
+// fileStore writes <dir>/<name>.filter through a temporary file so a crash never leaves half a snapshot
+type fileStore struct {
+	dir string
+}
+
+func (s *fileStore) Save(filter Snapshotter) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, filter.Meta().Name+SnapshotSuffix)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := filter.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *fileStore) Load() ([]Snapshotter, map[string]error, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+SnapshotSuffix))
+	if err != nil {
+		return nil, nil, err
+	}
+	filters, failed := []Snapshotter{}, map[string]error{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			failed[path] = err
+			continue
+		}
+		filter, err := ReadSnapshot(file)
+		file.Close()
+		if err != nil {
+			failed[path] = err
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	return filters, failed, nil
+}
+
+func (s *fileStore) Remove(name string) error {
+	err := os.Remove(filepath.Join(s.dir, name+SnapshotSuffix))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// redisStore writes bloom-snapshot:<name>, replicas saving the same filter overwrite each other
+type redisStore struct {
+	rdb *redis.Client
+}
+
+func (s *redisStore) Save(filter Snapshotter) error {
+	content := bytes.Buffer{}
+	if err := filter.WriteSnapshot(&content); err != nil {
+		return err
+	}
+	return s.rdb.Set(SnapshotKeyPrefix+filter.Meta().Name, content.Bytes(), 0).Err()
+}
+
+func (s *redisStore) Load() ([]Snapshotter, map[string]error, error) {
+	keys := []string{}
+	cursor := uint64(0)
+	for {
+		page, next, err := s.rdb.Scan(cursor, SnapshotKeyPrefix+"*", 1000).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, page...)
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	filters, failed := []Snapshotter{}, map[string]error{}
+	for _, key := range keys {
+		content, err := s.rdb.Get(key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		filter, err := ReadSnapshot(bytes.NewReader(content))
+		if err != nil {
+			failed[strings.TrimPrefix(key, SnapshotKeyPrefix)] = err
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	return filters, failed, nil
+}
+
+func (s *redisStore) Remove(name string) error {
+	return s.rdb.Del(SnapshotKeyPrefix + name).Err()
+}
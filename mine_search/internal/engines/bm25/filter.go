@@ -0,0 +1,199 @@
+package bm25
+
+import (
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"strconv"
+)
+
+// matchSearch applies the conditions, the filter tree and the geo area of a validated request to document
+func (x *Index) matchSearch(dataInput models.Search, document map[string]interface{}) bool {
+	for _, condition := range dataInput.Conditions {
+		if !x.matchCondition(condition, document) {
+			return false
+		}
+	}
+	if dataInput.Filter != nil && !x.matchFilter(*dataInput.Filter, document) {
+		return false
+	}
+	if dataInput.Geo != nil && !x.matchGeo(dataInput.Geo, document) {
+		return false
+	}
+	return true
+}
+
+func (x *Index) matchFilter(filter models.FilterSearching, document map[string]interface{}) bool {
+	if filter.Condition != nil {
+		return x.matchCondition(*filter.Condition, document)
+	}
+	switch filter.FilterOperator {
+	case models.FilterOr:
+		for _, child := range filter.Filters {
+			if x.matchFilter(child, document) {
+				return true
+			}
+		}
+		return false
+	case models.FilterNot:
+		for _, child := range filter.Filters {
+			if x.matchFilter(child, document) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, child := range filter.Filters {
+		if !x.matchFilter(child, document) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCondition compares every value of an array field, ne and not_in hold when no value matches
+func (x *Index) matchCondition(condition models.ConditionSearching, document map[string]interface{}) bool {
+	field, _ := x.schema.GetField(condition.ConditionName)
+	values := documentValues(document[field.Name])
+	operator := condition.Operator()
+	switch operator {
+	case models.OperatorExists:
+		return len(values) > 0
+	case models.OperatorNe:
+		return !anyValue(values, func(value interface{}) bool { return compare(field, value, condition.ConditionValue) == 0 })
+	case models.OperatorNotIn:
+		return !anyValue(values, func(value interface{}) bool { return inValues(field, value, condition.ConditionValues) })
+	}
+	return anyValue(values, func(value interface{}) bool {
+		switch operator {
+		case models.OperatorGt:
+			return compare(field, value, condition.ConditionValue) > 0
+		case models.OperatorGte:
+			return compare(field, value, condition.ConditionValue) >= 0
+		case models.OperatorLt:
+			return compare(field, value, condition.ConditionValue) < 0
+		case models.OperatorLte:
+			return compare(field, value, condition.ConditionValue) <= 0
+		case models.OperatorRange:
+			return compare(field, value, condition.ConditionValues[0]) >= 0 && compare(field, value, condition.ConditionValues[1]) <= 0
+		case models.OperatorIn:
+			return inValues(field, value, condition.ConditionValues)
+		}
+		return compare(field, value, condition.ConditionValue) == 0
+	})
+}
+
+func (x *Index) matchGeo(geo *models.GeoSearching, document map[string]interface{}) bool {
+	point, ok := geoPoint(x.schema, geo, document)
+	if !ok {
+		return false
+	}
+	switch {
+	case geo.RadiusKm > 0:
+		return query_builder.DistanceKm(*geo.Point, point) <= geo.RadiusKm
+	case geo.BoundingBox != nil:
+		box := geo.BoundingBox
+		return point.Lat <= box.TopLeft.Lat && point.Lat >= box.BottomRight.Lat &&
+			point.Lng >= box.TopLeft.Lng && point.Lng <= box.BottomRight.Lng
+	case len(geo.Polygon) > 0:
+		return inPolygon(point, geo.Polygon)
+	}
+	return true
+}
+
+func geoPoint(schema *models.CollectionSchema, geo *models.GeoSearching, document map[string]interface{}) (models.GeoPoint, bool) {
+	field, _ := query_builder.GeoField(schema, geo)
+	point, ok := document[field.Name].([]float64)
+	if !ok || len(point) != 2 {
+		return models.GeoPoint{}, false
+	}
+	return models.GeoPoint{Lat: point[0], Lng: point[1]}, true
+}
+
+// inPolygon casts a ray along the latitude of point and counts the edges it crosses
+func inPolygon(point models.GeoPoint, polygon []models.GeoPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Lng < (b.Lng-a.Lng)*(point.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func documentValues(value interface{}) []interface{} {
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case []string:
+		result := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			result = append(result, item)
+		}
+		return result
+	}
+	return []interface{}{value}
+}
+
+func anyValue(values []interface{}, match func(interface{}) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func inValues(field *models.CollectionField, value interface{}, candidates []string) bool {
+	for _, candidate := range candidates {
+		if compare(field, value, candidate) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders a document value and a request value, numbers numerically and strings exactly
+func compare(field *models.CollectionField, value interface{}, target string) int {
+	switch {
+	case field.IsNumeric():
+		number, ok := toFloat(value)
+		expected, err := strconv.ParseFloat(target, 64)
+		if !ok || err != nil {
+			return -2
+		}
+		switch {
+		case number < expected:
+			return -1
+		case number > expected:
+			return 1
+		}
+		return 0
+	case field.IsBool():
+		flag, _ := value.(bool)
+		expected, _ := strconv.ParseBool(target)
+		if flag == expected {
+			return 0
+		}
+		return -2
+	}
+	text, _ := value.(string)
+	switch {
+	case text < target:
+		return -1
+	case text > target:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case int64:
+		return float64(typed), true
+	case float64:
+		return typed, true
+	}
+	return 0, false
+}
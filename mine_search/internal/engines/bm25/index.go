@@ -0,0 +1,209 @@
+// Package bm25 is an in-process full text index for collections small enough to fit in memory.
+// It answers models.Search like Typesense does: BM25 scoring weighted by field, prefix matching
+// on the last token, typo tolerance, filters, facets, geo areas and sorting
+package bm25
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/schemas"
+	"mine/internal/utils"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Options struct {
+	K1 float64
+	B  float64
+	// FieldWeights boosts query_by fields, by default the first query_by field weighs the most
+	FieldWeights map[string]float64
+	// OneTypoMinLen and TwoTyposMinLen are the token lengths from which 1 and 2 typos are tolerated
+	OneTypoMinLen  int
+	TwoTyposMinLen int
+	// MaxPrefixTerms bounds the terms a prefix expands to, the shortest terms are kept
+	MaxPrefixTerms int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		K1:             1.2,
+		B:              0.75,
+		OneTypoMinLen:  4,
+		TwoTyposMinLen: 7,
+		MaxPrefixTerms: 50,
+	}
+}
+
+// Index holds the documents of one collection, it is safe for concurrent use
+type Index struct {
+	mu      sync.RWMutex
+	schema  *models.CollectionSchema
+	options Options
+	weights map[string]float64
+	// documents are typed by the schema, keyed by id
+	documents map[string]map[string]interface{}
+	// postings maps a term to the documents holding it and its frequency in each query_by field
+	postings map[string]map[string]map[string]int
+	// lengths is the number of terms of every query_by field of every document
+	lengths      map[string]map[string]int
+	totalLengths map[string]int
+	vocabulary   []string
+	sortedVocab  bool
+}
+
+func New(schema *models.CollectionSchema, options Options) *Index {
+	weights := map[string]float64{}
+	for idx, name := range schema.QueryBy {
+		weights[name] = float64(len(schema.QueryBy) - idx)
+	}
+	for name, weight := range options.FieldWeights {
+		weights[name] = weight
+	}
+	return &Index{
+		schema:       schema,
+		options:      options,
+		weights:      weights,
+		documents:    map[string]map[string]interface{}{},
+		postings:     map[string]map[string]map[string]int{},
+		lengths:      map[string]map[string]int{},
+		totalLengths: map[string]int{},
+	}
+}
+
+func (x *Index) Schema() *models.CollectionSchema {
+	return x.schema
+}
+
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.documents)
+}
+
+// Upsert indexes document, replacing the document with the same id
+func (x *Index) Upsert(document map[string]interface{}) error {
+	id, ok := document["id"]
+	if !ok || fmt.Sprint(id) == "" {
+		return fmt.Errorf("document has no id")
+	}
+	typed := schemas.DocumentFromRow(x.schema, document)
+	for _, field := range x.schema.Fields {
+		if _, ok := typed[field.Name]; !ok && !field.Optional {
+			return fmt.Errorf("document %v has no field %s", id, field.Name)
+		}
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(typed["id"].(string))
+	x.add(typed)
+	return nil
+}
+
+func (x *Index) Delete(id string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.remove(id)
+}
+
+// Get returns the document id
+func (x *Index) Get(id string) (map[string]interface{}, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	document, ok := x.documents[id]
+	return document, ok
+}
+
+// Documents returns every document, in no particular order
+func (x *Index) Documents() []map[string]interface{} {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	result := make([]map[string]interface{}, 0, len(x.documents))
+	for _, document := range x.documents {
+		result = append(result, document)
+	}
+	return result
+}
+
+func (x *Index) add(document map[string]interface{}) {
+	id := document["id"].(string)
+	x.documents[id] = document
+	x.lengths[id] = map[string]int{}
+	for _, name := range x.schema.QueryBy {
+		terms := Analyze(fieldText(document[name]))
+		x.lengths[id][name] = len(terms)
+		x.totalLengths[name] += len(terms)
+		for _, term := range terms {
+			docs, ok := x.postings[term]
+			if !ok {
+				docs = map[string]map[string]int{}
+				x.postings[term] = docs
+				x.vocabulary = append(x.vocabulary, term)
+				x.sortedVocab = false
+			}
+			if docs[id] == nil {
+				docs[id] = map[string]int{}
+			}
+			docs[id][name]++
+		}
+	}
+}
+
+func (x *Index) remove(id string) bool {
+	document, ok := x.documents[id]
+	if !ok {
+		return false
+	}
+	for _, name := range x.schema.QueryBy {
+		x.totalLengths[name] -= x.lengths[id][name]
+		for _, term := range Analyze(fieldText(document[name])) {
+			if docs, ok := x.postings[term]; ok {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(x.postings, term)
+					x.sortedVocab = false
+				}
+			}
+		}
+	}
+	delete(x.lengths, id)
+	delete(x.documents, id)
+	return true
+}
+
+// sortVocabulary drops removed terms and sorts the vocabulary for prefix lookups, the caller holds the write lock
+func (x *Index) sortVocabulary() {
+	if x.sortedVocab {
+		return
+	}
+	vocabulary := x.vocabulary[:0]
+	seen := map[string]bool{}
+	for _, term := range x.vocabulary {
+		if _, ok := x.postings[term]; ok && !seen[term] {
+			seen[term] = true
+			vocabulary = append(vocabulary, term)
+		}
+	}
+	sort.Strings(vocabulary)
+	x.vocabulary = vocabulary
+	x.sortedVocab = true
+}
+
+func fieldText(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case []string:
+		return strings.Join(typed, " ")
+	}
+	return ""
+}
+
+// Analyze tokenizes text and folds Vietnamese accents, "Hà Nội" and "ha noi" give the same terms
+func Analyze(text string) []string {
+	tokens := utils.Tokenize(text)
+	for idx, token := range tokens {
+		tokens[idx] = utils.Fold(token)
+	}
+	return tokens
+}
@@ -0,0 +1,552 @@
+package bm25
+
+import (
+	"math"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Penalties applied to the score of a term reached by expanding a query token
+const (
+	prefixPenalty   = 0.8
+	oneTypoPenalty  = 0.5
+	twoTyposPenalty = 0.25
+)
+
+// defaultMaxFacetValues is the number of values returned per facet when a request does not say, as Typesense does
+const defaultMaxFacetValues = 10
+
+// expansion is a vocabulary term a query token matches, with the penalty of the match
+type expansion struct {
+	term    string
+	penalty float64
+}
+
+type match struct {
+	id       string
+	document map[string]interface{}
+	tokens   int
+	score    float64
+	terms    []string
+	distance *float64
+}
+
+// Search answers a validated request, cursors are left to the caller which applies them as page and per_page
+func (x *Index) Search(dataInput models.Search) (*models.SearchResult, error) {
+	start := time.Now()
+	if err := query_builder.ValidateSearch(x.schema, dataInput); err != nil {
+		return nil, err
+	}
+	x.prepare()
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	tokens := []string{}
+	if text := strings.TrimSpace(dataInput.Text); text != "*" {
+		tokens = Analyze(text)
+	}
+	expansions := make([][]expansion, 0, len(tokens))
+	for idx, token := range tokens {
+		expansions = append(expansions, x.expand(token, idx == len(tokens)-1))
+	}
+
+	// a text query only reaches the documents holding a term it expands to, "*" reaches all of them
+	documents := x.documents
+	if len(tokens) > 0 {
+		documents = x.candidates(expansions)
+	}
+	matches := []*match{}
+	for id, document := range documents {
+		item := &match{id: id, document: document}
+		if len(tokens) > 0 {
+			x.score(item, expansions)
+			if item.tokens == 0 {
+				continue
+			}
+		}
+		if !x.matchSearch(dataInput, document) {
+			continue
+		}
+		if geo := dataInput.Geo; geo != nil && geo.Point != nil {
+			if point, ok := geoPoint(x.schema, geo, document); ok {
+				distance := query_builder.DistanceKm(*geo.Point, point) * 1000
+				item.distance = &distance
+			}
+		}
+		matches = append(matches, item)
+	}
+	x.sortMatches(dataInput, matches)
+
+	perPage := query_builder.PerPage(dataInput)
+	page := query_builder.CurrentPage(dataInput)
+	result := &models.SearchResult{
+		Found:   int64(len(matches)),
+		Page:    page,
+		PerPage: perPage,
+		Hits:    []models.SearchHit{},
+		Facets:  x.facets(dataInput, matches),
+	}
+	from := (page - 1) * perPage
+	for idx := from; idx < len(matches) && idx < from+perPage; idx++ {
+		item := matches[idx]
+		result.Hits = append(result.Hits, models.SearchHit{
+			Document:       item.document,
+			TextMatch:      item.score,
+			Highlights:     x.highlights(item),
+			DistanceMeters: item.distance,
+		})
+	}
+	result.SearchTimeMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// prepare sorts the vocabulary once after a write so prefix lookups can binary search it
+func (x *Index) prepare() {
+	x.mu.RLock()
+	sorted := x.sortedVocab
+	x.mu.RUnlock()
+	if sorted {
+		return
+	}
+	x.mu.Lock()
+	x.sortVocabulary()
+	x.mu.Unlock()
+}
+
+// expand lists the terms token matches: itself, the terms it prefixes when it is the last token,
+// and the terms within the typos its length allows when it is not a term itself
+func (x *Index) expand(token string, last bool) []expansion {
+	result := []expansion{}
+	_, exact := x.postings[token]
+	if exact {
+		result = append(result, expansion{term: token, penalty: 1})
+	}
+	if last {
+		for _, term := range x.prefixed(token) {
+			if term != token {
+				result = append(result, expansion{term: term, penalty: prefixPenalty})
+			}
+		}
+	}
+	if exact {
+		return result
+	}
+	maxTypos := 0
+	length := utf8.RuneCountInString(token)
+	switch {
+	case x.options.TwoTyposMinLen > 0 && length >= x.options.TwoTyposMinLen:
+		maxTypos = 2
+	case x.options.OneTypoMinLen > 0 && length >= x.options.OneTypoMinLen:
+		maxTypos = 1
+	}
+	if maxTypos == 0 {
+		return result
+	}
+	for term := range x.postings {
+		if distance := editDistance(token, term, maxTypos); distance > 0 && distance <= maxTypos {
+			penalty := oneTypoPenalty
+			if distance == 2 {
+				penalty = twoTyposPenalty
+			}
+			result = append(result, expansion{term: term, penalty: penalty})
+		}
+	}
+	return result
+}
+
+// prefixed returns the terms starting with prefix, keeping the MaxPrefixTerms shortest ones
+func (x *Index) prefixed(prefix string) []string {
+	terms := []string{}
+	if x.sortedVocab {
+		for idx := sort.SearchStrings(x.vocabulary, prefix); idx < len(x.vocabulary) && strings.HasPrefix(x.vocabulary[idx], prefix); idx++ {
+			terms = append(terms, x.vocabulary[idx])
+		}
+	} else {
+		// a write landed since prepare, the postings are the source of truth
+		for term := range x.postings {
+			if strings.HasPrefix(term, prefix) {
+				terms = append(terms, term)
+			}
+		}
+	}
+	if x.options.MaxPrefixTerms > 0 && len(terms) > x.options.MaxPrefixTerms {
+		sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) < len(terms[j]) })
+		terms = terms[:x.options.MaxPrefixTerms]
+	}
+	return terms
+}
+
+// candidates returns the documents in the postings of at least one expansion, keyed by id
+func (x *Index) candidates(expansions [][]expansion) map[string]map[string]interface{} {
+	result := map[string]map[string]interface{}{}
+	for _, tokenExpansions := range expansions {
+		for _, candidate := range tokenExpansions {
+			for id := range x.postings[candidate.term] {
+				if document, ok := x.documents[id]; ok {
+					result[id] = document
+				}
+			}
+		}
+	}
+	return result
+}
+
+// score adds the best BM25 score of every query token to item, each token counts once
+// through its best expansion
+func (x *Index) score(item *match, expansions [][]expansion) {
+	total := float64(len(x.documents))
+	for _, candidates := range expansions {
+		best, bestTerm := 0.0, ""
+		for _, candidate := range candidates {
+			docs := x.postings[candidate.term]
+			fields, ok := docs[item.id]
+			if !ok {
+				continue
+			}
+			idf := math.Log(1 + (total-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+			value := 0.0
+			for name, frequency := range fields {
+				average := float64(x.totalLengths[name]) / total
+				norm := 1 - x.options.B
+				if average > 0 {
+					norm += x.options.B * float64(x.lengths[item.id][name]) / average
+				}
+				tf := float64(frequency)
+				value += x.weights[name] * idf * tf * (x.options.K1 + 1) / (tf + x.options.K1*norm)
+			}
+			if value *= candidate.penalty; value > best {
+				best, bestTerm = value, candidate.term
+			}
+		}
+		if bestTerm != "" {
+			item.tokens++
+			item.score += best
+			item.terms = append(item.terms, bestTerm)
+		}
+	}
+}
+
+// sortMatches orders by distance, then order_bys, then matched tokens and score,
+// then default_sorting_field, the id keeps pages stable
+func (x *Index) sortMatches(dataInput models.Search, matches []*match) {
+	descDistance := dataInput.Geo != nil && query_builder.GeoDistanceOrder(dataInput.Geo) == "desc"
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.distance != nil && b.distance != nil && *a.distance != *b.distance {
+			return (*a.distance < *b.distance) != descDistance
+		}
+		for _, orderBy := range dataInput.OrderBys {
+			if order := compareValues(a.document[orderBy.OrderByName], b.document[orderBy.OrderByName]); order != 0 {
+				return (order < 0) == strings.EqualFold(orderBy.OrderByValue, "asc")
+			}
+		}
+		if a.tokens != b.tokens {
+			return a.tokens > b.tokens
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if name := x.schema.DefaultSortingField; name != "" {
+			if order := compareValues(a.document[name], b.document[name]); order != 0 {
+				return order > 0
+			}
+		}
+		return a.id < b.id
+	})
+}
+
+func compareValues(a, b interface{}) int {
+	if numberA, ok := toFloat(a); ok {
+		numberB, _ := toFloat(b)
+		switch {
+		case numberA < numberB:
+			return -1
+		case numberA > numberB:
+			return 1
+		}
+		return 0
+	}
+	if flagA, ok := a.(bool); ok {
+		flagB, _ := b.(bool)
+		switch {
+		case flagA == flagB:
+			return 0
+		case flagB:
+			return -1
+		}
+		return 1
+	}
+	textA, _ := a.(string)
+	textB, _ := b.(string)
+	return strings.Compare(textA, textB)
+}
+
+// highlights marks the matched terms in every query_by field of a hit
+func (x *Index) highlights(item *match) map[string][]string {
+	result := map[string][]string{}
+	if len(item.terms) == 0 {
+		return result
+	}
+	for _, name := range x.schema.QueryBy {
+		values := []string{}
+		switch typed := item.document[name].(type) {
+		case string:
+			values = append(values, typed)
+		case []string:
+			values = typed
+		}
+		for _, value := range values {
+			if marked, ok := highlight(value, item.terms); ok {
+				result[name] = append(result[name], marked)
+			}
+		}
+	}
+	return result
+}
+
+// highlight wraps the words of text whose folded form is one of terms in <mark></mark>
+func highlight(text string, terms []string) (string, bool) {
+	return markWords(text, func(word string) bool {
+		for _, term := range terms {
+			if word == term {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// markWords wraps the words of text accepted by marked in <mark></mark>, marked is given the folded word
+func markWords(text string, marked func(string) bool) (string, bool) {
+	var builder strings.Builder
+	matched := false
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if end == start {
+			builder.WriteRune(runes[start])
+			start++
+			continue
+		}
+		word := string(runes[start:end])
+		if marked(utils.Fold(strings.ToLower(word))) {
+			matched = true
+			builder.WriteString("<mark>" + word + "</mark>")
+		} else {
+			builder.WriteString(word)
+		}
+		start = end
+	}
+	return builder.String(), matched
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// facets counts the values of the requested facets over every match, not only the current page
+func (x *Index) facets(dataInput models.Search, matches []*match) []models.FacetResult {
+	result := make([]models.FacetResult, 0, len(dataInput.FacetBy))
+	limit := dataInput.MaxFacetValues
+	if limit == 0 {
+		limit = defaultMaxFacetValues
+	}
+	queryName, queryValue, _ := strings.Cut(dataInput.FacetQuery, ":")
+	queryTokens := Analyze(queryValue)
+	for _, facet := range dataInput.FacetBy {
+		field, _ := x.schema.GetField(facet.FacetName)
+		counts := map[string]int64{}
+		order := []string{}
+		stats := &models.FacetStats{}
+		numbers := map[float64]bool{}
+		for _, item := range matches {
+			for _, value := range documentValues(item.document[field.Name]) {
+				if number, ok := toFloat(value); ok {
+					addStat(stats, number)
+					numbers[number] = true
+				}
+				for _, label := range facetLabels(facet, value) {
+					if _, ok := counts[label]; !ok {
+						order = append(order, label)
+					}
+					counts[label]++
+				}
+			}
+		}
+		if field.IsNumeric() {
+			stats.TotalValues = int64(len(numbers))
+			if stats.Sum != nil && stats.TotalValues > 0 {
+				average := *stats.Sum / float64(countValues(matches, field.Name))
+				stats.Avg = &average
+			}
+		} else {
+			stats = &models.FacetStats{TotalValues: int64(len(order))}
+		}
+		if len(facet.FacetRanges) > 0 {
+			// range buckets keep the order of the request
+			order = order[:0]
+			for _, facetRange := range facet.FacetRanges {
+				if counts[facetRange.Label] > 0 {
+					order = append(order, facetRange.Label)
+				}
+			}
+		} else {
+			sort.SliceStable(order, func(i, j int) bool {
+				if counts[order[i]] != counts[order[j]] {
+					return counts[order[i]] > counts[order[j]]
+				}
+				return order[i] < order[j]
+			})
+		}
+		values := []models.FacetValue{}
+		for _, value := range order {
+			facetValue := models.FacetValue{Value: value, Count: counts[value]}
+			if queryName == facet.FacetName {
+				marked, ok := facetHighlight(value, queryTokens)
+				if !ok {
+					continue
+				}
+				facetValue.Highlighted = marked
+			}
+			values = append(values, facetValue)
+			if len(values) == limit {
+				break
+			}
+		}
+		result = append(result, models.FacetResult{Field: facet.FacetName, Values: values, Stats: stats})
+	}
+	return result
+}
+
+// facetLabels is the value itself, or the labels of the ranges holding it
+func facetLabels(facet models.FacetSearching, value interface{}) []string {
+	if len(facet.FacetRanges) == 0 {
+		return []string{formatValue(value)}
+	}
+	number, ok := toFloat(value)
+	if !ok {
+		return nil
+	}
+	labels := []string{}
+	for _, facetRange := range facet.FacetRanges {
+		if number >= facetRange.Min && number < facetRange.Max {
+			labels = append(labels, facetRange.Label)
+		}
+	}
+	return labels
+}
+
+// facetHighlight keeps the facet values having a word starting with every facet_query token
+func facetHighlight(value string, tokens []string) (string, bool) {
+	words := Analyze(value)
+	for _, token := range tokens {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, token) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return markWords(value, func(word string) bool {
+		for _, token := range tokens {
+			if strings.HasPrefix(word, token) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func addStat(stats *models.FacetStats, number float64) {
+	if stats.Min == nil || number < *stats.Min {
+		value := number
+		stats.Min = &value
+	}
+	if stats.Max == nil || number > *stats.Max {
+		value := number
+		stats.Max = &value
+	}
+	sum := number
+	if stats.Sum != nil {
+		sum += *stats.Sum
+	}
+	stats.Sum = &sum
+}
+
+func countValues(matches []*match, name string) int {
+	count := 0
+	for _, item := range matches {
+		count += len(documentValues(item.document[name]))
+	}
+	return count
+}
+
+func formatValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case int64:
+		return strconv.FormatInt(typed, 10)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	}
+	return ""
+}
+
+// editDistance is the Levenshtein distance between a and b, it stops at max+1 once no cheaper path is left
+func editDistance(a, b string, max int) int {
+	runesA, runesB := []rune(a), []rune(b)
+	if diff := len(runesA) - len(runesB); diff > max || -diff > max {
+		return max + 1
+	}
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(runesA); i++ {
+		current[0] = i
+		lowest := current[0]
+		for j := 1; j <= len(runesB); j++ {
+			cost := 1
+			if runesA[i-1] == runesB[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if current[j] < lowest {
+				lowest = current[j]
+			}
+		}
+		if lowest > max {
+			return max + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(runesB)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package bm25
+
+import (
+	"mine/internal/models"
+	"testing"
+)
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	schema := &models.CollectionSchema{
+		Name: "books",
+		Fields: []models.CollectionField{
+			{Name: "title", Type: "string"},
+			{Name: "authors", Type: "string[]"},
+			{Name: "ratings_count", Type: "int32", Sort: true},
+		},
+		DefaultSortingField: "ratings_count",
+		QueryBy:             []string{"title", "authors"},
+	}
+	index := New(schema, DefaultOptions())
+	documents := []map[string]interface{}{
+		{"id": "1", "title": "Harry Potter and the Philosopher's Stone", "authors": []string{"J.K. Rowling"}, "ratings_count": 100},
+		{"id": "2", "title": "Harry Potter and the Chamber of Secrets", "authors": []string{"J.K. Rowling"}, "ratings_count": 80},
+		{"id": "3", "title": "The Hobbit", "authors": []string{"J.R.R. Tolkien"}, "ratings_count": 90},
+		{"id": "4", "title": "Potter's Field", "authors": []string{"Ellis Peters"}, "ratings_count": 10},
+		{"id": "5", "title": "The Lord of the Rings", "authors": []string{"J.R.R. Tolkien"}, "ratings_count": 95},
+	}
+	for _, document := range documents {
+		if err := index.Upsert(document); err != nil {
+			t.Fatalf("Upsert(%v): %v", document["id"], err)
+		}
+	}
+	return index
+}
+
+func searchIDs(t *testing.T, index *Index, dataInput models.Search) []string {
+	t.Helper()
+	result, err := index.Search(dataInput)
+	if err != nil {
+		t.Fatalf("Search(%q): %v", dataInput.Text, err)
+	}
+	ids := []string{}
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.Document["id"].(string))
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSearchOrdersByBM25(t *testing.T) {
+	index := testIndex(t)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		// both tokens beat one, the shorter title scores higher for the same terms
+		{"harry potter chamber", []string{"2", "1", "4"}},
+		// a title match weighs more than an author match
+		{"tolkien hobbit", []string{"3", "5"}},
+		{"*", []string{"1", "5", "3", "2", "4"}},
+	}
+	for _, test := range tests {
+		if got := searchIDs(t, index, models.Search{Text: test.text}); !equalIDs(got, test.want) {
+			t.Errorf("Search(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestSearchExpandsPrefixAndTypos(t *testing.T) {
+	index := testIndex(t)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		// the last token is a prefix
+		{"hob", []string{"3"}},
+		// only the last token is, the tie is broken by default_sorting_field
+		{"hob tolkien", []string{"5", "3"}},
+		// one typo from 4 letters, two from 7
+		{"hobbir", []string{"3"}},
+		{"philosofer", []string{"1"}},
+		{"xyzzy", []string{}},
+	}
+	for _, test := range tests {
+		if got := searchIDs(t, index, models.Search{Text: test.text}); !equalIDs(got, test.want) {
+			t.Errorf("Search(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestUpsertAndDelete(t *testing.T) {
+	index := testIndex(t)
+	if err := index.Upsert(map[string]interface{}{"id": "3", "title": "The Silmarillion", "authors": []string{"J.R.R. Tolkien"}, "ratings_count": 40}); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, index, models.Search{Text: "hobbit"}); len(got) != 0 {
+		t.Errorf("the replaced title is still found: %v", got)
+	}
+	if got := searchIDs(t, index, models.Search{Text: "silmarillion"}); !equalIDs(got, []string{"3"}) {
+		t.Errorf("Search(silmarillion) = %v, want [3]", got)
+	}
+	if !index.Delete("1") || index.Delete("1") {
+		t.Error("Delete must find the document once")
+	}
+	if got := searchIDs(t, index, models.Search{Text: "harry"}); !equalIDs(got, []string{"2"}) {
+		t.Errorf("Search(harry) = %v, want [2]", got)
+	}
+	if index.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", index.Len())
+	}
+	if err := index.Upsert(map[string]interface{}{"title": "No id"}); err == nil {
+		t.Error("a document without id is accepted")
+	}
+}
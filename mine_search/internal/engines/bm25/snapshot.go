@@ -0,0 +1,146 @@
+package bm25
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mine/internal/models"
+	"os"
+	"path/filepath"
+)
+
+// maxReportErrors caps the line errors kept by LoadJSONL
+const maxReportErrors = 100
+
+// Files read by LoadDir for a collection, the snapshot wins when both exist
+const (
+	SnapshotSuffix = ".snapshot.json.gz"
+	JSONLSuffix    = ".jsonl"
+)
+
+// snapshot is the content of a snapshot file, the schema name guards against loading another collection
+type snapshot struct {
+	Collection string                   `json:"collection"`
+	Documents  []map[string]interface{} `json:"documents"`
+}
+
+// LoadJSONL upserts every line of reader, a line that is not a valid document is reported and skipped
+func (x *Index) LoadJSONL(reader io.Reader) (*models.ImportReport, error) {
+	report := &models.ImportReport{
+		Collection: x.schema.Name,
+		Action:     models.ImportActionUpsert,
+		Errors:     []models.ImportLineError{},
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+		document := map[string]interface{}{}
+		err := json.Unmarshal(content, &document)
+		if err == nil {
+			err = x.Upsert(document)
+		}
+		if err != nil {
+			report.Failed++
+			if len(report.Errors) < maxReportErrors {
+				report.Errors = append(report.Errors, models.ImportLineError{Line: lineNumber, Error: err.Error(), Document: string(content)})
+			}
+			continue
+		}
+		report.Succeeded++
+	}
+	report.LastLine = lineNumber
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+	report.Done = true
+	return report, nil
+}
+
+// SaveSnapshot writes every document as gzipped JSON, the index is rebuilt on load
+func (x *Index) SaveSnapshot(writer io.Writer) error {
+	zw := gzip.NewWriter(writer)
+	content := snapshot{Collection: x.schema.Name, Documents: x.Documents()}
+	if err := json.NewEncoder(zw).Encode(content); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadSnapshot upserts the documents of a snapshot written by SaveSnapshot, it returns how many were loaded
+func (x *Index) LoadSnapshot(reader io.Reader) (int, error) {
+	zr, err := gzip.NewReader(reader)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	content := snapshot{}
+	if err := json.NewDecoder(zr).Decode(&content); err != nil {
+		return 0, err
+	}
+	if content.Collection != x.schema.Name {
+		return 0, fmt.Errorf("snapshot holds collection %q, not %q", content.Collection, x.schema.Name)
+	}
+	for idx, document := range content.Documents {
+		if err := x.Upsert(document); err != nil {
+			return idx, err
+		}
+	}
+	return len(content.Documents), nil
+}
+
+// SaveSnapshotFile writes the snapshot through a temporary file so a crash never leaves half a snapshot
+func (x *Index) SaveSnapshotFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := x.SaveSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadDir loads <collection>.snapshot.json.gz from dir, or <collection>.jsonl when there is no snapshot.
+// It returns the file read and the number of documents loaded, an empty file name when dir has neither
+func (x *Index) LoadDir(dir string) (string, int, error) {
+	path := filepath.Join(dir, x.schema.Name+SnapshotSuffix)
+	if file, err := os.Open(path); err == nil {
+		defer file.Close()
+		count, err := x.LoadSnapshot(file)
+		return path, count, err
+	} else if !os.IsNotExist(err) {
+		return path, 0, err
+	}
+	path = filepath.Join(dir, x.schema.Name+JSONLSuffix)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return path, 0, err
+	}
+	defer file.Close()
+	report, err := x.LoadJSONL(file)
+	if err != nil {
+		return path, report.Succeeded, err
+	}
+	return path, report.Succeeded, nil
+}
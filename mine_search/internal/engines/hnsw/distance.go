@@ -0,0 +1,107 @@
+package hnsw
+
+import (
+	"container/heap"
+	"math"
+)
+
+// distances are computed on prepared vectors: cosine vectors are normalized, l2 is squared until results
+var distances = map[string]func(a, b []float32) float32{
+	MetricCosine: func(a, b []float32) float32 { return 1 - dot(a, b) },
+	MetricDot:    func(a, b []float32) float32 { return -dot(a, b) },
+	MetricL2:     squaredL2,
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for idx := range a {
+		sum += a[idx] * b[idx]
+	}
+	return sum
+}
+
+func squaredL2(a, b []float32) float32 {
+	var sum float32
+	for idx := range a {
+		diff := a[idx] - b[idx]
+		sum += diff * diff
+	}
+	return sum
+}
+
+// normalize scales vector to a length of 1 in place, a zero vector is left as is
+func normalize(vector []float32) {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for idx := range vector {
+		vector[idx] *= scale
+	}
+}
+
+// candidate is a node and its distance to the vector searched
+type candidate struct {
+	idx  uint32
+	dist float32
+}
+
+// less orders by distance, then by position so equal distances sort the same way every time
+func (c candidate) less(other candidate) bool {
+	if c.dist != other.dist {
+		return c.dist < other.dist
+	}
+	return c.idx < other.idx
+}
+
+// minHeap pops the nearest candidate first
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].less(h[j]) }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+func (h *minHeap) push(item candidate) { heap.Push(h, item) }
+func (h *minHeap) pop() candidate      { return heap.Pop(h).(candidate) }
+
+// maxHeap pops the farthest candidate first, top is the worst result kept
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[j].less(h[i]) }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+func (h *maxHeap) push(item candidate) { heap.Push(h, item) }
+func (h *maxHeap) pop() candidate      { return heap.Pop(h).(candidate) }
+func (h maxHeap) top() candidate       { return h[0] }
+
+// bitset marks the nodes visited by one search, searches running in parallel each have their own
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) set(idx uint32) {
+	b[idx/64] |= 1 << (idx % 64)
+}
+
+func (b bitset) has(idx uint32) bool {
+	return b[idx/64]&(1<<(idx%64)) != 0
+}
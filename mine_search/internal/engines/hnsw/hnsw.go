@@ -0,0 +1,459 @@
+// Package hnsw is an in-process approximate nearest neighbor index, a Hierarchical Navigable Small World
+// graph. Every vector is a node linked to its nearest neighbors on layer 0 and, with a probability
+// falling exponentially by layer, on the sparser layers above it. A search walks greedily down from the
+// top layer and widens to EfSearch candidates on layer 0
+package hnsw
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics of Config.Metric
+const (
+	MetricCosine = "cosine"
+	MetricDot    = "dot"
+	MetricL2     = "l2"
+)
+
+// Defaults of the zero values of Config
+const (
+	DefaultM              = 16
+	DefaultEfConstruction = 200
+	DefaultEfSearch       = 64
+	DefaultMetric         = MetricCosine
+	// MaxM bounds M, the neighbors of a node are stored with a 16 bit count
+	MaxM = 256
+	// MaxIDLength is the longest id in bytes, ids are stored with a 16 bit length
+	MaxIDLength = math.MaxUint16
+)
+
+var (
+	ErrDimensions = errors.New("vector does not have the dimensions of the index")
+	ErrEmptyID    = errors.New("vector has no id")
+)
+
+// Config is fixed when the index is created, EfSearch only is a default a search can override
+type Config struct {
+	Dimensions int `json:"dimensions"`
+	// M is the number of neighbors kept per node above layer 0, twice as many are kept on layer 0
+	M int `json:"m"`
+	// EfConstruction is the size of the candidate list while inserting, higher builds slower and finds better neighbors
+	EfConstruction int `json:"ef_construction"`
+	// EfSearch is the size of the candidate list while searching, higher is slower and recalls more
+	EfSearch int    `json:"ef_search"`
+	Metric   string `json:"metric"`
+	// Seed drives the layer draws, 0 seeds from the clock
+	Seed int64 `json:"seed"`
+}
+
+// Result is a neighbor of a query. Distance is 1 - cosine similarity for cosine, the negated inner
+// product for dot and the euclidean distance for l2, smaller is nearer for all three
+type Result struct {
+	ID       string  `json:"id"`
+	Distance float32 `json:"distance"`
+}
+
+// node is a vector and its neighbors, friends[l] holds the links of layer l
+type node struct {
+	id      string
+	vector  []float32
+	friends [][]uint32
+	deleted bool
+}
+
+// Index is safe for concurrent use, searches run in parallel and inserts are serialized
+type Index struct {
+	mu     sync.RWMutex
+	config Config
+	nodes  []*node
+	// ids maps the id of every live node to its position in nodes
+	ids map[string]uint32
+	// entry is the node searches start from, -1 while the index is empty
+	entry     int
+	maxLevel  int
+	deleted   int
+	rng       *rand.Rand
+	levelMult float64
+	distance  func(a, b []float32) float32
+}
+
+// New validates config and fills its zero values with the defaults
+func New(config Config) (*Index, error) {
+	if config.Dimensions <= 0 {
+		return nil, fmt.Errorf("dimensions must be positive, got %d", config.Dimensions)
+	}
+	if config.M == 0 {
+		config.M = DefaultM
+	}
+	if config.M < 2 || config.M > MaxM {
+		return nil, fmt.Errorf("m must be between 2 and %d, got %d", MaxM, config.M)
+	}
+	if config.EfConstruction == 0 {
+		config.EfConstruction = DefaultEfConstruction
+	}
+	if config.EfConstruction < config.M {
+		return nil, fmt.Errorf("ef_construction must be at least m (%d), got %d", config.M, config.EfConstruction)
+	}
+	if config.EfSearch == 0 {
+		config.EfSearch = DefaultEfSearch
+	}
+	if config.EfSearch < 1 {
+		return nil, fmt.Errorf("ef_search must be positive, got %d", config.EfSearch)
+	}
+	if config.Metric == "" {
+		config.Metric = DefaultMetric
+	}
+	distance, ok := distances[config.Metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", config.Metric)
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Index{
+		config:    config,
+		ids:       map[string]uint32{},
+		entry:     -1,
+		rng:       rand.New(rand.NewSource(seed)),
+		levelMult: 1 / math.Log(float64(config.M)),
+		distance:  distance,
+	}, nil
+}
+
+func (x *Index) Config() Config {
+	return x.config
+}
+
+// Len is the number of live vectors, deleted ones are not counted
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.ids)
+}
+
+// Deleted is the number of tombstones still linked in the graph, Compact drops them
+func (x *Index) Deleted() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.deleted
+}
+
+// Has reports whether id is a live vector
+func (x *Index) Has(id string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	_, ok := x.ids[id]
+	return ok
+}
+
+// Vector returns a copy of the vector stored for id, normalized for the cosine metric
+func (x *Index) Vector(id string) ([]float32, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	idx, ok := x.ids[id]
+	if !ok {
+		return nil, false
+	}
+	return append([]float32(nil), x.nodes[idx].vector...), true
+}
+
+// Insert adds vector under id, an id already there is replaced: the old node becomes a tombstone
+func (x *Index) Insert(id string, vector []float32) error {
+	if id == "" {
+		return ErrEmptyID
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("id is longer than %d bytes", MaxIDLength)
+	}
+	if len(vector) != x.config.Dimensions {
+		return fmt.Errorf("%w: %d instead of %d", ErrDimensions, len(vector), x.config.Dimensions)
+	}
+	vector = x.prepare(vector)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+	level := x.randomLevel()
+	current := &node{id: id, vector: vector, friends: make([][]uint32, level+1)}
+	idx := uint32(len(x.nodes))
+	x.nodes = append(x.nodes, current)
+	x.ids[id] = idx
+	if x.entry < 0 {
+		x.entry = int(idx)
+		x.maxLevel = level
+		return nil
+	}
+	nearest := x.candidate(uint32(x.entry), vector)
+	for layer := x.maxLevel; layer > level; layer-- {
+		nearest = x.greedy(vector, nearest, layer)
+	}
+	for layer := minInt(level, x.maxLevel); layer >= 0; layer-- {
+		candidates := x.searchLayer(vector, nearest, x.config.EfConstruction, layer)
+		if len(candidates) == 0 {
+			// every node reached is a tombstone, link to the entry so the node stays reachable
+			candidates = []candidate{nearest}
+		}
+		neighbors := x.selectNeighbors(candidates, x.config.M)
+		current.friends[layer] = make([]uint32, 0, len(neighbors))
+		for _, neighbor := range neighbors {
+			current.friends[layer] = append(current.friends[layer], neighbor.idx)
+			x.link(neighbor.idx, idx, layer)
+		}
+		nearest = candidates[0]
+	}
+	if level > x.maxLevel {
+		x.maxLevel = level
+		x.entry = int(idx)
+	}
+	return nil
+}
+
+// Delete tombstones id, it keeps routing searches through the graph but is never returned again
+func (x *Index) Delete(id string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.remove(id)
+}
+
+func (x *Index) remove(id string) bool {
+	idx, ok := x.ids[id]
+	if !ok {
+		return false
+	}
+	x.nodes[idx].deleted = true
+	delete(x.ids, id)
+	x.deleted++
+	return true
+}
+
+// Compact rebuilds the graph from the live vectors, to reclaim the tombstones left by deletes and updates
+func (x *Index) Compact() (*Index, error) {
+	x.mu.RLock()
+	live := make([]*node, 0, len(x.ids))
+	for _, current := range x.nodes {
+		if !current.deleted {
+			live = append(live, current)
+		}
+	}
+	x.mu.RUnlock()
+	compacted, err := New(x.config)
+	if err != nil {
+		return nil, err
+	}
+	for _, current := range live {
+		if err := compacted.Insert(current.id, current.vector); err != nil {
+			return nil, err
+		}
+	}
+	return compacted, nil
+}
+
+// Search returns the k live vectors nearest to vector, nearest first. ef is the candidate list of
+// layer 0, Config.EfSearch when it is 0, and never less than k
+func (x *Index) Search(vector []float32, k, ef int) ([]Result, error) {
+	if len(vector) != x.config.Dimensions {
+		return nil, fmt.Errorf("%w: %d instead of %d", ErrDimensions, len(vector), x.config.Dimensions)
+	}
+	if k <= 0 {
+		return []Result{}, nil
+	}
+	if ef <= 0 {
+		ef = x.config.EfSearch
+	}
+	if ef < k {
+		ef = k
+	}
+	vector = x.prepare(vector)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.entry < 0 || len(x.ids) == 0 {
+		return []Result{}, nil
+	}
+	nearest := x.candidate(uint32(x.entry), vector)
+	for layer := x.maxLevel; layer > 0; layer-- {
+		nearest = x.greedy(vector, nearest, layer)
+	}
+	candidates := x.searchLayer(vector, nearest, ef, 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return x.results(candidates), nil
+}
+
+// BruteForce compares vector to every live vector, the exact answer Search approximates
+func (x *Index) BruteForce(vector []float32, k int) ([]Result, error) {
+	if len(vector) != x.config.Dimensions {
+		return nil, fmt.Errorf("%w: %d instead of %d", ErrDimensions, len(vector), x.config.Dimensions)
+	}
+	vector = x.prepare(vector)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	candidates := make([]candidate, 0, len(x.ids))
+	for _, idx := range x.ids {
+		candidates = append(candidates, x.candidate(idx, vector))
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].less(candidates[j]) })
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return x.results(candidates), nil
+}
+
+func (x *Index) results(candidates []candidate) []Result {
+	results := make([]Result, 0, len(candidates))
+	for _, item := range candidates {
+		distance := item.dist
+		if x.config.Metric == MetricL2 {
+			distance = float32(math.Sqrt(float64(distance)))
+		}
+		results = append(results, Result{ID: x.nodes[item.idx].id, Distance: distance})
+	}
+	return results
+}
+
+// prepare copies vector, normalized for the cosine metric so cosine is an inner product
+func (x *Index) prepare(vector []float32) []float32 {
+	prepared := append([]float32(nil), vector...)
+	if x.config.Metric == MetricCosine {
+		normalize(prepared)
+	}
+	return prepared
+}
+
+// randomLevel draws the top layer of a new node, layer l is reached with probability M^-l
+func (x *Index) randomLevel() int {
+	return int(math.Floor(-math.Log(1-x.rng.Float64()) * x.levelMult))
+}
+
+func (x *Index) maxFriends(layer int) int {
+	if layer == 0 {
+		return 2 * x.config.M
+	}
+	return x.config.M
+}
+
+func (x *Index) candidate(idx uint32, vector []float32) candidate {
+	return candidate{idx: idx, dist: x.distance(vector, x.nodes[idx].vector)}
+}
+
+// greedy moves from nearest to its nearest neighbor on layer until none is nearer to vector
+func (x *Index) greedy(vector []float32, nearest candidate, layer int) candidate {
+	for changed := true; changed; {
+		changed = false
+		for _, friend := range x.nodes[nearest.idx].friends[layer] {
+			if next := x.candidate(friend, vector); next.less(nearest) {
+				nearest = next
+				changed = true
+			}
+		}
+	}
+	return nearest
+}
+
+// searchLayer explores layer from entry and returns up to ef live nodes nearest to vector, nearest first.
+// Tombstones are explored like live nodes so the graph stays connected, they are only left out of the results
+func (x *Index) searchLayer(vector []float32, entry candidate, ef, layer int) []candidate {
+	visited := newBitset(len(x.nodes))
+	visited.set(entry.idx)
+	candidates := &minHeap{entry}
+	results := &maxHeap{}
+	if !x.nodes[entry.idx].deleted {
+		results.push(entry)
+	}
+	for candidates.Len() > 0 {
+		current := candidates.pop()
+		if results.Len() >= ef && results.top().less(current) {
+			break
+		}
+		for _, friend := range x.nodes[current.idx].friends[layer] {
+			if visited.has(friend) {
+				continue
+			}
+			visited.set(friend)
+			next := x.candidate(friend, vector)
+			if results.Len() < ef || next.less(results.top()) {
+				candidates.push(next)
+				if x.nodes[friend].deleted {
+					continue
+				}
+				results.push(next)
+				if results.Len() > ef {
+					results.pop()
+				}
+			}
+		}
+	}
+	sorted := make([]candidate, results.Len())
+	for idx := len(sorted) - 1; idx >= 0; idx-- {
+		sorted[idx] = results.pop()
+	}
+	return sorted
+}
+
+// selectNeighbors keeps up to m of candidates, sorted nearest first. A candidate nearer to an already kept
+// neighbor than to the new node is skipped first, so links spread in every direction instead of into
+// one cluster; the skipped ones fill the remaining slots
+func (x *Index) selectNeighbors(candidates []candidate, m int) []candidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+	selected := make([]candidate, 0, m)
+	skipped := []candidate{}
+	for _, item := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		for _, kept := range selected {
+			if x.distance(x.nodes[item.idx].vector, x.nodes[kept.idx].vector) < item.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, item)
+		} else {
+			skipped = append(skipped, item)
+		}
+	}
+	for _, item := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, item)
+	}
+	return selected
+}
+
+// link adds to as a neighbor of from on layer, the neighbors of from are pruned back when they overflow
+func (x *Index) link(from, to uint32, layer int) {
+	current := x.nodes[from]
+	current.friends[layer] = append(current.friends[layer], to)
+	if len(current.friends[layer]) <= x.maxFriends(layer) {
+		return
+	}
+	candidates := make([]candidate, 0, len(current.friends[layer]))
+	for _, friend := range current.friends[layer] {
+		candidates = append(candidates, x.candidate(friend, current.vector))
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].less(candidates[j]) })
+	kept := x.selectNeighbors(candidates, x.maxFriends(layer))
+	friends := make([]uint32, 0, len(kept))
+	for _, item := range kept {
+		friends = append(friends, item.idx)
+	}
+	current.friends[layer] = friends
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+package hnsw
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// RecallReport measures one EfSearch against brute force on the same queries
+type RecallReport struct {
+	EfSearch int `json:"ef_search"`
+	K        int `json:"k"`
+	Queries  int `json:"queries"`
+	// Recall is the share of the exact k nearest found by Search, averaged over the queries
+	Recall float64 `json:"recall"`
+	// SearchMicros and BruteForceMicros are the mean latency of one query
+	SearchMicros     float64 `json:"search_micros"`
+	BruteForceMicros float64 `json:"brute_force_micros"`
+}
+
+// GenerateVectors draws n vectors of dimensions values around clusters random centers, so the data has
+// the neighborhoods real embeddings have. The same seed returns the same vectors
+func GenerateVectors(n, dimensions, clusters int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	if clusters < 1 {
+		clusters = 1
+	}
+	centers := make([][]float32, clusters)
+	for idx := range centers {
+		centers[idx] = make([]float32, dimensions)
+		for dim := range centers[idx] {
+			centers[idx][dim] = float32(rng.NormFloat64())
+		}
+	}
+	vectors := make([][]float32, n)
+	for idx := range vectors {
+		center := centers[rng.Intn(clusters)]
+		vectors[idx] = make([]float32, dimensions)
+		for dim := range vectors[idx] {
+			vectors[idx][dim] = center[dim] + float32(rng.NormFloat64()*0.5)
+		}
+	}
+	return vectors
+}
+
+// Build inserts vectors under their position as id, "0", "1", ...
+func Build(config Config, vectors [][]float32) (*Index, error) {
+	x, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	for idx, vector := range vectors {
+		if err := x.Insert(strconv.Itoa(idx), vector); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// MeasureRecall runs queries with every ef of efSearch and compares the k neighbors found to the exact ones
+func (x *Index) MeasureRecall(queries [][]float32, k int, efSearch []int) ([]RecallReport, error) {
+	exact := make([]map[string]bool, len(queries))
+	started := time.Now()
+	for idx, query := range queries {
+		results, err := x.BruteForce(query, k)
+		if err != nil {
+			return nil, err
+		}
+		exact[idx] = make(map[string]bool, len(results))
+		for _, result := range results {
+			exact[idx][result.ID] = true
+		}
+	}
+	bruteForceMicros := micros(time.Since(started), len(queries))
+	reports := make([]RecallReport, 0, len(efSearch))
+	for _, ef := range efSearch {
+		report := RecallReport{EfSearch: ef, K: k, Queries: len(queries), BruteForceMicros: bruteForceMicros}
+		found := 0
+		expected := 0
+		started := time.Now()
+		for idx, query := range queries {
+			results, err := x.Search(query, k, ef)
+			if err != nil {
+				return nil, err
+			}
+			for _, result := range results {
+				if exact[idx][result.ID] {
+					found++
+				}
+			}
+			expected += len(exact[idx])
+		}
+		report.SearchMicros = micros(time.Since(started), len(queries))
+		if expected > 0 {
+			report.Recall = float64(found) / float64(expected)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func micros(elapsed time.Duration, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return float64(elapsed.Microseconds()) / float64(count)
+}
@@ -0,0 +1,51 @@
+package hnsw
+
+import (
+	"testing"
+)
+
+func TestMeasureRecall(t *testing.T) {
+	vectors := GenerateVectors(2000, 16, 20, 7)
+	x, err := Build(Config{Dimensions: 16, Seed: 7}, vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := GenerateVectors(50, 16, 20, 8)
+	reports, err := x.MeasureRecall(queries, 10, []int{10, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].Queries != 50 || reports[0].K != 10 {
+		t.Fatalf("MeasureRecall = %+v", reports)
+	}
+	if reports[1].Recall < 0.95 {
+		t.Errorf("recall at ef 100 is %.3f, want 0.95 at least", reports[1].Recall)
+	}
+	if reports[1].Recall < reports[0].Recall {
+		t.Errorf("recall drops from %.3f to %.3f when ef grows", reports[0].Recall, reports[1].Recall)
+	}
+}
+
+func TestSearchAfterDelete(t *testing.T) {
+	vectors := GenerateVectors(200, 8, 4, 1)
+	x, err := Build(Config{Dimensions: 8, Metric: MetricL2, Seed: 1}, vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := x.Search(vectors[42], 1, 0)
+	if err != nil || len(results) != 1 || results[0].ID != "42" || results[0].Distance != 0 {
+		t.Fatalf("Search(vectors[42]) = %v, %v", results, err)
+	}
+	if !x.Delete("42") {
+		t.Fatal("Delete(42) did not find it")
+	}
+	results, _ = x.Search(vectors[42], 5, 0)
+	for _, result := range results {
+		if result.ID == "42" {
+			t.Errorf("a deleted vector is found: %v", results)
+		}
+	}
+	if x.Len() != 199 {
+		t.Errorf("Len() = %d, want 199", x.Len())
+	}
+}
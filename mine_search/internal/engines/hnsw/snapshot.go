@@ -0,0 +1,177 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshotMagic starts every snapshot, the version follows it
+const (
+	snapshotMagic   = "MHNS"
+	snapshotVersion = 1
+	// SnapshotSuffix ends the snapshot files, <collection>.hnsw
+	SnapshotSuffix = ".hnsw"
+)
+
+// Save writes magic, version, the config as length prefixed json, the entry, the top layer and the nodes,
+// little endian. A node is its id, tombstone flag, vector and the neighbors of each of its layers
+func (x *Index) Save(writer io.Writer) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	content, err := json.Marshal(x.config)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(snapshotMagic)
+	header := []interface{}{uint8(snapshotVersion), uint32(len(content)), content, int32(x.entry), uint8(x.maxLevel), uint32(len(x.nodes))}
+	for _, value := range header {
+		if err := binary.Write(buffered, binary.LittleEndian, value); err != nil {
+			return err
+		}
+	}
+	for _, current := range x.nodes {
+		deleted := uint8(0)
+		if current.deleted {
+			deleted = 1
+		}
+		values := []interface{}{uint16(len(current.id)), []byte(current.id), deleted, current.vector, uint8(len(current.friends))}
+		for _, friends := range current.friends {
+			values = append(values, uint16(len(friends)), friends)
+		}
+		for _, value := range values {
+			if err := binary.Write(buffered, binary.LittleEndian, value); err != nil {
+				return err
+			}
+		}
+	}
+	return buffered.Flush()
+}
+
+// Load rebuilds the index written by Save
+func Load(reader io.Reader) (*Index, error) {
+	buffered := bufio.NewReader(reader)
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(buffered, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic || header[len(snapshotMagic)] != snapshotVersion {
+		return nil, errors.New("not an hnsw snapshot")
+	}
+	var length uint32
+	if err := binary.Read(buffered, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(buffered, content); err != nil {
+		return nil, err
+	}
+	config := Config{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+	x, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	var entry int32
+	var maxLevel uint8
+	var count uint32
+	for _, value := range []interface{}{&entry, &maxLevel, &count} {
+		if err := binary.Read(buffered, binary.LittleEndian, value); err != nil {
+			return nil, err
+		}
+	}
+	if int64(entry) >= int64(count) || (entry < 0 && count > 0) {
+		return nil, fmt.Errorf("entry %d out of %d nodes", entry, count)
+	}
+	x.entry = int(entry)
+	x.maxLevel = int(maxLevel)
+	// the capacity is bounded, a corrupt count fails on a short read instead of a huge allocation
+	x.nodes = make([]*node, 0, minInt(int(count), 1<<20))
+	for idx := uint32(0); idx < count; idx++ {
+		current, err := readNode(buffered, config.Dimensions, count)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %w", idx, err)
+		}
+		x.nodes = append(x.nodes, current)
+		if current.deleted {
+			x.deleted++
+		} else {
+			x.ids[current.id] = idx
+		}
+	}
+	if x.entry >= 0 && len(x.nodes[x.entry].friends) != x.maxLevel+1 {
+		return nil, errors.New("the entry is not on the top layer")
+	}
+	return x, nil
+}
+
+func readNode(reader io.Reader, dimensions int, count uint32) (*node, error) {
+	var idLength uint16
+	if err := binary.Read(reader, binary.LittleEndian, &idLength); err != nil {
+		return nil, err
+	}
+	id := make([]byte, idLength)
+	var deleted, layers uint8
+	vector := make([]float32, dimensions)
+	for _, value := range []interface{}{id, &deleted, vector, &layers} {
+		if err := binary.Read(reader, binary.LittleEndian, value); err != nil {
+			return nil, err
+		}
+	}
+	current := &node{id: string(id), vector: vector, deleted: deleted == 1, friends: make([][]uint32, layers)}
+	for layer := range current.friends {
+		var length uint16
+		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+			return nil, err
+		}
+		friends := make([]uint32, length)
+		if err := binary.Read(reader, binary.LittleEndian, friends); err != nil {
+			return nil, err
+		}
+		for _, friend := range friends {
+			if friend >= count {
+				return nil, fmt.Errorf("neighbor %d out of %d nodes", friend, count)
+			}
+		}
+		current.friends[layer] = friends
+	}
+	return current, nil
+}
+
+// SaveFile writes the snapshot through a temporary file so a crash never leaves half a snapshot
+func (x *Index) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := x.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func LoadFile(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
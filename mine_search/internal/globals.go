@@ -43,17 +43,19 @@ var (
 )
 
 type HTTPRequestMethod struct {
-	GET   string
-	POST  string
-	PATCH string
-	PUT   string
+	GET    string
+	POST   string
+	PATCH  string
+	PUT    string
+	DELETE string
 }
 
 var httpRequestMethod = HTTPRequestMethod{
-	GET:   "GET",
-	POST:  "POST",
-	PATCH: "PATCH",
-	PUT:   "PUT",
+	GET:    "GET",
+	POST:   "POST",
+	PATCH:  "PATCH",
+	PUT:    "PUT",
+	DELETE: "DELETE",
 }
 
 const (
@@ -70,6 +72,9 @@ const (
 	CODE_INVALID_TOKEN_APP  = 2002
 	CODE_SYSTEM_BUSY        = 300
 	CODE_SYSTEM_ERROR       = 301
+	CODE_INVALID_CURSOR     = 402
+	CODE_CURSOR_MISMATCH    = 403
+	CODE_CURSOR_DRIFT       = 404
 
 	MSG_DB_FAILED          = "Kết nối hệ thống lỗi, vui lòng thử lại sau ít phút"
 	MSG_WRONG_PARAMS       = "Sai thông tin đầu vào, vui lòng kiểm tra lại thông tin"
@@ -82,6 +87,9 @@ const (
 	MSG_INVALID_TOKEN_APP  = "Token không hợp lệ"
 	MSG_SYSTEM_BUSY        = "Hệ thống đang bận, vui lòng thử lại sau ít phút"
 	MSG_SYSTEM_ERROR       = "Có lỗi trong quá trình xử lý, vui lòng thử lại sau ít phút"
+	MSG_INVALID_CURSOR     = "Cursor không hợp lệ"
+	MSG_CURSOR_MISMATCH    = "Điều kiện tìm kiếm đã thay đổi, vui lòng tải lại từ trang đầu"
+	MSG_CURSOR_DRIFT       = "Kết quả tìm kiếm đã thay đổi, vui lòng tải lại từ trang đầu"
 )
 
 type AppKeys struct {
@@ -96,6 +104,8 @@ type EnvVars struct {
 	SqlPort      int    `mapstructure:"DB_PORT"`
 	IsProduction bool   `mapstructure:"USE_PRODUCTION"`
 	IsDev        bool   `mapstructure:"IS_DEV"`
+	// TypeSenseHost overrides the Typesense domain, e.g. http://localhost:8108 for the compose container
+	TypeSenseHost string `mapstructure:"TYPESENSE_HOST"`
 }
 type ApiDomains struct {
 	TypeSense string
@@ -137,6 +147,15 @@ func InitSystemStatus() *AllSystemStatus {
 		}, TokenAppExpired: &SystemStatus{
 			Status: CODE_TOKEN_APP_EXPIRED,
 			Msg:    MSG_TOKEN_APP_EXPIRED,
+		}, InvalidCursor: &SystemStatus{
+			Status: CODE_INVALID_CURSOR,
+			Msg:    MSG_INVALID_CURSOR,
+		}, CursorMismatch: &SystemStatus{
+			Status: CODE_CURSOR_MISMATCH,
+			Msg:    MSG_CURSOR_MISMATCH,
+		}, CursorDrift: &SystemStatus{
+			Status: CODE_CURSOR_DRIFT,
+			Msg:    MSG_CURSOR_DRIFT,
 		},
 	}
 }
@@ -158,10 +177,23 @@ type AllSystemStatus struct {
 	TokenAppRequired *SystemStatus
 	InvalidTokenApp  *SystemStatus
 	TokenAppExpired  *SystemStatus
+	InvalidCursor    *SystemStatus
+	CursorMismatch   *SystemStatus
+	CursorDrift      *SystemStatus
 }
 
 type TypeSenseEndpoint struct {
-	TextSearch string
+	TextSearch  string
+	Collections string
+	Collection  string
+	Import      string
+	Export      string
+	Document    string
+	Documents   string
+	Health      string
+	Aliases     string
+	Alias       string
+	MultiSearch string
 }
 type ApiEndpoints struct {
 	TypeSense TypeSenseEndpoint
@@ -191,6 +223,7 @@ func InitEnvVars() *EnvVars {
 		viper.BindEnv("DB_PORT")
 		viper.BindEnv("USE_PRODUCTION")
 		viper.BindEnv("IS_DEV")
+		viper.BindEnv("TYPESENSE_HOST")
 	}
 	if err := viper.Unmarshal(envs); err != nil {
 		fmt.Println("Error viper.Unmarshal", err)
@@ -302,6 +335,11 @@ func NewKafkaTopicNameAll(useProduction bool) string {
 }
 
 func InitAPIDomains(isProduction bool) *ApiDomains {
+	if Envs.TypeSenseHost != "" {
+		return &ApiDomains{
+			TypeSense: strings.TrimSuffix(Envs.TypeSenseHost, "/"),
+		}
+	}
 	// production
 	if isProduction {
 		return &ApiDomains{}
@@ -315,7 +353,17 @@ func InitAPIDomains(isProduction bool) *ApiDomains {
 func InitAPIEndpoints() *ApiEndpoints {
 	endpoints := &ApiEndpoints{
 		TypeSense: TypeSenseEndpoint{
-			TextSearch: "/collections/{{document}}/documents/search",
+			TextSearch:  "/collections/{{document}}/documents/search",
+			Collections: "/collections",
+			Collection:  "/collections/{{document}}",
+			Import:      "/collections/{{document}}/documents/import",
+			Export:      "/collections/{{document}}/documents/export",
+			Document:    "/collections/{{document}}/documents/{{id}}",
+			Documents:   "/collections/{{document}}/documents",
+			Health:      "/health",
+			Aliases:     "/aliases",
+			Alias:       "/aliases/{{alias}}",
+			MultiSearch: "/multi_search",
 		},
 	}
 	return endpoints
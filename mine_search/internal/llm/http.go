@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/utils"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// httpTimeout is in seconds, a model may take a while to write a long answer
+const httpTimeout = 60
+
+// HTTPProvider calls an OpenAI compatible /chat/completions endpoint: {"model", "messages", "max_tokens"}
+// answered by {"model", "choices": [{"message": {"content"}}]}
+type HTTPProvider struct {
+	url       string
+	apiKey    string
+	model     string
+	maxTokens int
+}
+
+func NewHTTPProvider(url, apiKey, model string, maxTokens int) *HTTPProvider {
+	return &HTTPProvider{url: url, apiKey: apiKey, model: model, maxTokens: maxTokens}
+}
+
+func (p *HTTPProvider) Name() string {
+	return ProviderHTTP
+}
+
+type httpChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+func (p *HTTPProvider) Complete(prompt Prompt) (*Completion, error) {
+	return p.Chat(systemPrompt, prompt.UserMessage())
+}
+
+// Chat sends one system and one user message, callers other than the RAG endpoint bring their own instructions
+func (p *HTTPProvider) Chat(system, user string) (*Completion, error) {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	body := map[string]interface{}{
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"max_tokens":  p.maxTokens,
+		"temperature": 0,
+	}
+	if p.model != "" {
+		body["model"] = p.model
+	}
+	resp, err := utils.RequestWithMethod(http.MethodPost, p.url, headers, nil, body, httpTimeout, false)
+	if err != nil {
+		internal.Log.Error("HTTPProvider.Chat -> RequestWithMethod", zap.Any("url", p.url), zap.Error(err))
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		internal.Log.Error("HTTPProvider.Chat", zap.Any("url", p.url), zap.Any("status", resp.StatusCode()), zap.Any("response", resp.String()))
+		return nil, fmt.Errorf("llm provider http status %d", resp.StatusCode())
+	}
+	content := httpChatResponse{}
+	if err := json.Unmarshal(resp.Body(), &content); err != nil {
+		return nil, err
+	}
+	if len(content.Choices) == 0 {
+		return nil, errors.New("llm provider answered no choice")
+	}
+	model := content.Model
+	if model == "" {
+		model = p.model
+	}
+	return &Completion{Text: content.Choices[0].Message.Content, Model: model}, nil
+}
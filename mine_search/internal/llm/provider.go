@@ -0,0 +1,82 @@
+// Package llm writes the answers of the RAG endpoint from a question and the documents retrieved for it
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/settings"
+	"strings"
+)
+
+// Providers of LLM_PROVIDER
+const (
+	ProviderTemplate = "template"
+	ProviderHTTP     = "http"
+	// DefaultMaxTokens bounds the answer when LLM_MAX_TOKENS is not set
+	DefaultMaxTokens = 512
+)
+
+// systemPrompt keeps the model on the documents and asks for the ids the citations are read from
+const systemPrompt = "You answer questions about our catalog using only the documents given. " +
+	"Cite every document you use with its id in square brackets, e.g. [42]. " +
+	"When the documents do not answer the question, say that you do not know."
+
+type (
+	// Document is a retrieved hit as given to the model, Title is the value of its first field
+	Document struct {
+		ID    string
+		Title string
+		Text  string
+	}
+	// Prompt is the question with the documents retrieved for it, best first
+	Prompt struct {
+		Question  string
+		Documents []Document
+	}
+	Completion struct {
+		Text  string
+		Model string
+	}
+)
+
+// LLMProvider writes the answer of a prompt, citing documents by their id in square brackets
+type LLMProvider interface {
+	Name() string
+	Complete(prompt Prompt) (*Completion, error)
+}
+
+// Chatter is a provider taking free instructions, the model behind it can do more than answer from documents
+type Chatter interface {
+	Chat(system, user string) (*Completion, error)
+}
+
+// New builds the provider named by LLM_PROVIDER, the template provider when it is empty
+func New(appSettings *settings.AppSettings) (LLMProvider, error) {
+	cfgs := appSettings.Cfgs
+	switch cfgs.LLMProvider {
+	case "", ProviderTemplate:
+		return NewTemplateProvider(), nil
+	case ProviderHTTP:
+		if cfgs.LLMURL == "" {
+			return nil, errors.New("the http llm provider needs LLM_URL")
+		}
+		maxTokens := cfgs.LLMMaxTokens
+		if maxTokens <= 0 {
+			maxTokens = DefaultMaxTokens
+		}
+		return NewHTTPProvider(cfgs.LLMURL, cfgs.LLMAPIKey, cfgs.LLMModel, maxTokens), nil
+	}
+	return nil, fmt.Errorf("unknown llm provider %q", cfgs.LLMProvider)
+}
+
+// UserMessage lists the documents with their id then the question
+func (p Prompt) UserMessage() string {
+	builder := strings.Builder{}
+	builder.WriteString("Documents:\n")
+	for _, document := range p.Documents {
+		fmt.Fprintf(&builder, "[%s]\n%s\n\n", document.ID, document.Text)
+	}
+	builder.WriteString("Question: ")
+	builder.WriteString(p.Question)
+	return builder.String()
+}
@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"strings"
+	"text/template"
+)
+
+// defaultTemplate lists the title of every document with its citation, enough to check the retrieval and
+// the citations without a model
+const defaultTemplate = `{{if .Documents}}Here is what the catalog has about "{{.Question}}":
+{{range .Documents}}- {{.Title}} [{{.ID}}]
+{{end}}{{else}}I do not know, the catalog has nothing about "{{.Question}}".{{end}}`
+
+// TemplateProvider renders the prompt with a text/template instead of calling a model, the answer is
+// the same for the same documents
+type TemplateProvider struct {
+	template *template.Template
+}
+
+func NewTemplateProvider() *TemplateProvider {
+	return &TemplateProvider{template: template.Must(template.New(ProviderTemplate).Parse(defaultTemplate))}
+}
+
+func (p *TemplateProvider) Name() string {
+	return ProviderTemplate
+}
+
+func (p *TemplateProvider) Complete(prompt Prompt) (*Completion, error) {
+	builder := strings.Builder{}
+	if err := p.template.Execute(&builder, prompt); err != nil {
+		return nil, err
+	}
+	return &Completion{Text: strings.TrimSpace(builder.String()), Model: ProviderTemplate}, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+type (
+	// BackendCapabilities lists the parts of a Search an engine can answer
+	BackendCapabilities struct {
+		Text       bool `json:"text"`
+		Conditions bool `json:"conditions"`
+		Filter     bool `json:"filter"`
+		Facets     bool `json:"facets"`
+		Highlights bool `json:"highlights"`
+		GeoRadius  bool `json:"geo_radius"`
+		GeoBox     bool `json:"geo_box"`
+		GeoPolygon bool `json:"geo_polygon"`
+		// Vector is set for the engines answering vector searches
+		Vector bool `json:"vector"`
+		// MaxSortFields is the number of order_bys accepted, 0 when the engine cannot sort on fields
+		MaxSortFields int `json:"max_sort_fields"`
+	}
+	BackendStatus struct {
+		Name         string              `json:"name"`
+		Healthy      bool                `json:"healthy"`
+		Error        string              `json:"error,omitempty"`
+		Capabilities BackendCapabilities `json:"capabilities"`
+	}
+)
+
+const (
+	BackendTypeSense = "typesense"
+	BackendRedis     = "redis"
+	BackendRedisGeo  = "redis_geo"
+	BackendMemory    = "memory"
+	BackendMySQL     = "mysql"
+	BackendHNSW      = "hnsw"
+	// FallbackCache names the cached answers among the failover secondaries, it is not a backend
+	FallbackCache = "cache"
+)
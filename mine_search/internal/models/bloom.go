@@ -0,0 +1,70 @@
+package models
+
+type (
+	// BloomCreate sizes a filter from the items it should hold and the false positive rate wanted at that count
+	BloomCreate struct {
+		Name      string  `json:"name" validate:"required,max=64"`
+		Type      string  `json:"type" validate:"omitempty,oneof=bloom cuckoo"`
+		Capacity  uint64  `json:"capacity" validate:"required,gt=0"`
+		ErrorRate float64 `json:"error_rate" validate:"required,gt=0,lt=1"`
+		Storage   string  `json:"storage" validate:"omitempty,oneof=redis memory"`
+		// Collection and Field name the export the filter is rebuilt from, Field is id by default
+		Collection string `json:"collection"`
+		Field      string `json:"field"`
+	}
+	// BloomMeta is fixed when the filter is created, Bits and Hashes come from Capacity and ErrorRate.
+	// A cuckoo filter has Buckets of BucketSize fingerprints of 16 bits instead of hashes
+	BloomMeta struct {
+		Name       string  `json:"name"`
+		Type       string  `json:"type"`
+		Storage    string  `json:"storage"`
+		Capacity   uint64  `json:"capacity"`
+		ErrorRate  float64 `json:"error_rate"`
+		Bits       uint64  `json:"bits"`
+		Hashes     int     `json:"hashes,omitempty"`
+		Buckets    uint64  `json:"buckets,omitempty"`
+		BucketSize int     `json:"bucket_size,omitempty"`
+		Collection string  `json:"collection,omitempty"`
+		Field      string  `json:"field,omitempty"`
+	}
+	BloomStats struct {
+		BloomMeta
+		// Count is the number of added items that were not in the filter yet, less the deleted ones.
+		// A cuckoo filter counts the fingerprints it stores, one per Add
+		Count uint64 `json:"count"`
+		// BitsSet counts the bits of the stored fingerprints for a cuckoo filter
+		BitsSet   uint64  `json:"bits_set"`
+		FillRatio float64 `json:"fill_ratio"`
+		// EstimatedErrorRate is the false positive rate at the current fill, above ErrorRate once Count passes Capacity
+		EstimatedErrorRate float64 `json:"estimated_error_rate"`
+	}
+	BloomItem struct {
+		Item string `json:"item" validate:"required"`
+	}
+	BloomItems struct {
+		Items []string `json:"items" validate:"required,min=1,max=10000,dive,required"`
+	}
+	BloomAddResult struct {
+		Items int `json:"items"`
+		// Added counts the items that were not in the filter, an item reported as present may be a false positive
+		Added int `json:"added"`
+	}
+	BloomDeleteResult struct {
+		Items int `json:"items"`
+		// Deleted counts the items whose fingerprint was found and removed
+		Deleted int `json:"deleted"`
+	}
+	BloomCheck struct {
+		Item         string `json:"item"`
+		MightContain bool   `json:"might_contain"`
+	}
+)
+
+const (
+	BloomTypeBloom = "bloom"
+	// BloomTypeCuckoo supports deletion, it is kept in memory and persisted by snapshots
+	BloomTypeCuckoo = "cuckoo"
+	// BloomStorageRedis keeps the bits in Redis so every replica shares them
+	BloomStorageRedis  = "redis"
+	BloomStorageMemory = "memory"
+)
@@ -0,0 +1,55 @@
+package models
+
+type (
+	// ChunkingConfig is the chunking of a collection in the schema registry. The text of Fields is cut into
+	// chunks of Size tokens, the last Overlap tokens of a chunk starting the next one
+	ChunkingConfig struct {
+		Fields []string `yaml:"fields"`
+		// Collection is the child collection of the chunks, <name>_chunks by default
+		Collection string `yaml:"collection"`
+		// Strategy is tokens (default), fixed windows of words, or sentences, whole sentences up to Size
+		Strategy string `yaml:"strategy"`
+		Size     int    `yaml:"size"`
+		Overlap  int    `yaml:"overlap"`
+		// Copy lists parent fields copied into every chunk so chunk searches can filter on them
+		Copy []string `yaml:"copy"`
+	}
+	// ChunkSearch searches the chunks of a collection and answers with the parent documents of the best ones
+	ChunkSearch struct {
+		// Collection is the parent collection, the one declaring the chunking
+		Collection string `json:"collection"`
+		Text       string `json:"text" validate:"required"`
+		// Mode is keyword, vector or hybrid, hybrid by default when the chunks have an embedding field
+		Mode string `json:"mode" validate:"omitempty,oneof=keyword vector hybrid"`
+		// Conditions and Filter apply to the chunks, on parent_id and the copied fields
+		Conditions []ConditionSearching `json:"conditions"`
+		Filter     *FilterSearching     `json:"filter"`
+		Page       int                  `json:"page"`
+		PerPage    int                  `json:"per_page"`
+		// Candidates is the number of chunks searched before they are rolled up, page·per_page at least
+		Candidates int `json:"candidates" validate:"gte=0,lte=100"`
+		// ChunksPerDocument bounds the chunks kept on each parent hit, 3 by default
+		ChunksPerDocument int      `json:"chunks_per_document" validate:"gte=0,lte=10"`
+		Fusion            string   `json:"fusion" validate:"omitempty,oneof=rrf weighted"`
+		Alpha             *float64 `json:"alpha" validate:"omitempty,gte=0,lte=1"`
+	}
+	// ChunkMatch is a chunk of a parent hit, Rank is its position among the chunks searched from 1
+	ChunkMatch struct {
+		ID         string              `json:"id"`
+		Index      int                 `json:"index"`
+		Text       string              `json:"text"`
+		Rank       int                 `json:"rank"`
+		Highlights map[string][]string `json:"highlights,omitempty"`
+	}
+)
+
+const (
+	ChunkStrategyTokens    = "tokens"
+	ChunkStrategySentences = "sentences"
+	// ChunkParentField, ChunkIndexField and ChunkTextField are the fields of every child collection
+	ChunkParentField = "parent_id"
+	ChunkIndexField  = "chunk_index"
+	ChunkTextField   = "text"
+	// DefaultChunkSize is in tokens, about a paragraph
+	DefaultChunkSize = 200
+)
@@ -0,0 +1,179 @@
+package models
+
+type (
+	CollectionField struct {
+		Name     string `json:"name" yaml:"name"`
+		Type     string `json:"type" yaml:"type"`
+		Facet    bool   `json:"facet,omitempty" yaml:"facet"`
+		Optional bool   `json:"optional,omitempty" yaml:"optional"`
+		Sort     bool   `json:"sort,omitempty" yaml:"sort"`
+		Index    *bool  `json:"index,omitempty" yaml:"index"`
+		// NumDim is the length of the vectors of a float[] embedding field
+		NumDim int `json:"num_dim,omitempty" yaml:"num_dim"`
+		// EmbedFrom lists the string fields whose text is embedded into this field at import time,
+		// Typesense only sees a float[] field
+		EmbedFrom []string `json:"-" yaml:"embed_from"`
+	}
+	CollectionSchema struct {
+		Name                string            `json:"name" yaml:"name"`
+		Fields              []CollectionField `json:"fields" yaml:"fields"`
+		DefaultSortingField string            `json:"default_sorting_field,omitempty" yaml:"default_sorting_field"`
+		// QueryBy is the default list of fields searched when a request does not say otherwise
+		QueryBy []string `json:"-" yaml:"query_by"`
+		// Chunking cuts long text fields into the documents of a child collection at import time
+		Chunking *ChunkingConfig `json:"-" yaml:"chunking"`
+	}
+	// CollectionInfo is a collection as described by Typesense
+	CollectionInfo struct {
+		CollectionSchema
+		NumDocuments int64 `json:"num_documents"`
+		CreatedAt    int64 `json:"created_at"`
+	}
+	CollectionFieldPatch struct {
+		CollectionField
+		Drop bool `json:"drop,omitempty"`
+	}
+	CollectionFieldChange struct {
+		Name   string          `json:"name"`
+		Local  CollectionField `json:"local"`
+		Remote CollectionField `json:"remote"`
+	}
+	// CollectionDiff lists what must change on Typesense to match the registry
+	CollectionDiff struct {
+		Collection string                  `json:"collection"`
+		Exists     bool                    `json:"exists"`
+		Added      []CollectionField       `json:"added"`
+		Removed    []CollectionField       `json:"removed"`
+		Changed    []CollectionFieldChange `json:"changed"`
+	}
+	CollectionStatus struct {
+		Name           string `json:"name"`
+		Registered     bool   `json:"registered"`
+		LiveCollection string `json:"live_collection,omitempty"`
+		Exists         bool   `json:"exists"`
+		InSync         bool   `json:"in_sync"`
+		NumDocuments   int64  `json:"num_documents"`
+	}
+)
+
+const (
+	FieldTypeString      = "string"
+	FieldTypeStringArray = "string[]"
+	FieldTypeInt32       = "int32"
+	FieldTypeInt64       = "int64"
+	FieldTypeFloat       = "float"
+	FieldTypeBool        = "bool"
+	FieldTypeGeopoint    = "geopoint"
+	FieldTypeFloatArray  = "float[]"
+)
+
+func (c *CollectionSchema) GetField(name string) (*CollectionField, bool) {
+	for idx := range c.Fields {
+		if c.Fields[idx].Name == name {
+			return &c.Fields[idx], true
+		}
+	}
+	return nil, false
+}
+
+// EmbeddingFields returns the fields filled by the embedder, in schema order
+func (c *CollectionSchema) EmbeddingFields() []CollectionField {
+	fields := []CollectionField{}
+	for _, field := range c.Fields {
+		if field.IsEmbedding() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (d *CollectionDiff) InSync() bool {
+	return d.Exists && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (f *CollectionField) IsNumeric() bool {
+	switch f.Type {
+	case FieldTypeInt32, FieldTypeInt64, FieldTypeFloat, "int32[]", "int64[]", "float[]":
+		return true
+	}
+	return false
+}
+
+func (f *CollectionField) IsSortable() bool {
+	switch f.Type {
+	case FieldTypeInt32, FieldTypeInt64, FieldTypeFloat, FieldTypeBool:
+		return true
+	}
+	return f.Sort
+}
+
+func (f *CollectionField) IsInteger() bool {
+	switch f.Type {
+	case FieldTypeInt32, FieldTypeInt64, "int32[]", "int64[]":
+		return true
+	}
+	return false
+}
+
+func (f *CollectionField) IsBool() bool {
+	return f.Type == FieldTypeBool || f.Type == "bool[]"
+}
+
+func (f *CollectionField) IsGeo() bool {
+	return f.Type == FieldTypeGeopoint || f.Type == "geopoint[]"
+}
+
+func (f *CollectionField) IsEmbedding() bool {
+	return len(f.EmbedFrom) > 0
+}
+
+func (f *CollectionField) IsIndexed() bool {
+	return f.Index == nil || *f.Index
+}
+
+type (
+	CollectionAlias struct {
+		Name           string `json:"name"`
+		CollectionName string `json:"collection_name"`
+	}
+	ReindexOptions struct {
+		Collection string `json:"collection" validate:"required"`
+		// Source is one of jsonl, mysql, collection
+		Source    string `json:"source" validate:"required,oneof=jsonl mysql collection"`
+		File      string `json:"file"`
+		Table     string `json:"table"`
+		BatchSize int    `json:"batch_size"`
+		// MinDocumentRatio is the lowest accepted num_documents of the new version compared to the live one
+		MinDocumentRatio float64  `json:"min_document_ratio"`
+		SampleQueries    []string `json:"sample_queries"`
+		// Adopt builds the first version of a plain collection that has the name of the alias, the collection keeps
+		// serving until the version is adopted
+		Adopt bool `json:"adopt"`
+	}
+	ReindexCheck struct {
+		Name   string `json:"name"`
+		Passed bool   `json:"passed"`
+		Detail string `json:"detail"`
+	}
+	ReindexReport struct {
+		Collection   string         `json:"collection"`
+		Version      string         `json:"version"`
+		Previous     string         `json:"previous"`
+		NumDocuments int64          `json:"num_documents"`
+		Import       *ImportReport  `json:"import,omitempty"`
+		Checks       []ReindexCheck `json:"checks"`
+		Switched     bool           `json:"switched"`
+		Pruned       []string       `json:"pruned"`
+	}
+	CollectionVersions struct {
+		Collection string           `json:"collection"`
+		Live       string           `json:"live"`
+		Versions   []CollectionInfo `json:"versions"`
+	}
+)
+
+const (
+	ReindexSourceJSONL      = "jsonl"
+	ReindexSourceMySQL      = "mysql"
+	ReindexSourceCollection = "collection"
+)
@@ -0,0 +1,58 @@
+package models
+
+type (
+	// ConversationMessage is a turn of a search conversation, a follow-up is rewritten with the previous turns
+	// of the session before it is searched
+	ConversationMessage struct {
+		// SessionID tells apart the conversations of a customer, default by default
+		SessionID  string               `json:"session_id" validate:"omitempty,max=64"`
+		Message    string               `json:"message" validate:"required,max=1000"`
+		Collection string               `json:"collection"`
+		Mode       string               `json:"mode" validate:"omitempty,oneof=keyword vector hybrid"`
+		Conditions []ConditionSearching `json:"conditions"`
+		Filter     *FilterSearching     `json:"filter"`
+		Page       int                  `json:"page"`
+		PerPage    int                  `json:"per_page"`
+		// Backend is the engine of the keyword or vector search, Field the embedding field of the vector search
+		Backend string `json:"backend"`
+		Field   string `json:"field"`
+	}
+	// ConversationTurn is what a session keeps of a message, Query is the standalone query searched for it
+	ConversationTurn struct {
+		Message string `json:"message"`
+		Query   string `json:"query"`
+		Found   int64  `json:"found"`
+		At      int64  `json:"at"`
+	}
+	// ConversationReply carries the query searched so clients can show "searching for ..."
+	ConversationReply struct {
+		SessionID string `json:"session_id"`
+		Query     string `json:"query"`
+		// Rewritten is false when the message was searched as sent
+		Rewritten bool   `json:"rewritten"`
+		Rewriter  string `json:"rewriter"`
+		// Turns is the number of turns the session keeps, this one included
+		Turns  int           `json:"turns"`
+		Result *SearchResult `json:"result"`
+	}
+	ConversationSession struct {
+		SessionID string             `json:"session_id"`
+		Turns     []ConversationTurn `json:"turns"`
+		// ExpiresIn is in seconds, every turn starts it again
+		ExpiresIn int64 `json:"expires_in"`
+	}
+	// ConversationSessionRequest names a session of the customer, All clears every one of them
+	ConversationSessionRequest struct {
+		SessionID string `json:"session_id" validate:"omitempty,max=64"`
+		All       bool   `json:"all"`
+	}
+	ConversationCleared struct {
+		Sessions int `json:"sessions"`
+	}
+)
+
+const (
+	DefaultConversationSession = "default"
+	RewriterRule               = "rule"
+	RewriterLLM                = "llm"
+)
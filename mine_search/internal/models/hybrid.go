@@ -0,0 +1,64 @@
+package models
+
+type (
+	// HybridSearch runs Text as a keyword search and as a vector search, then fuses the two rankings
+	HybridSearch struct {
+		Collection string `json:"collection"`
+		Text       string `json:"text" validate:"required"`
+		// Field is the embedding field searched, the first one of the schema by default
+		Field      string               `json:"field"`
+		Conditions []ConditionSearching `json:"conditions"`
+		Filter     *FilterSearching     `json:"filter"`
+		Page       int                  `json:"page"`
+		PerPage    int                  `json:"per_page"`
+		// Backend names the engine of the keyword search, VectorBackend the one of the vector search, typesense by default
+		Backend       string `json:"backend"`
+		VectorBackend string `json:"vector_backend"`
+		// Fusion is rrf (reciprocal rank fusion) or weighted (min-max normalized scores), HYBRID_FUSION by default
+		Fusion string `json:"fusion" validate:"omitempty,oneof=rrf weighted"`
+		// Alpha is the weight of the vector search, 0 ranks by keywords only and 1 by vectors only, HYBRID_ALPHA by default
+		Alpha *float64 `json:"alpha" validate:"omitempty,gte=0,lte=1"`
+		// RankConstant is the k of rrf, 1/(k+rank), 60 by default
+		RankConstant int `json:"rank_constant" validate:"gte=0,lte=1000"`
+		// Candidates is the number of hits fetched from each search, page·per_page at least
+		Candidates int `json:"candidates" validate:"gte=0,lte=100"`
+	}
+	// HybridScore tells how a hybrid hit was ranked, a rank is missing when that search did not return the document
+	HybridScore struct {
+		Score       float64 `json:"score"`
+		KeywordRank *int    `json:"keyword_rank,omitempty"`
+		VectorRank  *int    `json:"vector_rank,omitempty"`
+	}
+)
+
+const (
+	FusionRRF      = "rrf"
+	FusionWeighted = "weighted"
+)
+
+// KeywordSearch is the keyword half of the hybrid search, the first candidates hits of the text query
+func (h *HybridSearch) KeywordSearch(candidates int) Search {
+	return Search{
+		Collection: h.Collection,
+		Text:       h.Text,
+		Conditions: h.Conditions,
+		Filter:     h.Filter,
+		Page:       1,
+		PerPage:    candidates,
+		Backend:    h.Backend,
+	}
+}
+
+// VectorSearch is the vector half of the hybrid search, the candidates nearest neighbors of the embedded text
+func (h *HybridSearch) VectorSearch(candidates int) VectorSearch {
+	return VectorSearch{
+		Collection: h.Collection,
+		Text:       h.Text,
+		Field:      h.Field,
+		K:          candidates,
+		Conditions: h.Conditions,
+		Filter:     h.Filter,
+		Page:       1,
+		PerPage:    candidates,
+	}
+}
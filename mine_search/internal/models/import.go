@@ -0,0 +1,36 @@
+package models
+
+type (
+	ImportOptions struct {
+		Collection string `json:"collection" validate:"required"`
+		Action     string `json:"action"`
+		BatchSize  int    `json:"batch_size"`
+		// SkipLines resumes an import after the lines an earlier run already sent
+		SkipLines int `json:"skip_lines"`
+	}
+	ImportLineError struct {
+		Line     int    `json:"line"`
+		Error    string `json:"error"`
+		Document string `json:"document"`
+	}
+	ImportReport struct {
+		Collection string `json:"collection"`
+		Action     string `json:"action"`
+		// LastLine is the last line of the file sent to Typesense, a resumed import starts after it
+		LastLine  int  `json:"last_line"`
+		Skipped   int  `json:"skipped"`
+		Succeeded int  `json:"succeeded"`
+		Failed    int  `json:"failed"`
+		Done      bool `json:"done"`
+		// Chunks and ChunksFailed count the chunks written to the child collection of a chunked collection
+		Chunks       int               `json:"chunks,omitempty"`
+		ChunksFailed int               `json:"chunks_failed,omitempty"`
+		Errors       []ImportLineError `json:"errors,omitempty"`
+	}
+)
+
+const (
+	ImportActionCreate = "create"
+	ImportActionUpsert = "upsert"
+	ImportActionUpdate = "update"
+)
@@ -0,0 +1,24 @@
+package models
+
+// MySQLScoreColumn is the alias of the MATCH relevance selected with the rows
+const MySQLScoreColumn = "_text_match"
+
+// Text modes of the mysql backend
+const (
+	MySQLModeNatural = "natural"
+	MySQLModeBoolean = "boolean"
+	// MySQLModeLike needs no FULLTEXT index, it scans the table
+	MySQLModeLike = "like"
+)
+
+// MySQLQuery is a search compiled for one table, Where and Score hold ? placeholders bound to their Args
+type MySQLQuery struct {
+	Table     string
+	Where     string
+	Args      []interface{}
+	Score     string
+	ScoreArgs []interface{}
+	OrderBy   string
+	Offset    int
+	Limit     int
+}
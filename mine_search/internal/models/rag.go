@@ -0,0 +1,54 @@
+package models
+
+type (
+	// RAGAsk answers Question in natural language from the K documents of Collection retrieved for it
+	RAGAsk struct {
+		Collection string `json:"collection"`
+		Question   string `json:"question" validate:"required,max=1000"`
+		// Mode is the retrieval: keyword, vector or hybrid, hybrid by default when the collection has an embedding field
+		Mode string `json:"mode" validate:"omitempty,oneof=keyword vector hybrid"`
+		// K is the number of documents retrieved, 5 by default
+		K          int                  `json:"k" validate:"gte=0,lte=20"`
+		Conditions []ConditionSearching `json:"conditions"`
+		Filter     *FilterSearching     `json:"filter"`
+		// Backend is the engine of the keyword or vector retrieval, Field the embedding field of the vector retrieval
+		Backend string `json:"backend"`
+		Field   string `json:"field"`
+		// Fusion and Alpha tune the hybrid retrieval as on the hybrid search route
+		Fusion string   `json:"fusion" validate:"omitempty,oneof=rrf weighted"`
+		Alpha  *float64 `json:"alpha" validate:"omitempty,gte=0,lte=1"`
+		// Fields are the document fields given to the model, the query_by fields of the schema by default
+		Fields []string `json:"fields"`
+		// MaxContextTokens bounds the documents given to the model, RAG_CONTEXT_TOKENS by default
+		MaxContextTokens int `json:"max_context_tokens" validate:"gte=0,lte=32000"`
+	}
+	RAGAnswer struct {
+		Answer string `json:"answer"`
+		// Citations are the documents the answer cites, in the order they are first cited
+		Citations []RAGCitation `json:"citations"`
+		Provider  string        `json:"provider"`
+		Model     string        `json:"model,omitempty"`
+		Mode      string        `json:"mode"`
+		// Documents counts the hits given to the model, the last ones may not fit the context
+		Documents     int   `json:"documents"`
+		ContextTokens int   `json:"context_tokens"`
+		SearchTimeMs  int64 `json:"search_time_ms"`
+		AnswerTimeMs  int64 `json:"answer_time_ms"`
+		// Backend and Degraded are the ones of the retrieval
+		Backend  string `json:"backend,omitempty"`
+		Degraded bool   `json:"degraded"`
+	}
+	// RAGCitation points back to a hit of the retrieval, Rank is its position from 1
+	RAGCitation struct {
+		ID       string                 `json:"id"`
+		Rank     int                    `json:"rank"`
+		Document map[string]interface{} `json:"document"`
+	}
+)
+
+// Retrieval modes of the RAG and chunk searches
+const (
+	RetrievalKeyword = "keyword"
+	RetrievalVector  = "vector"
+	RetrievalHybrid  = "hybrid"
+)
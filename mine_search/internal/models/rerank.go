@@ -0,0 +1,56 @@
+package models
+
+type (
+	// RerankSearch retrieves the TopN first hits of Text, rescores them with the rerank stages and pages the new order
+	RerankSearch struct {
+		Collection string `json:"collection"`
+		Text       string `json:"text" validate:"required"`
+		// Mode is the first-stage retrieval, keyword, vector or hybrid, keyword by default
+		Mode       string               `json:"mode" validate:"omitempty,oneof=keyword vector hybrid"`
+		Conditions []ConditionSearching `json:"conditions"`
+		Filter     *FilterSearching     `json:"filter"`
+		Page       int                  `json:"page"`
+		PerPage    int                  `json:"per_page"`
+		// Backend names the engine of the keyword retrieval, typesense by default
+		Backend string   `json:"backend"`
+		Fusion  string   `json:"fusion" validate:"omitempty,oneof=rrf weighted"`
+		Alpha   *float64 `json:"alpha" validate:"omitempty,gte=0,lte=1"`
+		// TopN is the number of first-stage hits rescored, RERANK_TOP_N by default, page·per_page at least
+		TopN int `json:"top_n" validate:"gte=0,lte=100"`
+		// Stages replaces the RERANK_STAGES pipeline for this request
+		Stages []RerankStage `json:"stages" validate:"omitempty,max=8,dive"`
+		// Debug adds the score breakdown of every hit
+		Debug bool `json:"debug"`
+	}
+	// RerankStage is a pass of the pipeline. It rescores the TopN first hits of the previous pass, all of them
+	// when TopN is 0, and adds Weight times its score to theirs
+	RerankStage struct {
+		Name   string   `json:"name" validate:"required,oneof=field_match freshness popularity cross_encoder"`
+		Weight *float64 `json:"weight" validate:"omitempty,gte=0,lte=10"`
+		TopN   int      `json:"top_n" validate:"gte=0,lte=100"`
+	}
+	// RerankPass is the timing of a stage, Error is set when it failed and was skipped
+	RerankPass struct {
+		Name   string  `json:"name"`
+		Weight float64 `json:"weight"`
+		Hits   int     `json:"hits"`
+		TimeMs float64 `json:"time_ms"`
+		Error  string  `json:"error,omitempty"`
+	}
+	// RerankScore is the breakdown of a reranked hit. Retrieval scores the first-stage rank, 1 for the first hit,
+	// Stages holds the unweighted score of each stage that rescored the hit
+	RerankScore struct {
+		Score     float64            `json:"score"`
+		Retrieval float64            `json:"retrieval"`
+		FirstRank int                `json:"first_rank"`
+		Stages    map[string]float64 `json:"stages"`
+	}
+)
+
+// Rerankers of RerankStage.Name
+const (
+	RerankerFieldMatch   = "field_match"
+	RerankerFreshness    = "freshness"
+	RerankerPopularity   = "popularity"
+	RerankerCrossEncoder = "cross_encoder"
+)
@@ -0,0 +1,55 @@
+package models
+
+type (
+	FacetValue struct {
+		Value       string `json:"value"`
+		Count       int64  `json:"count"`
+		Highlighted string `json:"highlighted,omitempty"`
+	}
+	// FacetStats is only filled with min, max, avg, sum for numeric fields
+	FacetStats struct {
+		Min         *float64 `json:"min,omitempty"`
+		Max         *float64 `json:"max,omitempty"`
+		Avg         *float64 `json:"avg,omitempty"`
+		Sum         *float64 `json:"sum,omitempty"`
+		TotalValues int64    `json:"total_values"`
+	}
+	FacetResult struct {
+		Field  string       `json:"field"`
+		Values []FacetValue `json:"values"`
+		Stats  *FacetStats  `json:"stats,omitempty"`
+	}
+	// SearchHit is one matched document, Highlights holds the marked snippets of every matched field
+	SearchHit struct {
+		Document   map[string]interface{} `json:"document"`
+		TextMatch  float64                `json:"text_match"`
+		Highlights map[string][]string    `json:"highlights"`
+		// DistanceMeters is set by geo searches sorted by distance
+		DistanceMeters *float64 `json:"distance_meters,omitempty"`
+		// VectorDistance is set by vector searches, 0 for the same direction
+		VectorDistance *float64 `json:"vector_distance,omitempty"`
+		// Hybrid is set by hybrid searches with the fused score and the rank of each search
+		Hybrid *HybridScore `json:"hybrid,omitempty"`
+		// Chunks are the matching chunks of a parent document, best first, set by chunk searches
+		Chunks []ChunkMatch `json:"chunks,omitempty"`
+		// Rerank is the score breakdown of a reranked hit, set in debug mode
+		Rerank *RerankScore `json:"rerank,omitempty"`
+	}
+	// SearchResult is the response of every search route whatever engine answered it
+	SearchResult struct {
+		Found        int64         `json:"found"`
+		Page         int           `json:"page"`
+		PerPage      int           `json:"per_page"`
+		SearchTimeMs int64         `json:"search_time_ms"`
+		Hits         []SearchHit   `json:"hits"`
+		Facets       []FacetResult `json:"facets"`
+		// NextCursor is empty on the last page
+		NextCursor string `json:"next_cursor,omitempty"`
+		// Backend is the engine that answered, Degraded is set when it is a failover secondary
+		// because the engine asked for was unavailable
+		Backend  string `json:"backend,omitempty"`
+		Degraded bool   `json:"degraded"`
+		// Rerank times the passes of a reranked search, in order
+		Rerank []RerankPass `json:"rerank,omitempty"`
+	}
+)
@@ -2,16 +2,174 @@ package models
 
 type (
 	ConditionSearching struct {
-		ConditionName  string `json:"name"`
-		ConditionValue string `json:"value"`
+		ConditionName     string   `json:"name"`
+		ConditionOperator string   `json:"operator"`
+		ConditionValue    string   `json:"value"`
+		ConditionValues   []string `json:"values"`
 	}
 	OrderBySearching struct {
 		OrderByName  string `json:"name"`
 		OrderByValue string `json:"value"`
 	}
+	// FilterSearching is a node of a boolean filter tree, a leaf holds a Condition
+	// and a group holds Operator (and, or, not) with its child Filters
+	FilterSearching struct {
+		FilterOperator string              `json:"operator"`
+		Filters        []FilterSearching   `json:"filters"`
+		Condition      *ConditionSearching `json:"condition"`
+	}
+	// FacetSearching counts the values of a facet field, Ranges buckets a numeric field instead
+	FacetSearching struct {
+		FacetName   string       `json:"name"`
+		FacetRanges []FacetRange `json:"ranges"`
+	}
+	// FacetRange is the bucket [Min, Max) named Label
+	FacetRange struct {
+		Label string  `json:"label"`
+		Min   float64 `json:"min"`
+		Max   float64 `json:"max"`
+	}
 	Search struct {
-		Text       string               `json:"text" validate:"required"`
-		Conditions []ConditionSearching `json:"conditions"`
-		OrderBys   []OrderBySearching   `json:"order_bys"`
+		Collection     string               `json:"collection"`
+		Text           string               `json:"text" validate:"required"`
+		Conditions     []ConditionSearching `json:"conditions"`
+		Filter         *FilterSearching     `json:"filter"`
+		OrderBys       []OrderBySearching   `json:"order_bys"`
+		FacetBy        []FacetSearching     `json:"facet_by"`
+		MaxFacetValues int                  `json:"max_facet_values" validate:"gte=0,lte=100"`
+		// FacetQuery narrows the values of one facet, e.g. "authors:rowl"
+		FacetQuery string `json:"facet_query"`
+		Page       int    `json:"page"`
+		PerPage    int    `json:"per_page"`
+		// Cursor is the next_cursor of the previous response, it replaces page
+		Cursor string        `json:"cursor"`
+		Geo    *GeoSearching `json:"geo"`
+		// Backend names the engine of the generic search routes, engine routes ignore it
+		Backend string `json:"backend"`
+		// TextMode is how the mysql backend matches text: boolean (default), natural or like
+		TextMode string `json:"text_mode" validate:"omitempty,oneof=natural boolean like"`
+	}
+	GeoPoint struct {
+		Lat float64 `json:"lat"`
+		Lng float64 `json:"lng"`
+	}
+	GeoBoundingBox struct {
+		TopLeft     GeoPoint `json:"top_left"`
+		BottomRight GeoPoint `json:"bottom_right"`
+	}
+	// GeoSearching keeps the documents whose geopoint Field is within RadiusKm of Point, the BoundingBox
+	// or the Polygon, documents are sorted by distance when Point is set
+	GeoSearching struct {
+		GeoField    string          `json:"field"`
+		Point       *GeoPoint       `json:"point"`
+		RadiusKm    float64         `json:"radius_km"`
+		BoundingBox *GeoBoundingBox `json:"bounding_box"`
+		Polygon     []GeoPoint      `json:"polygon"`
+		// DistanceOrder is asc (nearest first) unless desc
+		DistanceOrder string `json:"distance_order"`
+		// Limit caps the documents considered by engines that cannot skip results, such as Redis GEOSEARCH
+		Limit int `json:"limit"`
 	}
+	// LocationSearch is the body of the location-search routes, Text defaults to every document
+	LocationSearch struct {
+		Collection string   `json:"collection"`
+		Text       string   `json:"text"`
+		Lat        *float64 `json:"lat"`
+		Lng        *float64 `json:"lng"`
+		RadiusKm   float64  `json:"radius_km"`
+		// Radius is given in Unit (m, km, mi, ft), it replaces RadiusKm
+		Radius        float64              `json:"radius"`
+		Unit          string               `json:"unit"`
+		DistanceOrder string               `json:"distance_order"`
+		Limit         int                  `json:"limit"`
+		BoundingBox   *GeoBoundingBox      `json:"bounding_box"`
+		Polygon       []GeoPoint           `json:"polygon"`
+		Field         string               `json:"field"`
+		Conditions    []ConditionSearching `json:"conditions"`
+		Filter        *FilterSearching     `json:"filter"`
+		OrderBys      []OrderBySearching   `json:"order_bys"`
+		Page          int                  `json:"page"`
+		PerPage       int                  `json:"per_page"`
+		Cursor        string               `json:"cursor"`
+		Backend       string               `json:"backend"`
+	}
+)
+
+// Operators of ConditionSearching, an empty operator means OperatorEq
+const (
+	OperatorEq     = "eq"
+	OperatorNe     = "ne"
+	OperatorGt     = "gt"
+	OperatorGte    = "gte"
+	OperatorLt     = "lt"
+	OperatorLte    = "lte"
+	OperatorRange  = "range"
+	OperatorIn     = "in"
+	OperatorNotIn  = "not_in"
+	OperatorExists = "exists"
+)
+
+// Operators of FilterSearching groups
+const (
+	FilterAnd = "and"
+	FilterOr  = "or"
+	FilterNot = "not"
 )
+
+func (c ConditionSearching) Operator() string {
+	if c.ConditionOperator == "" {
+		return OperatorEq
+	}
+	return c.ConditionOperator
+}
+
+func (l *LocationSearch) ToSearch() Search {
+	search := Search{
+		Collection: l.Collection,
+		Text:       l.Text,
+		Conditions: l.Conditions,
+		Filter:     l.Filter,
+		OrderBys:   l.OrderBys,
+		Page:       l.Page,
+		PerPage:    l.PerPage,
+		Cursor:     l.Cursor,
+		Backend:    l.Backend,
+		Geo: &GeoSearching{
+			GeoField:      l.Field,
+			RadiusKm:      l.RadiusKm,
+			BoundingBox:   l.BoundingBox,
+			Polygon:       l.Polygon,
+			DistanceOrder: l.DistanceOrder,
+			Limit:         l.Limit,
+		},
+	}
+	if l.Radius != 0 {
+		unit := l.Unit
+		if unit == "" {
+			unit = GeoUnitKm
+		}
+		search.Geo.RadiusKm = l.Radius * GeoUnits[unit]
+	}
+	if search.Text == "" {
+		search.Text = "*"
+	}
+	if l.Lat != nil && l.Lng != nil {
+		search.Geo.Point = &GeoPoint{Lat: *l.Lat, Lng: *l.Lng}
+	}
+	return search
+}
+
+const (
+	GeoUnitM  = "m"
+	GeoUnitKm = "km"
+	GeoUnitMi = "mi"
+	GeoUnitFt = "ft"
+)
+
+// GeoUnits is the length of each unit in km
+var GeoUnits = map[string]float64{
+	GeoUnitM:  0.001,
+	GeoUnitKm: 1,
+	GeoUnitMi: 1.609344,
+	GeoUnitFt: 0.0003048,
+}
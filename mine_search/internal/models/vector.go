@@ -0,0 +1,33 @@
+package models
+
+type (
+	// VectorSearch finds the K documents nearest to Vector, or to the embedding of Text, in an embedding field
+	VectorSearch struct {
+		Collection string    `json:"collection"`
+		Text       string    `json:"text" validate:"required_without=Vector"`
+		Vector     []float32 `json:"vector" validate:"required_without=Text"`
+		// Field is the embedding field searched, the first one of the schema by default
+		Field string `json:"field"`
+		// K is the number of neighbors, page·per_page by default
+		K int `json:"k" validate:"gte=0,lte=250"`
+		// DistanceThreshold drops the neighbors farther than it, cosine distances go from 0 to 2
+		DistanceThreshold float64              `json:"distance_threshold" validate:"gte=0,lte=2"`
+		Conditions        []ConditionSearching `json:"conditions"`
+		Filter            *FilterSearching     `json:"filter"`
+		Page              int                  `json:"page"`
+		PerPage           int                  `json:"per_page"`
+		// Backend is typesense by default, or hnsw to search the in-process graphs
+		Backend string `json:"backend"`
+	}
+)
+
+func (v *VectorSearch) ToSearch() Search {
+	return Search{
+		Collection: v.Collection,
+		Text:       "*",
+		Conditions: v.Conditions,
+		Filter:     v.Filter,
+		Page:       v.Page,
+		PerPage:    v.PerPage,
+	}
+}
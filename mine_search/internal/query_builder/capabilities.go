@@ -0,0 +1,50 @@
+package query_builder
+
+import (
+	"mine/internal/models"
+	"strconv"
+	"strings"
+)
+
+// CheckCapabilities rejects the parts of a request the engine named backend cannot answer,
+// a request is refused rather than answered with part of it ignored
+func CheckCapabilities(backend string, capabilities models.BackendCapabilities, dataInput models.Search) error {
+	errs := FieldErrors{}
+	unsupported := func(field string) {
+		errs = append(errs, newFieldError(field, "not_supported_by_engine", backend))
+	}
+	if text := strings.TrimSpace(dataInput.Text); !capabilities.Text && text != "" && text != "*" {
+		unsupported("text")
+	}
+	if !capabilities.Conditions && len(dataInput.Conditions) > 0 {
+		unsupported("conditions")
+	}
+	if !capabilities.Filter && dataInput.Filter != nil {
+		unsupported("filter")
+	}
+	if !capabilities.Facets && (len(dataInput.FacetBy) > 0 || dataInput.FacetQuery != "") {
+		unsupported("facet_by")
+	}
+	if len(dataInput.OrderBys) > capabilities.MaxSortFields {
+		if capabilities.MaxSortFields == 0 {
+			unsupported("order_bys")
+		} else {
+			errs = append(errs, newFieldError("order_bys", "max", strconv.Itoa(capabilities.MaxSortFields)))
+		}
+	}
+	if geo := dataInput.Geo; geo != nil {
+		if !capabilities.GeoRadius && geo.Point != nil && geo.BoundingBox == nil && len(geo.Polygon) == 0 {
+			unsupported("geo.radius_km")
+		}
+		if !capabilities.GeoBox && geo.BoundingBox != nil {
+			unsupported("geo.bounding_box")
+		}
+		if !capabilities.GeoPolygon && len(geo.Polygon) > 0 {
+			unsupported("geo.polygon")
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package query_builder
+
+import (
+	"mine/internal/models"
+)
+
+// DefaultChunksPerDocument is the number of chunks kept on a parent hit by default
+const DefaultChunksPerDocument = 3
+
+// TypeSenseInFilter matches the documents whose string field is one of values, field:=[`a`,`b`]
+func TypeSenseInFilter(field string, values []string) string {
+	return field + ":=[" + typeSenseValues(&models.CollectionField{Name: field, Type: models.FieldTypeString}, values) + "]"
+}
@@ -0,0 +1,202 @@
+package query_builder
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultPerPage = 10
+	MaxPerPage     = 100
+	// MaxPage limits page based paging, deeper results are reached with cursors
+	MaxPage         = 50
+	MaxCursorOffset = 10000
+)
+
+var (
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrCursorMismatch = errors.New("cursor belongs to another query")
+	ErrCursorDrift    = errors.New("results moved since the cursor was made")
+)
+
+// Cursor is the position reached by a client, it is signed so the offset and query cannot be forged.
+// SortValues are the order_by values of the last hit sent followed by its id, the next page must start after them
+type Cursor struct {
+	QueryHash  string        `json:"h"`
+	Offset     int           `json:"o"`
+	PerPage    int           `json:"n"`
+	SortValues []interface{} `json:"s,omitempty"`
+}
+
+// ValidatePaging checks page and per_page limits, page is ignored when a cursor is given
+func ValidatePaging(dataInput models.Search) FieldErrors {
+	errs := FieldErrors{}
+	if dataInput.PerPage < 0 || dataInput.PerPage > MaxPerPage {
+		errs = append(errs, newFieldError("per_page", "max", strconv.Itoa(MaxPerPage)))
+	}
+	if dataInput.Cursor != "" {
+		return errs
+	}
+	if dataInput.Page < 0 || dataInput.Page > MaxPage {
+		errs = append(errs, newFieldError("page", "max", strconv.Itoa(MaxPage)))
+	}
+	return errs
+}
+
+// PerPage returns the per_page of the request or the default one
+func PerPage(dataInput models.Search) int {
+	if dataInput.PerPage == 0 {
+		return DefaultPerPage
+	}
+	return dataInput.PerPage
+}
+
+// CurrentPage returns the page of the request, pages start at 1
+func CurrentPage(dataInput models.Search) int {
+	if dataInput.Page == 0 {
+		return 1
+	}
+	return dataInput.Page
+}
+
+// QueryHash identifies everything of a request but its position, a cursor is only valid for the same hash
+func QueryHash(collection string, dataInput models.Search) string {
+	dataInput.Collection = collection
+	dataInput.Page, dataInput.PerPage, dataInput.Cursor = 0, 0, ""
+	content, _ := json.Marshal(dataInput)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:16])
+}
+
+func EncodeCursor(key string, cursor Cursor) string {
+	content, _ := json.Marshal(cursor)
+	payload := base64.RawURLEncoding.EncodeToString(content)
+	return payload + "." + signCursor(key, payload)
+}
+
+func DecodeCursor(key, token string) (*Cursor, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(key, payload))) {
+		return nil, ErrInvalidCursor
+	}
+	content, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &Cursor{}
+	if err := json.Unmarshal(content, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.PerPage <= 0 || cursor.PerPage > MaxPerPage || cursor.Offset < 0 || cursor.Offset > MaxCursorOffset ||
+		cursor.Offset%cursor.PerPage != 0 {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func signCursor(key, payload string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ApplyCursor replaces page and per_page of dataInput with the position of its cursor and returns the cursor, nil
+// without one. It fails with ErrCursorMismatch when the query is not the one the cursor was made for
+func ApplyCursor(key, collection string, dataInput *models.Search) (*Cursor, error) {
+	if dataInput.Cursor == "" {
+		return nil, nil
+	}
+	cursor, err := DecodeCursor(key, dataInput.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.QueryHash != QueryHash(collection, *dataInput) ||
+		(dataInput.PerPage != 0 && dataInput.PerPage != cursor.PerPage) ||
+		len(cursor.SortValues) != len(dataInput.OrderBys)+1 {
+		return nil, ErrCursorMismatch
+	}
+	dataInput.PerPage = cursor.PerPage
+	dataInput.Page = cursor.Offset/cursor.PerPage + 1
+	return cursor, nil
+}
+
+// CheckCursor fails with ErrCursorDrift when the page found at the offset of cursor does not continue the previous
+// one: its first hit is the last hit already sent, or sorts before it. Documents indexed or deleted since then have
+// moved the results and the client would see hits twice
+func CheckCursor(cursor *Cursor, dataInput models.Search, result *models.SearchResult) error {
+	if cursor == nil || len(result.Hits) == 0 {
+		return nil
+	}
+	first := sortValues(dataInput, result.Hits[0])
+	last := len(first) - 1
+	if first[last] != nil && fmt.Sprint(first[last]) == fmt.Sprint(cursor.SortValues[last]) {
+		return ErrCursorDrift
+	}
+	for idx, orderBy := range dataInput.OrderBys {
+		order := compareSortValues(first[idx], cursor.SortValues[idx])
+		// order_bys are descending unless asc
+		if !strings.EqualFold(orderBy.OrderByValue, "asc") {
+			order = -order
+		}
+		if order < 0 {
+			return ErrCursorDrift
+		}
+		if order > 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// sortValues are the order_by values of hit followed by its id
+func sortValues(dataInput models.Search, hit models.SearchHit) []interface{} {
+	values := make([]interface{}, 0, len(dataInput.OrderBys)+1)
+	for _, orderBy := range dataInput.OrderBys {
+		values = append(values, hit.Document[orderBy.OrderByName])
+	}
+	return append(values, hit.Document["id"])
+}
+
+// compareSortValues orders two values of an order_by field, numbers by value and anything else by its text.
+// A missing value is equal to any other, it cannot tell the order
+func compareSortValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		return 0
+	}
+	numberA, errA := strconv.ParseFloat(fmt.Sprint(a), 64)
+	numberB, errB := strconv.ParseFloat(fmt.Sprint(b), 64)
+	if errA == nil && errB == nil {
+		switch {
+		case numberA < numberB:
+			return -1
+		case numberA > numberB:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// NextCursor returns the cursor of the page following result, or "" when there is none
+func NextCursor(key, collection string, dataInput models.Search, result *models.SearchResult) string {
+	perPage := PerPage(dataInput)
+	offset := CurrentPage(dataInput) * perPage
+	if len(result.Hits) < perPage || int64(offset) >= result.Found || offset > MaxCursorOffset {
+		return ""
+	}
+	cursor := Cursor{
+		QueryHash:  QueryHash(collection, dataInput),
+		Offset:     offset,
+		PerPage:    perPage,
+		SortValues: sortValues(dataInput, result.Hits[len(result.Hits)-1]),
+	}
+	return EncodeCursor(key, cursor)
+}
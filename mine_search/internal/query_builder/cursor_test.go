@@ -0,0 +1,190 @@
+package query_builder
+
+import (
+	"errors"
+	"mine/internal/models"
+	"strings"
+	"testing"
+)
+
+const testCursorKey = "test key"
+
+func TestCursorSignAndVerify(t *testing.T) {
+	valid := EncodeCursor(testCursorKey, Cursor{QueryHash: "h", Offset: 20, PerPage: 10, SortValues: []interface{}{"7"}})
+	payload, signature, _ := strings.Cut(valid, ".")
+	forged := EncodeCursor("another key", Cursor{QueryHash: "h", Offset: 20, PerPage: 10})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	tests := []struct {
+		name  string
+		key   string
+		token string
+		want  error
+	}{
+		{"valid", testCursorKey, valid, nil},
+		{"other key", "another key", valid, ErrInvalidCursor},
+		{"payload of another cursor", testCursorKey, forgedPayload + "." + signature, ErrInvalidCursor},
+		{"no signature", testCursorKey, payload, ErrInvalidCursor},
+		{"empty", testCursorKey, "", ErrInvalidCursor},
+		{"offset not on a page", testCursorKey, EncodeCursor(testCursorKey, Cursor{QueryHash: "h", Offset: 15, PerPage: 10}), ErrInvalidCursor},
+		{"offset too deep", testCursorKey, EncodeCursor(testCursorKey, Cursor{QueryHash: "h", Offset: MaxCursorOffset + 10, PerPage: 10}), ErrInvalidCursor},
+		{"per_page too large", testCursorKey, EncodeCursor(testCursorKey, Cursor{QueryHash: "h", Offset: 0, PerPage: MaxPerPage + 1}), ErrInvalidCursor},
+	}
+	for _, test := range tests {
+		cursor, err := DecodeCursor(test.key, test.token)
+		if !errors.Is(err, test.want) {
+			t.Errorf("DecodeCursor(%s) = %v, want %v", test.name, err, test.want)
+			continue
+		}
+		if err == nil && (cursor.Offset != 20 || cursor.PerPage != 10 || len(cursor.SortValues) != 1) {
+			t.Errorf("DecodeCursor(%s) = %+v, want offset 20, per_page 10 and one sort value", test.name, cursor)
+		}
+	}
+}
+
+func testPage(perPage int, found int64) *models.SearchResult {
+	result := &models.SearchResult{Found: found}
+	for idx := 0; idx < perPage; idx++ {
+		result.Hits = append(result.Hits, models.SearchHit{Document: map[string]interface{}{
+			"id":               string(rune('a' + idx)),
+			"publication_year": float64(2000 - idx),
+		}})
+	}
+	return result
+}
+
+func TestApplyCursor(t *testing.T) {
+	first := models.Search{Text: "harry", OrderBys: []models.OrderBySearching{{OrderByName: "publication_year"}}}
+	token := NextCursor(testCursorKey, "books", first, testPage(10, 100))
+	if token == "" {
+		t.Fatal("NextCursor of a full first page is empty")
+	}
+	tests := []struct {
+		name       string
+		collection string
+		change     func(*models.Search)
+		want       error
+	}{
+		{"same query", "books", func(*models.Search) {}, nil},
+		{"page is ignored", "books", func(s *models.Search) { s.Page = 7 }, nil},
+		{"other text", "books", func(s *models.Search) { s.Text = "potter" }, ErrCursorMismatch},
+		{"other collection", "stores", func(*models.Search) {}, ErrCursorMismatch},
+		{"other per_page", "books", func(s *models.Search) { s.PerPage = 20 }, ErrCursorMismatch},
+		{"other order", "books", func(s *models.Search) { s.OrderBys[0].OrderByValue = "asc" }, ErrCursorMismatch},
+		{"forged", "books", func(s *models.Search) { s.Cursor = "x" + s.Cursor }, ErrInvalidCursor},
+	}
+	for _, test := range tests {
+		next := first
+		next.OrderBys = append([]models.OrderBySearching{}, first.OrderBys...)
+		next.Cursor = token
+		test.change(&next)
+		cursor, err := ApplyCursor(testCursorKey, test.collection, &next)
+		if !errors.Is(err, test.want) {
+			t.Errorf("ApplyCursor(%s) = %v, want %v", test.name, err, test.want)
+			continue
+		}
+		if err == nil && (cursor == nil || next.Page != 2 || next.PerPage != 10) {
+			t.Errorf("ApplyCursor(%s) gives page %d per_page %d, want page 2 per_page 10", test.name, next.Page, next.PerPage)
+		}
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	search := models.Search{Text: "harry"}
+	tests := []struct {
+		name   string
+		search models.Search
+		result *models.SearchResult
+		want   bool
+	}{
+		{"full page", search, testPage(10, 100), true},
+		{"short page", search, testPage(4, 100), false},
+		{"last page", models.Search{Text: "harry", Page: 10}, testPage(10, 100), false},
+		{"past the cursor depth", models.Search{Text: "harry", Page: MaxCursorOffset/10 + 1}, testPage(10, 1e6), false},
+	}
+	for _, test := range tests {
+		if got := NextCursor(testCursorKey, "books", test.search, test.result) != ""; got != test.want {
+			t.Errorf("NextCursor(%s) set = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCheckCursor(t *testing.T) {
+	byYear := models.Search{Text: "harry", OrderBys: []models.OrderBySearching{{OrderByName: "publication_year"}}}
+	byYearAsc := models.Search{Text: "harry", OrderBys: []models.OrderBySearching{{OrderByName: "publication_year", OrderByValue: "asc"}}}
+	relevance := models.Search{Text: "harry"}
+	hit := func(id string, year float64) *models.SearchResult {
+		return &models.SearchResult{Hits: []models.SearchHit{{Document: map[string]interface{}{"id": id, "publication_year": year}}}}
+	}
+	tests := []struct {
+		name   string
+		search models.Search
+		cursor *Cursor
+		page   *models.SearchResult
+		want   error
+	}{
+		{"no cursor", byYear, nil, hit("j", 1991), nil},
+		{"continues", byYear, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, hit("k", 1990), nil},
+		{"same value, other document", byYear, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, hit("k", 1991), nil},
+		{"last hit again", byYear, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, hit("j", 1991), ErrCursorDrift},
+		{"sorts before the last hit", byYear, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, hit("k", 1995), ErrCursorDrift},
+		{"ascending continues", byYearAsc, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, hit("k", 1995), nil},
+		{"ascending sorts before", byYearAsc, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, hit("k", 1990), ErrCursorDrift},
+		{"relevance continues", relevance, &Cursor{SortValues: []interface{}{"j"}}, hit("k", 0), nil},
+		{"relevance last hit again", relevance, &Cursor{SortValues: []interface{}{"j"}}, hit("j", 0), ErrCursorDrift},
+		{"empty page", byYear, &Cursor{SortValues: []interface{}{float64(1991), "j"}}, &models.SearchResult{}, nil},
+	}
+	for _, test := range tests {
+		if err := CheckCursor(test.cursor, test.search, test.page); !errors.Is(err, test.want) {
+			t.Errorf("CheckCursor(%s) = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestValidatePaging(t *testing.T) {
+	tests := []struct {
+		name   string
+		search models.Search
+		want   []string
+	}{
+		{"defaults", models.Search{}, nil},
+		{"limits", models.Search{Page: MaxPage, PerPage: MaxPerPage}, nil},
+		{"page too deep", models.Search{Page: MaxPage + 1}, []string{"page"}},
+		{"negative page", models.Search{Page: -1}, []string{"page"}},
+		{"per_page too large", models.Search{PerPage: MaxPerPage + 1}, []string{"per_page"}},
+		{"cursor ignores page", models.Search{Page: MaxPage + 1, Cursor: "token"}, nil},
+		{"cursor keeps per_page", models.Search{PerPage: MaxPerPage + 1, Cursor: "token"}, []string{"per_page"}},
+	}
+	for _, test := range tests {
+		if got := errorFields(ValidatePaging(test.search)); !equalStrings(got, test.want) {
+			t.Errorf("ValidatePaging(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func errorFields(errs FieldErrors) []string {
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func errorTags(errs FieldErrors) []string {
+	tags := []string{}
+	for _, err := range errs {
+		tags = append(tags, err.Tag)
+	}
+	return tags
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package query_builder
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxFacetFields = 10
+	MaxFacetRanges = 20
+)
+
+var facetLabelPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ValidateFacets checks that facet_by names facet enabled fields, ranges only bucket numeric fields
+// and facet_query targets one of the requested facets
+func ValidateFacets(schema *models.CollectionSchema, dataInput models.Search) FieldErrors {
+	errs := FieldErrors{}
+	if len(dataInput.FacetBy) > MaxFacetFields {
+		return FieldErrors{newFieldError("facet_by", "max", strconv.Itoa(MaxFacetFields))}
+	}
+	requested := map[string]bool{}
+	for idx, facet := range dataInput.FacetBy {
+		path := fmt.Sprintf("facet_by[%d]", idx)
+		field, ok := schema.GetField(facet.FacetName)
+		if !ok || !field.Facet {
+			errs = append(errs, newFieldError(path+".name", "not_facetable", facet.FacetName))
+			continue
+		}
+		requested[facet.FacetName] = true
+		if len(facet.FacetRanges) == 0 {
+			continue
+		}
+		if !field.IsNumeric() {
+			errs = append(errs, newFieldError(path+".ranges", "ranges_need_numeric_field", field.Type))
+			continue
+		}
+		if len(facet.FacetRanges) > MaxFacetRanges {
+			errs = append(errs, newFieldError(path+".ranges", "max", strconv.Itoa(MaxFacetRanges)))
+			continue
+		}
+		labels := map[string]bool{}
+		for rangeIdx, facetRange := range facet.FacetRanges {
+			rangePath := fmt.Sprintf("%s.ranges[%d]", path, rangeIdx)
+			if !facetLabelPattern.MatchString(facetRange.Label) || labels[facetRange.Label] {
+				errs = append(errs, newFieldError(rangePath+".label", "invalid_label", facetRange.Label))
+			}
+			labels[facetRange.Label] = true
+			if facetRange.Min >= facetRange.Max {
+				errs = append(errs, newFieldError(rangePath, "range_min_greater_than_max",
+					fmt.Sprintf("%v,%v", facetRange.Min, facetRange.Max)))
+			}
+		}
+	}
+	if dataInput.FacetQuery != "" {
+		name, value, ok := strings.Cut(dataInput.FacetQuery, ":")
+		if !ok || strings.TrimSpace(value) == "" {
+			errs = append(errs, newFieldError("facet_query", "invalid_value", dataInput.FacetQuery))
+		} else if !requested[name] {
+			errs = append(errs, newFieldError("facet_query", "facet_not_requested", name))
+		}
+	}
+	return errs
+}
+
+// TypeSenseFacetBy renders facet_by, a facet with ranges becomes field(label:[min, max], ...)
+func TypeSenseFacetBy(facets []models.FacetSearching) string {
+	result := make([]string, 0, len(facets))
+	for _, facet := range facets {
+		if len(facet.FacetRanges) == 0 {
+			result = append(result, facet.FacetName)
+			continue
+		}
+		ranges := make([]string, 0, len(facet.FacetRanges))
+		for _, facetRange := range facet.FacetRanges {
+			ranges = append(ranges, fmt.Sprintf("%s:[%s, %s]", facetRange.Label,
+				strconv.FormatFloat(facetRange.Min, 'f', -1, 64), strconv.FormatFloat(facetRange.Max, 'f', -1, 64)))
+		}
+		result = append(result, fmt.Sprintf("%s(%s)", facet.FacetName, strings.Join(ranges, ", ")))
+	}
+	return strings.Join(result, ",")
+}
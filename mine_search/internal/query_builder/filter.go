@@ -0,0 +1,95 @@
+package query_builder
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"strconv"
+)
+
+const (
+	MaxFilterDepth = 5
+	MaxFilterNodes = 50
+)
+
+// FilterDialect renders a filter tree for one engine. NOT groups are pushed down to the
+// conditions with De Morgan's laws so a dialect only has to negate single conditions
+type FilterDialect interface {
+	Condition(field *models.CollectionField, condition models.ConditionSearching, negate bool) string
+	And(parts []string) string
+	Or(parts []string) string
+}
+
+// ValidateFilter checks shape, depth and size of the tree and every condition inside it
+func ValidateFilter(schema *models.CollectionSchema, filter *models.FilterSearching) FieldErrors {
+	if filter == nil {
+		return nil
+	}
+	nodes := 0
+	errs := validateFilterNode(schema, filter, "filter", 1, false, &nodes)
+	if nodes > MaxFilterNodes {
+		errs = append(errs, newFieldError("filter", "max_nodes", strconv.Itoa(MaxFilterNodes)))
+	}
+	return errs
+}
+
+func validateFilterNode(schema *models.CollectionSchema, node *models.FilterSearching, path string, depth int, negated bool, nodes *int) FieldErrors {
+	*nodes++
+	if depth > MaxFilterDepth {
+		return FieldErrors{newFieldError(path, "max_depth", strconv.Itoa(MaxFilterDepth))}
+	}
+	if node.Condition != nil {
+		if node.FilterOperator != "" || len(node.Filters) > 0 {
+			return FieldErrors{newFieldError(path, "condition_with_children", node.FilterOperator)}
+		}
+		errs := ValidateCondition(schema, *node.Condition, path+".condition")
+		if len(errs) == 0 && negated && node.Condition.Operator() == models.OperatorExists {
+			errs = append(errs, newFieldError(path+".condition.operator", "operator_not_negatable", models.OperatorExists))
+		}
+		return errs
+	}
+	switch node.FilterOperator {
+	case models.FilterAnd, models.FilterOr:
+		if len(node.Filters) == 0 {
+			return FieldErrors{newFieldError(path+".filters", "required", "")}
+		}
+	case models.FilterNot:
+		if len(node.Filters) != 1 {
+			return FieldErrors{newFieldError(path+".filters", "not_needs_one_filter", strconv.Itoa(len(node.Filters)))}
+		}
+		negated = !negated
+	default:
+		return FieldErrors{newFieldError(path+".operator", "unknown_operator", node.FilterOperator)}
+	}
+	errs := FieldErrors{}
+	for idx := range node.Filters {
+		errs = append(errs, validateFilterNode(schema, &node.Filters[idx], fmt.Sprintf("%s.filters[%d]", path, idx), depth+1, negated, nodes)...)
+	}
+	return errs
+}
+
+// CompileFilter expects a tree already checked by ValidateFilter
+func CompileFilter(schema *models.CollectionSchema, filter *models.FilterSearching, dialect FilterDialect) string {
+	if filter == nil {
+		return ""
+	}
+	return compileFilterNode(schema, filter, dialect, false)
+}
+
+func compileFilterNode(schema *models.CollectionSchema, node *models.FilterSearching, dialect FilterDialect, negate bool) string {
+	if node.Condition != nil {
+		field, _ := schema.GetField(node.Condition.ConditionName)
+		return dialect.Condition(field, *node.Condition, negate)
+	}
+	if node.FilterOperator == models.FilterNot {
+		return compileFilterNode(schema, &node.Filters[0], dialect, !negate)
+	}
+	parts := make([]string, 0, len(node.Filters))
+	for idx := range node.Filters {
+		parts = append(parts, compileFilterNode(schema, &node.Filters[idx], dialect, negate))
+	}
+	// NOT (a AND b) = NOT a OR NOT b, NOT (a OR b) = NOT a AND NOT b
+	if (node.FilterOperator == models.FilterAnd) != negate {
+		return dialect.And(parts)
+	}
+	return dialect.Or(parts)
+}
@@ -0,0 +1,152 @@
+package query_builder
+
+import (
+	"mine/internal/models"
+	"testing"
+)
+
+var testSchema = &models.CollectionSchema{
+	Name: "books",
+	Fields: []models.CollectionField{
+		{Name: "title", Type: models.FieldTypeString},
+		{Name: "authors", Type: models.FieldTypeStringArray, Facet: true},
+		{Name: "publication_year", Type: models.FieldTypeInt32, Facet: true},
+		{Name: "average_rating", Type: models.FieldTypeFloat},
+		{Name: "in_stock", Type: models.FieldTypeBool},
+		{Name: "location", Type: models.FieldTypeGeopoint},
+	},
+	QueryBy: []string{"title", "authors"},
+}
+
+func condition(name, operator, value string, values ...string) *models.ConditionSearching {
+	return &models.ConditionSearching{ConditionName: name, ConditionOperator: operator, ConditionValue: value, ConditionValues: values}
+}
+
+func leaf(c *models.ConditionSearching) models.FilterSearching {
+	return models.FilterSearching{Condition: c}
+}
+
+func group(operator string, filters ...models.FilterSearching) models.FilterSearching {
+	return models.FilterSearching{FilterOperator: operator, Filters: filters}
+}
+
+func TestValidateConditionOperators(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition *models.ConditionSearching
+		want      []string
+	}{
+		{"eq on string", condition("title", "", "Dune"), nil},
+		{"in on string array", condition("authors", models.OperatorIn, "", "Frank Herbert", "Isaac Asimov"), nil},
+		{"gt on string", condition("title", models.OperatorGt, "Dune"), []string{"operator_not_supported"}},
+		{"exists on string", condition("title", models.OperatorExists, ""), []string{"operator_not_supported"}},
+		{"range on int", condition("publication_year", models.OperatorRange, "", "1950", "2000"), nil},
+		{"range reversed", condition("publication_year", models.OperatorRange, "", "2000", "1950"), []string{"range_min_greater_than_max"}},
+		{"range with one value", condition("publication_year", models.OperatorRange, "", "1950"), []string{"range_needs_two_values"}},
+		{"float value on int", condition("publication_year", models.OperatorGte, "1950.5"), []string{"invalid_value"}},
+		{"lte on float", condition("average_rating", models.OperatorLte, "4.5"), nil},
+		{"exists on float", condition("average_rating", models.OperatorExists, ""), nil},
+		{"eq on bool", condition("in_stock", "", "true"), nil},
+		{"in on bool", condition("in_stock", models.OperatorIn, "", "true"), []string{"operator_not_supported"}},
+		{"not a bool", condition("in_stock", "", "yes"), []string{"invalid_value"}},
+		{"unknown field", condition("isbn", "", "1"), []string{"unknown_field"}},
+		{"geopoint", condition("location", "", "1"), []string{"geopoint_needs_geo"}},
+		{"in without values", condition("authors", models.OperatorIn, ""), []string{"required"}},
+	}
+	for _, test := range tests {
+		if got := errorTags(ValidateCondition(testSchema, *test.condition, "condition")); !equalStrings(got, test.want) {
+			t.Errorf("ValidateCondition(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCompileFilterPushesNotDown(t *testing.T) {
+	recent := leaf(condition("publication_year", models.OperatorGte, "2000"))
+	herbert := leaf(condition("authors", "", "Frank Herbert"))
+	tests := []struct {
+		name   string
+		filter models.FilterSearching
+		want   string
+	}{
+		{"and", group(models.FilterAnd, recent, herbert), "(publication_year:>=2000 && authors:=`Frank Herbert`)"},
+		{"not and", group(models.FilterNot, group(models.FilterAnd, recent, herbert)), "(publication_year:<2000 || authors:!=`Frank Herbert`)"},
+		{"not or", group(models.FilterNot, group(models.FilterOr, recent, herbert)), "(publication_year:<2000 && authors:!=`Frank Herbert`)"},
+		{"not not", group(models.FilterNot, group(models.FilterNot, recent)), "publication_year:>=2000"},
+		{"not range", group(models.FilterNot, leaf(condition("publication_year", models.OperatorRange, "", "1950", "2000"))),
+			"(publication_year:<1950 || publication_year:>2000)"},
+		{"not in", group(models.FilterNot, leaf(condition("authors", models.OperatorIn, "", "A", "B"))), "authors:!=[`A`,`B`]"},
+		{"nested", group(models.FilterNot, group(models.FilterAnd, recent, group(models.FilterOr, herbert, group(models.FilterNot, recent)))),
+			"(publication_year:<2000 || (authors:!=`Frank Herbert` && publication_year:>=2000))"},
+	}
+	for _, test := range tests {
+		if errs := ValidateFilter(testSchema, &test.filter); len(errs) > 0 {
+			t.Errorf("ValidateFilter(%s) = %v", test.name, errorTags(errs))
+			continue
+		}
+		if got := CompileFilter(testSchema, &test.filter, TypeSenseDialect{}); got != test.want {
+			t.Errorf("CompileFilter(%s) = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateFilterLimits(t *testing.T) {
+	recent := leaf(condition("publication_year", models.OperatorGte, "2000"))
+	deep := func(depth int) models.FilterSearching {
+		node := recent
+		for level := 1; level < depth; level++ {
+			node = group(models.FilterAnd, node)
+		}
+		return node
+	}
+	wide := func(leaves int) models.FilterSearching {
+		node := group(models.FilterOr)
+		for idx := 0; idx < leaves; idx++ {
+			node.Filters = append(node.Filters, recent)
+		}
+		return node
+	}
+	tests := []struct {
+		name   string
+		filter models.FilterSearching
+		want   []string
+	}{
+		{"max depth", deep(MaxFilterDepth), nil},
+		{"too deep", deep(MaxFilterDepth + 1), []string{"max_depth"}},
+		{"max nodes", wide(MaxFilterNodes - 1), nil},
+		{"too many nodes", wide(MaxFilterNodes), []string{"max_nodes"}},
+		{"empty group", group(models.FilterAnd), []string{"required"}},
+		{"not with two filters", group(models.FilterNot, recent, recent), []string{"not_needs_one_filter"}},
+		{"unknown operator", group("xor", recent), []string{"unknown_operator"}},
+		{"condition with children", models.FilterSearching{FilterOperator: models.FilterAnd, Condition: recent.Condition}, []string{"condition_with_children"}},
+		{"negated exists", group(models.FilterNot, leaf(condition("average_rating", models.OperatorExists, ""))), []string{"operator_not_negatable"}},
+	}
+	for _, test := range tests {
+		if got := errorTags(ValidateFilter(testSchema, &test.filter)); !equalStrings(got, test.want) {
+			t.Errorf("ValidateFilter(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestTypeSenseFilterByEscapesValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition *models.ConditionSearching
+		want      string
+	}{
+		{"backtick", condition("title", "", "The `Hobbit`"), "title:=`The \\`Hobbit\\``"},
+		{"operators stay literal", condition("title", "", "a && b:=[c]"), "title:=`a && b:=[c]`"},
+		{"comma in a list", condition("authors", models.OperatorIn, "", "Tolkien, J.R.R.", "x`"), "authors:=[`Tolkien, J.R.R.`,`x\\``]"},
+		{"number is reformatted", condition("publication_year", "", "0042"), "publication_year:=42"},
+		{"bool is reformatted", condition("in_stock", "", "1"), "in_stock:=true"},
+	}
+	for _, test := range tests {
+		search := models.Search{Text: "*", Conditions: []models.ConditionSearching{*test.condition}}
+		if err := ValidateSearch(testSchema, search); err != nil {
+			t.Errorf("ValidateSearch(%s) = %v", test.name, err)
+			continue
+		}
+		if got := TypeSenseFilterBy(testSchema, search); got != test.want {
+			t.Errorf("TypeSenseFilterBy(%s) = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
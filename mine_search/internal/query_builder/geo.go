@@ -0,0 +1,176 @@
+package query_builder
+
+import (
+	"fmt"
+	"math"
+	"mine/internal/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxRadiusKm      = 1000
+	MaxPolygonPoints = 50
+	// MaxGeoLimit is the default and highest geo limit
+	MaxGeoLimit = 1000
+	// earthRadiusKm is the mean radius used by Redis as well
+	earthRadiusKm = 6372.797560856
+	// MaxSortFields is the number of sort_by fields Typesense accepts
+	MaxSortFields = 3
+)
+
+// ValidateLocationSearch checks what is lost once a location request becomes a Search, lat and lng go together
+func ValidateLocationSearch(dataInput models.LocationSearch) error {
+	if (dataInput.Lat == nil) != (dataInput.Lng == nil) {
+		return FieldErrors{newFieldError("lat,lng", "required_together", "")}
+	}
+	if dataInput.Lat == nil && dataInput.RadiusKm == 0 && dataInput.BoundingBox == nil && len(dataInput.Polygon) == 0 {
+		return FieldErrors{newFieldError("lat,lng", "required", "")}
+	}
+	errs := FieldErrors{}
+	if dataInput.Radius != 0 && dataInput.RadiusKm != 0 {
+		errs = append(errs, newFieldError("radius", "excluded_with", "radius_km"))
+	}
+	if _, ok := models.GeoUnits[dataInput.Unit]; dataInput.Unit != "" && !ok {
+		errs = append(errs, newFieldError("unit", "oneof", "m km mi ft"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// GeoField returns the geopoint field searched by geo, the first geopoint of the schema unless one is named
+func GeoField(schema *models.CollectionSchema, geo *models.GeoSearching) (*models.CollectionField, bool) {
+	if geo.GeoField != "" {
+		field, ok := schema.GetField(geo.GeoField)
+		return field, ok && field.IsGeo()
+	}
+	for idx := range schema.Fields {
+		if schema.Fields[idx].IsGeo() {
+			return &schema.Fields[idx], true
+		}
+	}
+	return nil, false
+}
+
+func ValidateGeo(schema *models.CollectionSchema, dataInput models.Search) FieldErrors {
+	geo := dataInput.Geo
+	if geo == nil {
+		return nil
+	}
+	if _, ok := GeoField(schema, geo); !ok {
+		return FieldErrors{newFieldError("geo.field", "not_geopoint", geo.GeoField)}
+	}
+	errs := FieldErrors{}
+	if geo.Point != nil {
+		errs = append(errs, validateGeoPoint("geo.point", *geo.Point)...)
+		if len(dataInput.OrderBys) >= MaxSortFields {
+			errs = append(errs, newFieldError("order_bys", "max", strconv.Itoa(MaxSortFields-1)))
+		}
+	}
+	switch geo.DistanceOrder {
+	case "", "asc", "desc":
+	default:
+		errs = append(errs, newFieldError("geo.distance_order", "invalid_direction", geo.DistanceOrder))
+	}
+	if geo.Limit < 0 || geo.Limit > MaxGeoLimit {
+		errs = append(errs, newFieldError("geo.limit", "max", strconv.Itoa(MaxGeoLimit)))
+	}
+	areas := 0
+	if geo.RadiusKm != 0 {
+		areas++
+		if geo.Point == nil {
+			errs = append(errs, newFieldError("geo.point", "required_with_radius", ""))
+		}
+		if geo.RadiusKm < 0 || geo.RadiusKm > MaxRadiusKm {
+			errs = append(errs, newFieldError("geo.radius_km", "max", strconv.Itoa(MaxRadiusKm)))
+		}
+	}
+	if box := geo.BoundingBox; box != nil {
+		areas++
+		errs = append(errs, validateGeoPoint("geo.bounding_box.top_left", box.TopLeft)...)
+		errs = append(errs, validateGeoPoint("geo.bounding_box.bottom_right", box.BottomRight)...)
+		if box.TopLeft.Lat <= box.BottomRight.Lat || box.TopLeft.Lng >= box.BottomRight.Lng {
+			errs = append(errs, newFieldError("geo.bounding_box", "invalid_corners", ""))
+		}
+	}
+	if len(geo.Polygon) > 0 {
+		areas++
+		if len(geo.Polygon) < 3 || len(geo.Polygon) > MaxPolygonPoints {
+			errs = append(errs, newFieldError("geo.polygon", "polygon_points", fmt.Sprintf("3..%d", MaxPolygonPoints)))
+		}
+		for idx, point := range geo.Polygon {
+			errs = append(errs, validateGeoPoint(fmt.Sprintf("geo.polygon[%d]", idx), point)...)
+		}
+	}
+	if areas > 1 {
+		errs = append(errs, newFieldError("geo", "one_area_only", "radius_km, bounding_box, polygon"))
+	}
+	return errs
+}
+
+func validateGeoPoint(path string, point models.GeoPoint) FieldErrors {
+	errs := FieldErrors{}
+	if point.Lat < -90 || point.Lat > 90 {
+		errs = append(errs, newFieldError(path+".lat", "invalid_value", formatFloat(point.Lat)))
+	}
+	if point.Lng < -180 || point.Lng > 180 {
+		errs = append(errs, newFieldError(path+".lng", "invalid_value", formatFloat(point.Lng)))
+	}
+	return errs
+}
+
+// TypeSenseGeoFilter renders the area of geo, a bounding box is sent as a four corner polygon
+func TypeSenseGeoFilter(schema *models.CollectionSchema, geo *models.GeoSearching) string {
+	field, _ := GeoField(schema, geo)
+	points := []models.GeoPoint{}
+	switch {
+	case geo.RadiusKm > 0:
+		return fmt.Sprintf("%s:(%s, %s, %s km)", field.Name, formatFloat(geo.Point.Lat), formatFloat(geo.Point.Lng), formatFloat(geo.RadiusKm))
+	case geo.BoundingBox != nil:
+		box := geo.BoundingBox
+		points = append(points,
+			box.TopLeft,
+			models.GeoPoint{Lat: box.TopLeft.Lat, Lng: box.BottomRight.Lng},
+			box.BottomRight,
+			models.GeoPoint{Lat: box.BottomRight.Lat, Lng: box.TopLeft.Lng})
+	case len(geo.Polygon) > 0:
+		points = geo.Polygon
+	default:
+		return ""
+	}
+	coordinates := make([]string, 0, len(points)*2)
+	for _, point := range points {
+		coordinates = append(coordinates, formatFloat(point.Lat), formatFloat(point.Lng))
+	}
+	return fmt.Sprintf("%s:(%s)", field.Name, strings.Join(coordinates, ", "))
+}
+
+// TypeSenseGeoSort sorts by distance to the point of geo, Typesense then returns geo_distance_meters per hit
+func TypeSenseGeoSort(schema *models.CollectionSchema, geo *models.GeoSearching) string {
+	if geo == nil || geo.Point == nil {
+		return ""
+	}
+	field, _ := GeoField(schema, geo)
+	return fmt.Sprintf("%s(%s, %s):%s", field.Name, formatFloat(geo.Point.Lat), formatFloat(geo.Point.Lng), GeoDistanceOrder(geo))
+}
+
+func GeoDistanceOrder(geo *models.GeoSearching) string {
+	if geo.DistanceOrder == "desc" {
+		return "desc"
+	}
+	return "asc"
+}
+
+// DistanceKm is the haversine distance between two points
+func DistanceKm(from, to models.GeoPoint) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat, dLng := lat2-lat1, (to.Lng-from.Lng)*math.Pi/180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
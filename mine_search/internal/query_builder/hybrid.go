@@ -0,0 +1,47 @@
+package query_builder
+
+import (
+	"mine/internal/models"
+	"strconv"
+)
+
+// DefaultCandidates is the number of hits fetched from each search of a hybrid or chunk request by default,
+// a document ranked low by one search can still rise with the other
+const DefaultCandidates = 50
+
+// Candidates returns the number of hits fetched from each search of a request merging several of them,
+// candidates when it is set. The requested page must fall in them since the merged ranking only knows the candidates
+func Candidates(page, perPage, candidates int) (int, error) {
+	search := models.Search{Page: page, PerPage: perPage}
+	if errs := ValidatePaging(search); len(errs) > 0 {
+		return 0, errs
+	}
+	last := CurrentPage(search) * PerPage(search)
+	if candidates == 0 {
+		candidates = DefaultCandidates
+		if last > candidates {
+			candidates = last
+		}
+	}
+	if candidates > MaxPerPage {
+		candidates = MaxPerPage
+	}
+	if last > candidates {
+		return 0, FieldErrors{newFieldError("page", "max_candidates", strconv.Itoa(candidates))}
+	}
+	return candidates, nil
+}
+
+// PageRange returns the bounds of page among the count candidates ranked by a request merging several searches,
+// an empty range past the last one
+func PageRange(page, perPage, count int) (int, int) {
+	from := (page - 1) * perPage
+	if from > count {
+		from = count
+	}
+	to := from + perPage
+	if to > count {
+		to = count
+	}
+	return from, to
+}
@@ -0,0 +1,253 @@
+package query_builder
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/utils"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mysqlIdentifier matches every table and column name a query may hold, they come from the schema registry
+// and are checked again since they cannot be bound as parameters
+var mysqlIdentifier = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// likeEscaper keeps the wildcards of a token literal in a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// MySQLFullTextIndexName is the FULLTEXT index on the query_by columns MATCH needs
+func MySQLFullTextIndexName(collection string) string {
+	return "ft_" + collection
+}
+
+// MySQLSearch compiles a request for the table named like the collection. Filters and sorts only reach
+// columns of the schema, text is matched with MATCH AGAINST in natural or boolean mode, or with LIKE
+func MySQLSearch(schema *models.CollectionSchema, dataInput models.Search) (*models.MySQLQuery, error) {
+	errs := FieldErrors{}
+	if err := ValidateSearch(schema, dataInput); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	if len(dataInput.FacetBy) > 0 {
+		errs = append(errs, newFieldError("facet_by", "not_supported_by_engine", models.BackendMySQL))
+	}
+	if dataInput.Geo != nil {
+		errs = append(errs, newFieldError("geo", "not_supported_by_engine", models.BackendMySQL))
+	}
+	mode := dataInput.TextMode
+	if mode == "" {
+		mode = models.MySQLModeBoolean
+	}
+	switch mode {
+	case models.MySQLModeNatural, models.MySQLModeBoolean, models.MySQLModeLike:
+	default:
+		errs = append(errs, newFieldError("text_mode", "invalid_value", dataInput.TextMode))
+	}
+	if err := checkMySQLIdentifiers(schema.Name); err != nil {
+		errs = append(errs, newFieldError("collection", "invalid_table", schema.Name))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	perPage := PerPage(dataInput)
+	query := &models.MySQLQuery{
+		Table:  schema.Name,
+		Offset: (CurrentPage(dataInput) - 1) * perPage,
+		Limit:  perPage,
+	}
+	args := []interface{}{}
+	dialect := MySQLDialect{args: &args}
+	clauses := []string{}
+	if tokens := textTokens(dataInput.Text); len(tokens) > 0 {
+		switch mode {
+		case models.MySQLModeLike:
+			for _, token := range tokens {
+				parts := make([]string, 0, len(schema.QueryBy))
+				for _, column := range schema.QueryBy {
+					parts = append(parts, mysqlQuote(column)+" LIKE ?")
+					args = append(args, "%"+likeEscaper.Replace(token)+"%")
+				}
+				clauses = append(clauses, "("+strings.Join(parts, " OR ")+")")
+			}
+		default:
+			match, against := MySQLMatch(schema, tokens, mode)
+			clauses = append(clauses, match)
+			args = append(args, against)
+			query.Score = match
+			query.ScoreArgs = []interface{}{against}
+		}
+	}
+	for _, condition := range dataInput.Conditions {
+		field, _ := schema.GetField(condition.ConditionName)
+		clauses = append(clauses, dialect.Condition(field, condition, false))
+	}
+	if filter := CompileFilter(schema, dataInput.Filter, dialect); filter != "" {
+		clauses = append(clauses, filter)
+	}
+	query.Where = strings.Join(clauses, " AND ")
+	query.Args = args
+
+	orders := []string{}
+	for _, orderBy := range dataInput.OrderBys {
+		direction := strings.ToUpper(orderBy.OrderByValue)
+		if direction == "" {
+			direction = "DESC"
+		}
+		orders = append(orders, mysqlQuote(orderBy.OrderByName)+" "+direction)
+	}
+	if query.Score != "" {
+		orders = append(orders, mysqlQuote(models.MySQLScoreColumn)+" DESC")
+	}
+	if schema.DefaultSortingField != "" {
+		orders = append(orders, mysqlQuote(schema.DefaultSortingField)+" DESC")
+	}
+	query.OrderBy = strings.Join(append(orders, mysqlQuote("id")), ", ")
+	return query, nil
+}
+
+// MySQLMatch renders MATCH on the query_by columns. In boolean mode every token is required and the last
+// one is a prefix, tokens hold letters and digits only so they cannot carry boolean operators
+func MySQLMatch(schema *models.CollectionSchema, tokens []string, mode string) (string, string) {
+	if mode == models.MySQLModeNatural {
+		return fmt.Sprintf("MATCH(%s) AGAINST (? IN NATURAL LANGUAGE MODE)", mysqlColumns(schema.QueryBy)), strings.Join(tokens, " ")
+	}
+	terms := make([]string, 0, len(tokens))
+	for idx, token := range tokens {
+		term := "+" + token
+		if idx == len(tokens)-1 {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return fmt.Sprintf("MATCH(%s) AGAINST (? IN BOOLEAN MODE)", mysqlColumns(schema.QueryBy)), strings.Join(terms, " ")
+}
+
+// textTokens is empty for "*", which matches every row
+func textTokens(text string) []string {
+	if text = strings.TrimSpace(text); text == "*" {
+		return nil
+	}
+	return utils.Tokenize(text)
+}
+
+// MySQLDialect renders filter trees as a WHERE clause, values are appended to args in placeholder order
+type MySQLDialect struct {
+	args *[]interface{}
+}
+
+func (d MySQLDialect) Condition(field *models.CollectionField, condition models.ConditionSearching, negate bool) string {
+	clause := d.condition(field, condition)
+	if negate {
+		return "NOT " + clause
+	}
+	return clause
+}
+
+func (d MySQLDialect) condition(field *models.CollectionField, condition models.ConditionSearching) string {
+	column := mysqlQuote(field.Name)
+	// string[] columns hold comma separated values, spaces after the commas are ignored
+	if field.Type == models.FieldTypeStringArray {
+		values := []string{condition.ConditionValue}
+		if len(condition.ConditionValues) > 0 {
+			values = condition.ConditionValues
+		}
+		parts := make([]string, 0, len(values))
+		for _, value := range values {
+			parts = append(parts, "FIND_IN_SET(?, REPLACE("+column+", ', ', ',')) > 0")
+			*d.args = append(*d.args, value)
+		}
+		clause := "(" + strings.Join(parts, " OR ") + ")"
+		switch condition.Operator() {
+		case models.OperatorNe, models.OperatorNotIn:
+			return "(NOT " + clause + ")"
+		}
+		return clause
+	}
+	switch condition.Operator() {
+	case models.OperatorNe:
+		*d.args = append(*d.args, mysqlValue(field, condition.ConditionValue))
+		return "(" + column + " <> ?)"
+	case models.OperatorGt:
+		*d.args = append(*d.args, mysqlValue(field, condition.ConditionValue))
+		return "(" + column + " > ?)"
+	case models.OperatorGte:
+		*d.args = append(*d.args, mysqlValue(field, condition.ConditionValue))
+		return "(" + column + " >= ?)"
+	case models.OperatorLt:
+		*d.args = append(*d.args, mysqlValue(field, condition.ConditionValue))
+		return "(" + column + " < ?)"
+	case models.OperatorLte:
+		*d.args = append(*d.args, mysqlValue(field, condition.ConditionValue))
+		return "(" + column + " <= ?)"
+	case models.OperatorRange:
+		*d.args = append(*d.args, mysqlValue(field, condition.ConditionValues[0]), mysqlValue(field, condition.ConditionValues[1]))
+		return "(" + column + " BETWEEN ? AND ?)"
+	case models.OperatorIn, models.OperatorNotIn:
+		placeholders := make([]string, 0, len(condition.ConditionValues))
+		for _, value := range condition.ConditionValues {
+			placeholders = append(placeholders, "?")
+			*d.args = append(*d.args, mysqlValue(field, value))
+		}
+		operator := " IN "
+		if condition.Operator() == models.OperatorNotIn {
+			operator = " NOT IN "
+		}
+		return "(" + column + operator + "(" + strings.Join(placeholders, ", ") + "))"
+	case models.OperatorExists:
+		return "(" + column + " IS NOT NULL)"
+	}
+	*d.args = append(*d.args, mysqlValue(field, condition.ConditionValue))
+	return "(" + column + " = ?)"
+}
+
+func (MySQLDialect) And(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " AND ") + ")"
+}
+
+func (MySQLDialect) Or(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// mysqlValue binds numbers and bools with their type so MySQL compares them as such
+func mysqlValue(field *models.CollectionField, value string) interface{} {
+	switch {
+	case field.IsInteger():
+		number, _ := strconv.ParseInt(value, 10, 64)
+		return number
+	case field.IsNumeric():
+		number, _ := strconv.ParseFloat(value, 64)
+		return number
+	case field.IsBool():
+		flag, _ := strconv.ParseBool(value)
+		return flag
+	}
+	return value
+}
+
+func checkMySQLIdentifiers(names ...string) error {
+	for _, name := range names {
+		if !mysqlIdentifier.MatchString(name) {
+			return fmt.Errorf("%w: invalid mysql identifier %q", ErrInvalidQuery, name)
+		}
+	}
+	return nil
+}
+
+func mysqlQuote(name string) string {
+	return "`" + name + "`"
+}
+
+func mysqlColumns(names []string) string {
+	columns := make([]string, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, mysqlQuote(name))
+	}
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,219 @@
+package query_builder
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/utils"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RediSearchTagSuffix names the TAG attribute of a string field that is also searched as TEXT
+const RediSearchTagSuffix = "_tag"
+
+func RediSearchIndexName(collection string) string {
+	return "idx:" + collection
+}
+
+// RediSearchKeyPrefix is the prefix of the hashes holding the documents of collection, the id follows it
+func RediSearchKeyPrefix(collection string) string {
+	return "search:" + collection + ":"
+}
+
+// RediSearchCreateArgs renders FT.CREATE for schema: query_by strings are TEXT, every string is also
+// a TAG for exact conditions, numbers are NUMERIC and geopoints GEO
+func RediSearchCreateArgs(schema *models.CollectionSchema) []interface{} {
+	args := []interface{}{"FT.CREATE", RediSearchIndexName(schema.Name), "ON", "HASH",
+		"PREFIX", 1, RediSearchKeyPrefix(schema.Name), "SCHEMA"}
+	for idx := range schema.Fields {
+		field := &schema.Fields[idx]
+		switch {
+		case field.NumDim > 0:
+			// vectors are searched with Typesense only
+		case field.IsNumeric():
+			args = append(args, field.Name, "NUMERIC", "SORTABLE")
+		case field.IsBool():
+			args = append(args, field.Name, "TAG")
+		case field.IsGeo():
+			args = append(args, field.Name, "GEO")
+		case strings.HasPrefix(field.Type, models.FieldTypeString):
+			if isQueryBy(schema, field.Name) {
+				args = append(args, field.Name, "AS", field.Name, "TEXT")
+				if field.Sort {
+					args = append(args, "SORTABLE")
+				}
+			}
+			args = append(args, field.Name, "AS", RediSearchTagAttribute(schema, field), "TAG", "SEPARATOR", "|")
+		}
+	}
+	return args
+}
+
+// RediSearchTagAttribute is the attribute exact string conditions run on
+func RediSearchTagAttribute(schema *models.CollectionSchema, field *models.CollectionField) string {
+	if isQueryBy(schema, field.Name) {
+		return field.Name + RediSearchTagSuffix
+	}
+	return field.Name
+}
+
+func isQueryBy(schema *models.CollectionSchema, name string) bool {
+	for _, queryBy := range schema.QueryBy {
+		if queryBy == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RediSearchArgs compiles a search request into FT.SEARCH, FT.SEARCH sorts by one attribute only
+// and has no facets or geo areas, those requests are rejected instead of answered differently
+func RediSearchArgs(schema *models.CollectionSchema, dataInput models.Search) ([]interface{}, error) {
+	errs := FieldErrors{}
+	if err := ValidateSearch(schema, dataInput); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	if len(dataInput.OrderBys) > 1 {
+		errs = append(errs, newFieldError("order_bys", "max", "1"))
+	}
+	if len(dataInput.FacetBy) > 0 {
+		errs = append(errs, newFieldError("facet_by", "not_supported_by_engine", "redis"))
+	}
+	if dataInput.Geo != nil {
+		errs = append(errs, newFieldError("geo", "not_supported_by_engine", "redis"))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	perPage := PerPage(dataInput)
+	args := []interface{}{"FT.SEARCH", RediSearchIndexName(schema.Name), RediSearchQuery(schema, dataInput),
+		"WITHSCORES", "LIMIT", (CurrentPage(dataInput) - 1) * perPage, perPage}
+	for _, orderBy := range dataInput.OrderBys {
+		direction := strings.ToUpper(orderBy.OrderByValue)
+		if direction == "" {
+			direction = "DESC"
+		}
+		args = append(args, "SORTBY", orderBy.OrderByName, direction)
+	}
+	return args, nil
+}
+
+// RediSearchQuery is the query string of FT.SEARCH, the last text token is a prefix like Typesense does
+func RediSearchQuery(schema *models.CollectionSchema, dataInput models.Search) string {
+	clauses := []string{}
+	if tokens := utils.Tokenize(dataInput.Text); len(tokens) > 0 {
+		if last := tokens[len(tokens)-1]; len([]rune(last)) >= 2 {
+			tokens[len(tokens)-1] = last + "*"
+		}
+		clauses = append(clauses, fmt.Sprintf("@%s:(%s)", strings.Join(schema.QueryBy, "|"), strings.Join(tokens, " ")))
+	}
+	dialect := RediSearchDialect{Schema: schema}
+	for _, condition := range dataInput.Conditions {
+		field, _ := schema.GetField(condition.ConditionName)
+		clauses = append(clauses, dialect.Condition(field, condition, false))
+	}
+	if filter := CompileFilter(schema, dataInput.Filter, dialect); filter != "" {
+		clauses = append(clauses, filter)
+	}
+	if len(clauses) == 0 {
+		return "*"
+	}
+	return strings.Join(clauses, " ")
+}
+
+// RediSearchDialect renders filter trees in the RediSearch query syntax, Schema tells TAG attributes apart
+type RediSearchDialect struct {
+	Schema *models.CollectionSchema
+}
+
+func (d RediSearchDialect) Condition(field *models.CollectionField, condition models.ConditionSearching, negate bool) string {
+	clause := d.condition(field, condition)
+	if !negate {
+		return clause
+	}
+	if strings.HasPrefix(clause, "-") {
+		return clause[1:]
+	}
+	return "-" + clause
+}
+
+func (d RediSearchDialect) condition(field *models.CollectionField, condition models.ConditionSearching) string {
+	name := field.Name
+	if !field.IsNumeric() {
+		name = RediSearchTagAttribute(d.Schema, field)
+		values := []string{condition.ConditionValue}
+		if len(condition.ConditionValues) > 0 {
+			values = condition.ConditionValues
+		}
+		tags := make([]string, 0, len(values))
+		for _, value := range values {
+			tags = append(tags, rediSearchTag(field, value))
+		}
+		clause := fmt.Sprintf("@%s:{%s}", name, strings.Join(tags, " | "))
+		switch condition.Operator() {
+		case models.OperatorNe, models.OperatorNotIn:
+			return "-" + clause
+		}
+		return clause
+	}
+	value := typeSenseValue(field, condition.ConditionValue)
+	switch condition.Operator() {
+	case models.OperatorNe:
+		return fmt.Sprintf("-@%s:[%s %s]", name, value, value)
+	case models.OperatorGt:
+		return fmt.Sprintf("@%s:[(%s +inf]", name, value)
+	case models.OperatorGte:
+		return fmt.Sprintf("@%s:[%s +inf]", name, value)
+	case models.OperatorLt:
+		return fmt.Sprintf("@%s:[-inf (%s]", name, value)
+	case models.OperatorLte:
+		return fmt.Sprintf("@%s:[-inf %s]", name, value)
+	case models.OperatorRange:
+		return fmt.Sprintf("@%s:[%s %s]", name, typeSenseValue(field, condition.ConditionValues[0]), typeSenseValue(field, condition.ConditionValues[1]))
+	case models.OperatorIn, models.OperatorNotIn:
+		parts := make([]string, 0, len(condition.ConditionValues))
+		for _, item := range condition.ConditionValues {
+			item = typeSenseValue(field, item)
+			parts = append(parts, fmt.Sprintf("@%s:[%s %s]", name, item, item))
+		}
+		clause := "(" + strings.Join(parts, " | ") + ")"
+		if condition.Operator() == models.OperatorNotIn {
+			return "-" + clause
+		}
+		return clause
+	case models.OperatorExists:
+		return fmt.Sprintf("@%s:[-inf +inf]", name)
+	}
+	return fmt.Sprintf("@%s:[%s %s]", name, value, value)
+}
+
+func (RediSearchDialect) And(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func (RediSearchDialect) Or(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " | ") + ")"
+}
+
+// rediSearchTag escapes every character a TAG value cannot hold as is, spaces included
+func rediSearchTag(field *models.CollectionField, value string) string {
+	if field.IsBool() {
+		flag, _ := strconv.ParseBool(value)
+		return strconv.FormatBool(flag)
+	}
+	var builder strings.Builder
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
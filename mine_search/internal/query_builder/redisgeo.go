@@ -0,0 +1,80 @@
+package query_builder
+
+import (
+	"mine/internal/models"
+	"strings"
+)
+
+// RedisGeoKey is the sorted set holding the geopoint field of every document of collection
+func RedisGeoKey(collection, field string) string {
+	return "geo:" + collection + ":" + field
+}
+
+// RedisGeoSearchArgs compiles a geo request into GEOSEARCH ... WITHDIST, distances come back in km.
+// GEOSEARCH only knows circles and boxes around one point, so text, conditions and polygons are rejected
+// and a box is searched from its own center
+func RedisGeoSearchArgs(schema *models.CollectionSchema, dataInput models.Search) ([]interface{}, error) {
+	errs := FieldErrors{}
+	if dataInput.Geo == nil {
+		return nil, FieldErrors{newFieldError("geo", "required", "")}
+	}
+	if err := ValidateSearch(schema, dataInput); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	geo := dataInput.Geo
+	if text := strings.TrimSpace(dataInput.Text); text != "" && text != "*" {
+		errs = append(errs, newFieldError("text", "not_supported_by_engine", "redis geo"))
+	}
+	if len(dataInput.Conditions) > 0 || dataInput.Filter != nil {
+		errs = append(errs, newFieldError("conditions", "not_supported_by_engine", "redis geo"))
+	}
+	if len(dataInput.OrderBys) > 0 || len(dataInput.FacetBy) > 0 {
+		errs = append(errs, newFieldError("order_bys", "not_supported_by_engine", "redis geo"))
+	}
+	if len(geo.Polygon) > 0 {
+		errs = append(errs, newFieldError("geo.polygon", "not_supported_by_engine", "redis geo"))
+	}
+	if geo.BoundingBox != nil && geo.Point != nil {
+		errs = append(errs, newFieldError("geo.point", "excluded_with", "bounding_box"))
+	}
+	if geo.BoundingBox == nil && geo.Point == nil {
+		errs = append(errs, newFieldError("geo.point", "required", ""))
+	}
+	if geo.Point != nil && geo.RadiusKm == 0 && geo.Limit == 0 {
+		// every document up to MaxRadiusKm would be read to page a few of them
+		errs = append(errs, newFieldError("geo.radius_km", "required_without", "limit"))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	field, _ := GeoField(schema, geo)
+	args := append([]interface{}{"GEOSEARCH", RedisGeoKey(schema.Name, field.Name)}, redisGeoArea(geo)...)
+	limit := geo.Limit
+	if limit == 0 {
+		limit = MaxGeoLimit
+	}
+	return append(args, strings.ToUpper(GeoDistanceOrder(geo)), "COUNT", limit, "WITHDIST"), nil
+}
+
+// RedisGeoCountArgs compiles the area of a request checked by RedisGeoSearchArgs into GEOSEARCHSTORE dest, it
+// answers how many documents the area holds whatever COUNT bounded GEOSEARCH to. dest is to be deleted after
+func RedisGeoCountArgs(schema *models.CollectionSchema, dataInput models.Search, dest string) []interface{} {
+	field, _ := GeoField(schema, dataInput.Geo)
+	return append([]interface{}{"GEOSEARCHSTORE", dest, RedisGeoKey(schema.Name, field.Name)}, redisGeoArea(dataInput.Geo)...)
+}
+
+// redisGeoArea is the FROMLONLAT and BYBOX or BYRADIUS arguments of geo
+func redisGeoArea(geo *models.GeoSearching) []interface{} {
+	if box := geo.BoundingBox; box != nil {
+		center := models.GeoPoint{Lat: (box.TopLeft.Lat + box.BottomRight.Lat) / 2, Lng: (box.TopLeft.Lng + box.BottomRight.Lng) / 2}
+		width := DistanceKm(models.GeoPoint{Lat: center.Lat, Lng: box.TopLeft.Lng}, models.GeoPoint{Lat: center.Lat, Lng: box.BottomRight.Lng})
+		height := DistanceKm(models.GeoPoint{Lat: box.TopLeft.Lat, Lng: center.Lng}, models.GeoPoint{Lat: box.BottomRight.Lat, Lng: center.Lng})
+		return []interface{}{"FROMLONLAT", center.Lng, center.Lat, "BYBOX", width, height, "km"}
+	}
+	radius := geo.RadiusKm
+	if radius == 0 {
+		// a point with a limit is a nearest lookup, the limit bounds it instead of the radius
+		radius = MaxRadiusKm
+	}
+	return []interface{}{"FROMLONLAT", geo.Point.Lng, geo.Point.Lat, "BYRADIUS", radius, "km"}
+}
@@ -0,0 +1,178 @@
+package query_builder
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TypeSenseSearchParams compiles a search request into the query string of
+// /collections/{collection}/documents/search
+func TypeSenseSearchParams(schema *models.CollectionSchema, dataInput models.Search) (url.Values, error) {
+	if err := ValidateSearch(schema, dataInput); err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("q", dataInput.Text)
+	params.Set("query_by", strings.Join(schema.QueryBy, ","))
+	params.Set("page", strconv.Itoa(CurrentPage(dataInput)))
+	params.Set("per_page", strconv.Itoa(PerPage(dataInput)))
+	if filterBy := TypeSenseFilterBy(schema, dataInput); filterBy != "" {
+		params.Set("filter_by", filterBy)
+	}
+	// distance comes first, order_bys only break ties between documents at the same distance
+	sorts := []string{}
+	if geoSort := TypeSenseGeoSort(schema, dataInput.Geo); geoSort != "" {
+		sorts = append(sorts, geoSort)
+	}
+	if sortBy := TypeSenseSortBy(dataInput.OrderBys); sortBy != "" {
+		sorts = append(sorts, sortBy)
+	}
+	if len(sorts) > 0 {
+		params.Set("sort_by", strings.Join(sorts, ","))
+	}
+	if len(dataInput.FacetBy) > 0 {
+		params.Set("facet_by", TypeSenseFacetBy(dataInput.FacetBy))
+		if dataInput.MaxFacetValues > 0 {
+			params.Set("max_facet_values", strconv.Itoa(dataInput.MaxFacetValues))
+		}
+		if dataInput.FacetQuery != "" {
+			params.Set("facet_query", dataInput.FacetQuery)
+		}
+	}
+	return params, nil
+}
+
+// TypeSenseFilterBy joins the flat conditions and the filter tree with &&,
+// it expects a request already checked by ValidateSearch
+func TypeSenseFilterBy(schema *models.CollectionSchema, dataInput models.Search) string {
+	dialect := TypeSenseDialect{}
+	filters := []string{}
+	for _, condition := range dataInput.Conditions {
+		field, _ := schema.GetField(condition.ConditionName)
+		filters = append(filters, dialect.Condition(field, condition, false))
+	}
+	if filter := CompileFilter(schema, dataInput.Filter, dialect); filter != "" {
+		filters = append(filters, filter)
+	}
+	if dataInput.Geo != nil {
+		if geoFilter := TypeSenseGeoFilter(schema, dataInput.Geo); geoFilter != "" {
+			filters = append(filters, geoFilter)
+		}
+	}
+	return strings.Join(filters, " && ")
+}
+
+func TypeSenseSortBy(orderBys []models.OrderBySearching) string {
+	sorts := []string{}
+	for _, orderBy := range orderBys {
+		direction := strings.ToLower(orderBy.OrderByValue)
+		if direction == "" {
+			direction = "desc"
+		}
+		sorts = append(sorts, fmt.Sprintf("%s:%s", orderBy.OrderByName, direction))
+	}
+	return strings.Join(sorts, ",")
+}
+
+// TypeSenseDialect renders filter trees in filter_by syntax
+type TypeSenseDialect struct{}
+
+// typeSenseNegations maps an operator to the one matching exactly the other documents
+var typeSenseNegations = map[string]string{
+	models.OperatorEq:    models.OperatorNe,
+	models.OperatorNe:    models.OperatorEq,
+	models.OperatorGt:    models.OperatorLte,
+	models.OperatorGte:   models.OperatorLt,
+	models.OperatorLt:    models.OperatorGte,
+	models.OperatorLte:   models.OperatorGt,
+	models.OperatorIn:    models.OperatorNotIn,
+	models.OperatorNotIn: models.OperatorIn,
+}
+
+func (TypeSenseDialect) Condition(field *models.CollectionField, condition models.ConditionSearching, negate bool) string {
+	name := field.Name
+	operator := condition.Operator()
+	if negate {
+		if operator == models.OperatorRange {
+			return fmt.Sprintf("(%s:<%s || %s:>%s)", name, typeSenseValue(field, condition.ConditionValues[0]),
+				name, typeSenseValue(field, condition.ConditionValues[1]))
+		}
+		operator = typeSenseNegations[operator]
+	}
+	value := typeSenseValue(field, condition.ConditionValue)
+	switch operator {
+	case models.OperatorNe:
+		return fmt.Sprintf("%s:!=%s", name, value)
+	case models.OperatorGt:
+		return fmt.Sprintf("%s:>%s", name, value)
+	case models.OperatorGte:
+		return fmt.Sprintf("%s:>=%s", name, value)
+	case models.OperatorLt:
+		return fmt.Sprintf("%s:<%s", name, value)
+	case models.OperatorLte:
+		return fmt.Sprintf("%s:<=%s", name, value)
+	case models.OperatorRange:
+		return fmt.Sprintf("%s:[%s..%s]", name, typeSenseValue(field, condition.ConditionValues[0]), typeSenseValue(field, condition.ConditionValues[1]))
+	case models.OperatorIn:
+		return fmt.Sprintf("%s:=[%s]", name, typeSenseValues(field, condition.ConditionValues))
+	case models.OperatorNotIn:
+		return fmt.Sprintf("%s:!=[%s]", name, typeSenseValues(field, condition.ConditionValues))
+	case models.OperatorExists:
+		return fmt.Sprintf("%s:>=%s", name, typeSenseMinValue(field))
+	}
+	return fmt.Sprintf("%s:=%s", name, value)
+}
+
+func (TypeSenseDialect) And(parts []string) string {
+	return typeSenseGroup(parts, " && ")
+}
+
+func (TypeSenseDialect) Or(parts []string) string {
+	return typeSenseGroup(parts, " || ")
+}
+
+func typeSenseGroup(parts []string, separator string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, separator) + ")"
+}
+
+func typeSenseValues(field *models.CollectionField, values []string) string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, typeSenseValue(field, value))
+	}
+	return strings.Join(result, ",")
+}
+
+// typeSenseValue re-formats numbers and bools so nothing but the parsed value reaches filter_by,
+// strings are wrapped in backticks which keeps commas, spaces and operators literal
+func typeSenseValue(field *models.CollectionField, value string) string {
+	switch {
+	case field.IsInteger():
+		number, _ := strconv.ParseInt(value, 10, 64)
+		return strconv.FormatInt(number, 10)
+	case field.IsNumeric():
+		number, _ := strconv.ParseFloat(value, 64)
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	case field.IsBool():
+		flag, _ := strconv.ParseBool(value)
+		return strconv.FormatBool(flag)
+	}
+	return "`" + strings.ReplaceAll(value, "`", "\\`") + "`"
+}
+
+// typeSenseMinValue is the lowest value a numeric field can hold, every document having the field matches >= it
+func typeSenseMinValue(field *models.CollectionField) string {
+	switch strings.TrimSuffix(field.Type, "[]") {
+	case models.FieldTypeInt32:
+		return "-2147483648"
+	case models.FieldTypeInt64:
+		return "-9223372036854775808"
+	}
+	return "-3.4028235e+38"
+}
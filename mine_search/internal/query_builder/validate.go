@@ -0,0 +1,153 @@
+package query_builder
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// MaxConditionValues limits the list size of in, not_in conditions
+const MaxConditionValues = 100
+
+// ErrInvalidQuery marks errors caused by the request rather than the engine
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// FieldErrors lists every invalid field of a search request, it is returned in Resp.Detail
+type FieldErrors []*utils.ErrorResponse
+
+func (e FieldErrors) Error() string {
+	return utils.ShowErrors(e).ErrorDescription
+}
+
+func (e FieldErrors) Is(target error) bool {
+	return target == ErrInvalidQuery
+}
+
+func newFieldError(field, tag, value string) *utils.ErrorResponse {
+	return &utils.ErrorResponse{Field: field, Tag: tag, Value: value}
+}
+
+// ValidateSearch checks conditions, filter tree, facets, paging, geo area and order bys of a request against the collection schema
+func ValidateSearch(schema *models.CollectionSchema, dataInput models.Search) error {
+	errs := FieldErrors{}
+	for idx, condition := range dataInput.Conditions {
+		errs = append(errs, ValidateCondition(schema, condition, fmt.Sprintf("conditions[%d]", idx))...)
+	}
+	errs = append(errs, ValidateFilter(schema, dataInput.Filter)...)
+	errs = append(errs, ValidateFacets(schema, dataInput)...)
+	errs = append(errs, ValidatePaging(dataInput)...)
+	errs = append(errs, ValidateGeo(schema, dataInput)...)
+	for idx, orderBy := range dataInput.OrderBys {
+		path := fmt.Sprintf("order_bys[%d]", idx)
+		field, ok := schema.GetField(orderBy.OrderByName)
+		if !ok || !field.IsSortable() || field.IsGeo() {
+			errs = append(errs, newFieldError(path+".name", "not_sortable", orderBy.OrderByName))
+			continue
+		}
+		switch strings.ToLower(orderBy.OrderByValue) {
+		case "", "asc", "desc":
+		default:
+			errs = append(errs, newFieldError(path+".value", "invalid_direction", orderBy.OrderByValue))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func ValidateCondition(schema *models.CollectionSchema, condition models.ConditionSearching, path string) FieldErrors {
+	field, ok := schema.GetField(condition.ConditionName)
+	if !ok {
+		return FieldErrors{newFieldError(path+".name", "unknown_field", condition.ConditionName)}
+	}
+	operator := condition.Operator()
+	if field.IsGeo() {
+		return FieldErrors{newFieldError(path+".name", "geopoint_needs_geo", condition.ConditionName)}
+	}
+	if !OperatorAllowed(field, operator) {
+		return FieldErrors{newFieldError(path+".operator", "operator_not_supported", operator+" on "+field.Type)}
+	}
+	errs := FieldErrors{}
+	switch operator {
+	case models.OperatorRange:
+		if len(condition.ConditionValues) != 2 {
+			return FieldErrors{newFieldError(path+".values", "range_needs_two_values", strings.Join(condition.ConditionValues, ","))}
+		}
+		for idx, value := range condition.ConditionValues {
+			if !validValue(field, value) {
+				errs = append(errs, newFieldError(fmt.Sprintf("%s.values[%d]", path, idx), "invalid_value", value))
+			}
+		}
+		if len(errs) == 0 {
+			low, _ := strconv.ParseFloat(condition.ConditionValues[0], 64)
+			high, _ := strconv.ParseFloat(condition.ConditionValues[1], 64)
+			if low > high {
+				errs = append(errs, newFieldError(path+".values", "range_min_greater_than_max", strings.Join(condition.ConditionValues, ",")))
+			}
+		}
+	case models.OperatorIn, models.OperatorNotIn:
+		if len(condition.ConditionValues) == 0 {
+			return FieldErrors{newFieldError(path+".values", "required", "")}
+		}
+		if len(condition.ConditionValues) > MaxConditionValues {
+			return FieldErrors{newFieldError(path+".values", "max", strconv.Itoa(MaxConditionValues))}
+		}
+		for idx, value := range condition.ConditionValues {
+			if !validValue(field, value) {
+				errs = append(errs, newFieldError(fmt.Sprintf("%s.values[%d]", path, idx), "invalid_value", value))
+			}
+		}
+	case models.OperatorExists:
+		if condition.ConditionValue != "" && condition.ConditionValue != "true" {
+			errs = append(errs, newFieldError(path+".value", "invalid_value", condition.ConditionValue))
+		}
+	default:
+		if !validValue(field, condition.ConditionValue) {
+			errs = append(errs, newFieldError(path+".value", "invalid_value", condition.ConditionValue))
+		}
+	}
+	return errs
+}
+
+// OperatorAllowed reports whether operator can be applied on the type of field.
+// exists is limited to numeric fields since Typesense cannot filter on a missing string
+func OperatorAllowed(field *models.CollectionField, operator string) bool {
+	switch {
+	case field.IsNumeric():
+		switch operator {
+		case models.OperatorEq, models.OperatorNe, models.OperatorGt, models.OperatorGte, models.OperatorLt,
+			models.OperatorLte, models.OperatorRange, models.OperatorIn, models.OperatorNotIn, models.OperatorExists:
+			return true
+		}
+	case field.IsBool():
+		switch operator {
+		case models.OperatorEq, models.OperatorNe:
+			return true
+		}
+	default:
+		switch operator {
+		case models.OperatorEq, models.OperatorNe, models.OperatorIn, models.OperatorNotIn:
+			return true
+		}
+	}
+	return false
+}
+
+func validValue(field *models.CollectionField, value string) bool {
+	switch {
+	case field.IsInteger():
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case field.IsNumeric():
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case field.IsBool():
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	}
+	return !utils.IsEmpty(value)
+}
@@ -0,0 +1,74 @@
+package query_builder
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"strconv"
+	"strings"
+)
+
+// VectorField returns the embedding field searched by a vector request, the first one of the schema when name is empty
+func VectorField(schema *models.CollectionSchema, name string) (*models.CollectionField, error) {
+	for idx, field := range schema.Fields {
+		if field.NumDim > 0 && (name == "" || field.Name == name) {
+			return &schema.Fields[idx], nil
+		}
+	}
+	if name == "" {
+		return nil, FieldErrors{newFieldError("field", "no_embedding_field", schema.Name)}
+	}
+	return nil, FieldErrors{newFieldError("field", "not_embedding_field", name)}
+}
+
+// ValidateVector checks that vector has the num_dim values of field
+func ValidateVector(field *models.CollectionField, vector []float32) error {
+	if len(vector) != field.NumDim {
+		return FieldErrors{newFieldError("vector", "num_dim", strconv.Itoa(field.NumDim))}
+	}
+	return nil
+}
+
+// TypeSenseVectorParams compiles a vector request into the parameters of a multi_search search, vector is the
+// one of the request or the embedding of its text. The vector field is left out of the documents returned
+func TypeSenseVectorParams(schema *models.CollectionSchema, dataInput models.VectorSearch, field *models.CollectionField, vector []float32) (map[string]string, error) {
+	search := dataInput.ToSearch()
+	errs := FieldErrors{}
+	if err := ValidateSearch(schema, search); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	if err := ValidateVector(field, vector); err != nil {
+		errs = append(errs, err.(FieldErrors)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	perPage := PerPage(search)
+	k := dataInput.K
+	if k == 0 {
+		k = CurrentPage(search) * perPage
+	}
+	params := map[string]string{
+		"q":              "*",
+		"vector_query":   TypeSenseVectorQuery(field.Name, vector, k, dataInput.DistanceThreshold),
+		"page":           strconv.Itoa(CurrentPage(search)),
+		"per_page":       strconv.Itoa(perPage),
+		"exclude_fields": field.Name,
+	}
+	if filterBy := TypeSenseFilterBy(schema, search); filterBy != "" {
+		params["filter_by"] = filterBy
+	}
+	return params, nil
+}
+
+// TypeSenseVectorQuery renders field:([v1,v2,...], k:K) with the distance threshold when it is set
+func TypeSenseVectorQuery(field string, vector []float32, k int, distanceThreshold float64) string {
+	values := make([]string, 0, len(vector))
+	for _, value := range vector {
+		values = append(values, strconv.FormatFloat(float64(value), 'f', -1, 32))
+	}
+	options := fmt.Sprintf("k:%d", k)
+	if distanceThreshold > 0 {
+		options += ", distance_threshold:" + strconv.FormatFloat(distanceThreshold, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%s:([%s], %s)", field, strings.Join(values, ","), options)
+}
@@ -0,0 +1,115 @@
+// Package rag turns the hits of a retrieval into the documents of a prompt and reads the citations back
+// from the answer
+package rag
+
+import (
+	"fmt"
+	"mine/internal/llm"
+	"mine/internal/models"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultContextTokens bounds the documents of a prompt when RAG_CONTEXT_TOKENS is not set
+const DefaultContextTokens = 2000
+
+// charsPerToken is the usual ratio of BPE tokenizers on latin text, the budget is an estimate
+const charsPerToken = 4
+
+// citationRegex matches [42] and [42, 43]
+var citationRegex = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// EstimateTokens counts about one token every 4 characters
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Documents renders the fields of the hits as "field: value" lines followed by their matching chunks as
+// "excerpt: text" lines, best hit first, until the budget is spent.
+// The first hit is cut to the budget when it alone is over it, a later hit over what is left ends the list.
+// It returns the documents with the tokens they take
+func Documents(hits []models.SearchHit, fields []string, budget int) ([]llm.Document, int) {
+	documents := []llm.Document{}
+	tokens := 0
+	for idx, hit := range hits {
+		document := llm.Document{ID: DocumentID(hit, idx)}
+		lines := []string{}
+		for _, field := range fields {
+			value := fieldText(hit.Document[field])
+			if value == "" {
+				continue
+			}
+			if document.Title == "" {
+				document.Title = value
+			}
+			lines = append(lines, field+": "+value)
+		}
+		for _, chunk := range hit.Chunks {
+			lines = append(lines, "excerpt: "+chunk.Text)
+		}
+		if document.Title == "" {
+			document.Title = document.ID
+		}
+		document.Text = strings.Join(lines, "\n")
+		size := EstimateTokens(document.Text)
+		if tokens+size > budget {
+			if len(documents) > 0 {
+				break
+			}
+			document.Text = string([]rune(document.Text)[:budget*charsPerToken])
+			size = budget
+		}
+		documents = append(documents, document)
+		tokens += size
+	}
+	return documents, tokens
+}
+
+// DocumentID is the id of the document of hit, its rank when it has none
+func DocumentID(hit models.SearchHit, idx int) string {
+	if id, ok := hit.Document["id"]; ok && id != nil {
+		return fmt.Sprint(id)
+	}
+	return fmt.Sprint(idx + 1)
+}
+
+// Citations returns the ids of documents cited in answer, in the order they are first cited.
+// Brackets holding anything else are ignored
+func Citations(answer string, documents []llm.Document) []string {
+	known := map[string]bool{}
+	for _, document := range documents {
+		known[document.ID] = true
+	}
+	cited := map[string]bool{}
+	ids := []string{}
+	for _, match := range citationRegex.FindAllStringSubmatch(answer, -1) {
+		for _, id := range strings.Split(match[1], ",") {
+			id = strings.TrimSpace(id)
+			if known[id] && !cited[id] {
+				cited[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// fieldText is the text of a document value, lists are joined with commas
+func fieldText(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(typed)
+	case []interface{}:
+		parts := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if text := fieldText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(value)
+}
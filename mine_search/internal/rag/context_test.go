@@ -0,0 +1,25 @@
+package rag
+
+import (
+	"mine/internal/llm"
+	"reflect"
+	"testing"
+)
+
+func TestCitations(t *testing.T) {
+	documents := []llm.Document{{ID: "42"}, {ID: "43"}, {ID: "7"}}
+	tests := []struct {
+		answer string
+		want   []string
+	}{
+		{"Rowling wrote it [42].", []string{"42"}},
+		{"Both [43, 42] and again [42][7].", []string{"43", "42", "7"}},
+		{"Unknown [99] and not a citation [see above].", []string{}},
+		{"No citation at all.", []string{}},
+	}
+	for _, test := range tests {
+		if got := Citations(test.answer, documents); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Citations(%q) = %v, want %v", test.answer, got, test.want)
+		}
+	}
+}
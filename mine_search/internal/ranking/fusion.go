@@ -0,0 +1,118 @@
+// Package ranking merges the hits of several searches of the same collection into one ranking
+package ranking
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"sort"
+)
+
+// DefaultRankConstant is the k of reciprocal rank fusion, it flattens the gap between the first ranks
+const DefaultRankConstant = 60
+
+// Options picks the fusion, Alpha is the weight of the vector hits and 1-Alpha the one of the keyword hits
+type Options struct {
+	Fusion       string
+	Alpha        float64
+	RankConstant int
+}
+
+type fused struct {
+	hit   models.SearchHit
+	score models.HybridScore
+	order int
+}
+
+// Fuse merges the keyword and vector hits, both sorted best first, into one list sorted by fused score.
+// A document found by both searches appears once, with the highlights of the keyword hit and the distance
+// of the vector hit
+func Fuse(keyword, vector []models.SearchHit, options Options) []models.SearchHit {
+	if options.RankConstant == 0 {
+		options.RankConstant = DefaultRankConstant
+	}
+	var keywordScores, vectorScores []float64
+	if options.Fusion == models.FusionWeighted {
+		keywordScores = normalize(keyword, func(hit models.SearchHit) float64 { return hit.TextMatch })
+		vectorScores = normalize(vector, func(hit models.SearchHit) float64 {
+			if hit.VectorDistance == nil {
+				return 0
+			}
+			return -*hit.VectorDistance
+		})
+	}
+	documents := map[string]*fused{}
+	merged := []*fused{}
+	add := func(hits []models.SearchHit, scores []float64, weight float64, vectorSide bool) {
+		for idx, hit := range hits {
+			key := documentKey(hit, vectorSide, idx)
+			entry, ok := documents[key]
+			if !ok {
+				entry = &fused{hit: hit, order: len(merged)}
+				documents[key] = entry
+				merged = append(merged, entry)
+			}
+			rank := idx + 1
+			if vectorSide {
+				entry.score.VectorRank = &rank
+				entry.hit.VectorDistance = hit.VectorDistance
+			} else {
+				entry.score.KeywordRank = &rank
+			}
+			if scores != nil {
+				entry.score.Score += weight * scores[idx]
+				continue
+			}
+			// both weights are doubled so a document first in both searches scores 2/(k+1) whatever alpha is
+			entry.score.Score += 2 * weight / float64(options.RankConstant+rank)
+		}
+	}
+	add(keyword, keywordScores, 1-options.Alpha, false)
+	add(vector, vectorScores, options.Alpha, true)
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].score.Score != merged[j].score.Score {
+			return merged[i].score.Score > merged[j].score.Score
+		}
+		return merged[i].order < merged[j].order
+	})
+	hits := make([]models.SearchHit, 0, len(merged))
+	for _, entry := range merged {
+		score := entry.score
+		entry.hit.Hybrid = &score
+		hits = append(hits, entry.hit)
+	}
+	return hits
+}
+
+// normalize maps the scores of hits to [0, 1] with min-max, every hit scores 1 when they are all equal
+func normalize(hits []models.SearchHit, score func(models.SearchHit) float64) []float64 {
+	scores := make([]float64, len(hits))
+	if len(hits) == 0 {
+		return scores
+	}
+	low, high := score(hits[0]), score(hits[0])
+	for idx, hit := range hits {
+		scores[idx] = score(hit)
+		if scores[idx] < low {
+			low = scores[idx]
+		}
+		if scores[idx] > high {
+			high = scores[idx]
+		}
+	}
+	for idx := range scores {
+		if high == low {
+			scores[idx] = 1
+			continue
+		}
+		scores[idx] = (scores[idx] - low) / (high - low)
+	}
+	return scores
+}
+
+// documentKey is the id of the document, a document without id is never merged with another hit
+func documentKey(hit models.SearchHit, vectorSide bool, idx int) string {
+	if id, ok := hit.Document["id"]; ok && id != nil {
+		return "id:" + fmt.Sprint(id)
+	}
+	return fmt.Sprintf("hit:%t:%d", vectorSide, idx)
+}
@@ -0,0 +1,68 @@
+package ranking
+
+import (
+	"mine/internal/models"
+	"testing"
+)
+
+func hit(id string, textMatch float64, distance float64) models.SearchHit {
+	return models.SearchHit{Document: map[string]interface{}{"id": id}, TextMatch: textMatch, VectorDistance: &distance}
+}
+
+func ids(hits []models.SearchHit) []string {
+	result := []string{}
+	for _, hit := range hits {
+		result = append(result, hit.Document["id"].(string))
+	}
+	return result
+}
+
+func TestFuse(t *testing.T) {
+	keyword := []models.SearchHit{hit("a", 30, 0), hit("b", 20, 0), hit("c", 10, 0)}
+	vector := []models.SearchHit{hit("c", 0, 0.1), hit("d", 0, 0.2), hit("a", 0, 0.9)}
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{"rrf balanced", Options{Fusion: models.FusionRRF, Alpha: 0.5}, []string{"a", "c", "b", "d"}},
+		{"rrf keyword only", Options{Fusion: models.FusionRRF, Alpha: 0}, []string{"a", "b", "c", "d"}},
+		{"rrf vector only", Options{Fusion: models.FusionRRF, Alpha: 1}, []string{"c", "d", "a", "b"}},
+		// min-max: c scores 0 + 1, a scores 1 + 0, d 0.875·alpha
+		{"weighted leaning vector", Options{Fusion: models.FusionWeighted, Alpha: 0.7}, []string{"c", "d", "a", "b"}},
+	}
+	for _, test := range tests {
+		fused := Fuse(keyword, vector, test.options)
+		got := ids(fused)
+		if len(got) != len(test.want) {
+			t.Fatalf("%s: Fuse = %v, want %v", test.name, got, test.want)
+		}
+		for idx := range got {
+			if got[idx] != test.want[idx] {
+				t.Errorf("%s: Fuse = %v, want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFuseMergesBothSides(t *testing.T) {
+	keyword := []models.SearchHit{hit("a", 30, 0)}
+	keyword[0].VectorDistance = nil
+	keyword[0].Highlights = map[string][]string{"title": {"<mark>a</mark>"}}
+	vector := []models.SearchHit{hit("a", 0, 0.25), {Document: map[string]interface{}{"title": "no id"}}}
+	fused := Fuse(keyword, vector, Options{Fusion: models.FusionRRF, Alpha: 0.5})
+	if len(fused) != 2 {
+		t.Fatalf("Fuse returned %d hits, want 2", len(fused))
+	}
+	first := fused[0]
+	if first.Hybrid == nil || first.Hybrid.KeywordRank == nil || first.Hybrid.VectorRank == nil {
+		t.Fatalf("the hit found twice lacks a rank: %+v", first.Hybrid)
+	}
+	if first.VectorDistance == nil || *first.VectorDistance != 0.25 || len(first.Highlights["title"]) != 1 {
+		t.Errorf("the merged hit does not keep the highlights and the distance: %+v", first)
+	}
+	if want := 2 / float64(DefaultRankConstant+1); first.Hybrid.Score != want {
+		t.Errorf("first in both searches scores %v, want %v", first.Hybrid.Score, want)
+	}
+}
@@ -0,0 +1,47 @@
+package ranking
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"strconv"
+)
+
+// Parent is a parent document found through its chunks, Best is its best chunk hit
+type Parent struct {
+	ID     string
+	Best   models.SearchHit
+	Chunks []models.ChunkMatch
+}
+
+// RollUp groups chunk hits, sorted best first, by their parent. Parents are ranked by their best chunk
+// and keep at most maxChunks of them. Chunks without parent are dropped
+func RollUp(hits []models.SearchHit, maxChunks int) []Parent {
+	parents := []Parent{}
+	positions := map[string]int{}
+	for idx, hit := range hits {
+		parentID, ok := hit.Document[models.ChunkParentField]
+		if !ok || parentID == nil {
+			continue
+		}
+		id := fmt.Sprint(parentID)
+		position, ok := positions[id]
+		if !ok {
+			position = len(parents)
+			positions[id] = position
+			parents = append(parents, Parent{ID: id, Best: hit})
+		}
+		if len(parents[position].Chunks) >= maxChunks {
+			continue
+		}
+		chunk := models.ChunkMatch{
+			ID:         fmt.Sprint(hit.Document["id"]),
+			Text:       fmt.Sprint(hit.Document[models.ChunkTextField]),
+			Rank:       idx + 1,
+			Highlights: hit.Highlights,
+		}
+		// chunk_index is a float64 or a json.Number depending on the decoder of the engine
+		chunk.Index, _ = strconv.Atoi(fmt.Sprint(hit.Document[models.ChunkIndexField]))
+		parents[position].Chunks = append(parents[position].Chunks, chunk)
+	}
+	return parents
+}
@@ -0,0 +1,102 @@
+package document_tb
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var tableNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+func (repo *documentRepo) StreamRows(table string, fn func(row map[string]interface{}) error) error {
+	if repo.db == nil {
+		return errors.New("Can't connect database")
+	}
+	if !tableNameRegex.MatchString(table) {
+		return errors.New("invalid table name")
+	}
+	rows, err := repo.db.Table(table).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		row := map[string]interface{}{}
+		if err := repo.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (repo *documentRepo) Search(query *models.MySQLQuery) ([]map[string]interface{}, int64, error) {
+	if repo.db == nil {
+		return nil, 0, errors.New("Can't connect database")
+	}
+	if !tableNameRegex.MatchString(query.Table) {
+		return nil, 0, errors.New("invalid table name")
+	}
+	tx := repo.db.Table(query.Table)
+	if query.Where != "" {
+		tx = tx.Where(query.Where, query.Args...)
+	}
+	// a new session lets the count and the page share the conditions
+	tx = tx.Session(&gorm.Session{})
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	page := tx
+	if query.Score != "" {
+		page = page.Select("*, "+query.Score+" AS `"+models.MySQLScoreColumn+"`", query.ScoreArgs...)
+	}
+	rows := []map[string]interface{}{}
+	if err := page.Order(query.OrderBy).Offset(query.Offset).Limit(query.Limit).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+func (repo *documentRepo) CreateFullTextIndex(table, name string, columns []string) (bool, error) {
+	if repo.db == nil {
+		return false, errors.New("Can't connect database")
+	}
+	for _, identifier := range append([]string{table, name}, columns...) {
+		if !tableNameRegex.MatchString(identifier) {
+			return false, errors.New("invalid table, index or column name")
+		}
+	}
+	var count int64
+	err := repo.db.Table("information_schema.statistics").
+		Where("table_schema = DATABASE() AND table_name = ? AND index_name = ?", table, name).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	statement := fmt.Sprintf("ALTER TABLE `%s` ADD FULLTEXT INDEX `%s` (`%s`)", table, name, strings.Join(columns, "`, `"))
+	if err := repo.db.Exec(statement).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *documentRepo) Ping() error {
+	if repo.db == nil {
+		return errors.New("Can't connect database")
+	}
+	db, err := repo.db.DB()
+	if err != nil {
+		return err
+	}
+	return db.Ping()
+}
@@ -0,0 +1,32 @@
+package document_tb
+
+import (
+	"mine/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type DocumentRepo interface {
+	// StreamRows calls fn with every row of table, stopping at the first error
+	StreamRows(table string, fn func(row map[string]interface{}) error) error
+	// Search returns the page of rows selected by query and the number of matching rows
+	Search(query *models.MySQLQuery) ([]map[string]interface{}, int64, error)
+	// CreateFullTextIndex adds the FULLTEXT index name on columns of table, created is false when it exists
+	CreateFullTextIndex(table, name string, columns []string) (created bool, err error)
+	Ping() error
+}
+type documentRepo struct {
+	db  *gorm.DB
+	log *zap.Logger
+}
+
+func NewDocumentRepo(
+	db *gorm.DB,
+	log *zap.Logger,
+) DocumentRepo {
+	return &documentRepo{
+		db:  db,
+		log: log,
+	}
+}
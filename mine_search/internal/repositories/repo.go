@@ -2,6 +2,7 @@ package repositories
 
 import (
 	control_api_tb "mine/internal/repositories/control_api_tb"
+	document_tb "mine/internal/repositories/document_tb"
 	sample_tb "mine/internal/repositories/sample"
 
 	"go.uber.org/zap"
@@ -11,6 +12,7 @@ import (
 type Repositories struct {
 	Sample     sample_tb.SampleRepo
 	ControlApi control_api_tb.ControlApiRepo
+	Document   document_tb.DocumentRepo
 }
 
 func NewRepositories(
@@ -20,5 +22,6 @@ func NewRepositories(
 	return &Repositories{
 		Sample:     sample_tb.NewSampleRepo(db, log),
 		ControlApi: control_api_tb.NewControlApiRepo(db, log),
+		Document:   document_tb.NewDocumentRepo(db, log),
 	}
 }
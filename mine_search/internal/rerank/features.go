@@ -0,0 +1,135 @@
+package rerank
+
+import (
+	"math"
+	"mine/internal/embedding"
+	"mine/internal/engines/bm25"
+	"mine/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldMatch scores how well the query_by fields of a hit cover the query: the share of the query terms
+// found in each field, plus a bonus when the field holds the whole query as a phrase. The first query_by
+// field weighs the most, like it does for the keyword search
+type FieldMatch struct{}
+
+// phraseBonus is the part of the field score given to an exact phrase, the rest goes to the term coverage
+const phraseBonus = 0.3
+
+func (m *FieldMatch) Name() string {
+	return models.RerankerFieldMatch
+}
+
+func (m *FieldMatch) Score(query string, schema *models.CollectionSchema, hits []models.SearchHit) ([]float64, error) {
+	scores := make([]float64, len(hits))
+	tokens := bm25.Analyze(query)
+	terms := map[string]bool{}
+	for _, token := range tokens {
+		terms[token] = true
+	}
+	if len(terms) == 0 || len(schema.QueryBy) == 0 {
+		return scores, nil
+	}
+	phrase := " " + strings.Join(tokens, " ") + " "
+	for idx, hit := range hits {
+		total, weights := 0.0, 0.0
+		for position, name := range schema.QueryBy {
+			weight := float64(len(schema.QueryBy) - position)
+			weights += weight
+			text, ok := embedding.DocumentText(hit.Document, []string{name})
+			if !ok {
+				continue
+			}
+			fieldTokens := bm25.Analyze(text)
+			found := map[string]bool{}
+			for _, token := range fieldTokens {
+				if terms[token] {
+					found[token] = true
+				}
+			}
+			score := (1 - phraseBonus) * float64(len(found)) / float64(len(terms))
+			if strings.Contains(" "+strings.Join(fieldTokens, " ")+" ", phrase) {
+				score += phraseBonus
+			}
+			total += weight * score
+		}
+		scores[idx] = total / weights
+	}
+	return scores, nil
+}
+
+// Freshness scores a hit by the year in Field, halving every HalfLife years of age. A hit without
+// the year scores 0, one from the current year or later scores 1
+type Freshness struct {
+	Field    string
+	HalfLife float64
+	Now      func() time.Time
+}
+
+func (f *Freshness) Name() string {
+	return models.RerankerFreshness
+}
+
+func (f *Freshness) Score(query string, schema *models.CollectionSchema, hits []models.SearchHit) ([]float64, error) {
+	scores := make([]float64, len(hits))
+	year := float64(f.Now().Year())
+	for idx, hit := range hits {
+		published, ok := toFloat(hit.Document[f.Field])
+		if !ok {
+			continue
+		}
+		scores[idx] = math.Pow(0.5, math.Max(0, year-published)/f.HalfLife)
+	}
+	return scores, nil
+}
+
+// Popularity scores a hit by the count in Field on a log scale, relative to the most popular hit rescored
+type Popularity struct {
+	Field string
+}
+
+func (p *Popularity) Name() string {
+	return models.RerankerPopularity
+}
+
+func (p *Popularity) Score(query string, schema *models.CollectionSchema, hits []models.SearchHit) ([]float64, error) {
+	scores := make([]float64, len(hits))
+	highest := 0.0
+	for idx, hit := range hits {
+		count, ok := toFloat(hit.Document[p.Field])
+		if !ok || count <= 0 {
+			continue
+		}
+		scores[idx] = math.Log1p(count)
+		highest = math.Max(highest, scores[idx])
+	}
+	if highest == 0 {
+		return scores, nil
+	}
+	for idx := range scores {
+		scores[idx] /= highest
+	}
+	return scores, nil
+}
+
+// toFloat reads a number as decoded from json, typed by a schema or sent as a string
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case float32:
+		return float64(number), true
+	case int:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
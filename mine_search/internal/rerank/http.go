@@ -0,0 +1,101 @@
+package rerank
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mine/internal"
+	"mine/internal/embedding"
+	"mine/internal/models"
+	"mine/internal/utils"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// httpTimeout is in seconds, the cross encoder runs while the client waits for its search
+	httpTimeout = 10
+	// maxDocumentRunes bounds the text sent per hit, cross encoders read a few hundred tokens at most
+	maxDocumentRunes = 2000
+)
+
+// CrossEncoder calls a /rerank endpoint scoring every query and document pair with a cross-encoder model:
+// {"model", "query", "documents": [texts]} answered by {"results": [{"index", "relevance_score"}]}, the shape
+// of Cohere, Jina and most inference servers. A hit is sent as the text of its query_by fields
+type CrossEncoder struct {
+	url    string
+	apiKey string
+	model  string
+}
+
+func NewCrossEncoder(url, apiKey, model string) *CrossEncoder {
+	return &CrossEncoder{url: url, apiKey: apiKey, model: model}
+}
+
+func (c *CrossEncoder) Name() string {
+	return models.RerankerCrossEncoder
+}
+
+type crossEncoderResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Score returns the relevance scores of the model, they go through a sigmoid when the model answers logits
+func (c *CrossEncoder) Score(query string, schema *models.CollectionSchema, hits []models.SearchHit) ([]float64, error) {
+	if len(hits) == 0 {
+		return []float64{}, nil
+	}
+	documents := make([]string, len(hits))
+	for idx, hit := range hits {
+		text, _ := embedding.DocumentText(hit.Document, schema.QueryBy)
+		if runes := []rune(text); len(runes) > maxDocumentRunes {
+			text = string(runes[:maxDocumentRunes])
+		}
+		documents[idx] = text
+	}
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	body := map[string]interface{}{"query": query, "documents": documents, "top_n": len(documents)}
+	if c.model != "" {
+		body["model"] = c.model
+	}
+	resp, err := utils.RequestWithMethod(http.MethodPost, c.url, headers, nil, body, httpTimeout, false)
+	if err != nil {
+		internal.Log.Error("CrossEncoder.Score -> RequestWithMethod", zap.Any("url", c.url), zap.Any("documents", len(documents)), zap.Error(err))
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		internal.Log.Error("CrossEncoder.Score", zap.Any("url", c.url), zap.Any("status", resp.StatusCode()), zap.Any("response", resp.String()))
+		return nil, fmt.Errorf("rerank provider http status %d", resp.StatusCode())
+	}
+	content := crossEncoderResponse{}
+	if err := json.Unmarshal(resp.Body(), &content); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(hits))
+	answered := make([]bool, len(hits))
+	logits := false
+	for _, item := range content.Results {
+		if item.Index < 0 || item.Index >= len(hits) || answered[item.Index] {
+			return nil, fmt.Errorf("rerank provider answered index %d for %d documents", item.Index, len(hits))
+		}
+		scores[item.Index] = item.RelevanceScore
+		answered[item.Index] = true
+		logits = logits || item.RelevanceScore < 0 || item.RelevanceScore > 1
+	}
+	if len(content.Results) != len(hits) {
+		return nil, fmt.Errorf("rerank provider answered %d scores for %d documents", len(content.Results), len(hits))
+	}
+	if logits {
+		for idx, score := range scores {
+			scores[idx] = 1 / (1 + math.Exp(-score))
+		}
+	}
+	return scores, nil
+}
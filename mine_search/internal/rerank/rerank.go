@@ -0,0 +1,192 @@
+// Package rerank is the second stage of a search: a pipeline of rerankers rescoring the first hits retrieved
+package rerank
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/settings"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the RERANK_ settings
+const (
+	DefaultStages          = "field_match:1,freshness:0.2,popularity:0.2"
+	DefaultFreshnessField  = "publication_year"
+	DefaultPopularityField = "ratings_count"
+	// DefaultHalfLife is in years, a document that old weighs half a new one for freshness
+	DefaultHalfLife = 10
+)
+
+// Reranker scores hits for the query of a search, one score between 0 and 1 per hit, in their order
+type Reranker interface {
+	Name() string
+	Score(query string, schema *models.CollectionSchema, hits []models.SearchHit) ([]float64, error)
+}
+
+// Stage is a pass of the pipeline, Weight scales the scores of Reranker and TopN bounds the hits it rescores
+type Stage struct {
+	Reranker Reranker
+	Weight   float64
+	TopN     int
+}
+
+// Rerankers holds the rerankers by name and the stages of RERANK_STAGES
+type Rerankers struct {
+	rerankers map[string]Reranker
+	stages    []models.RerankStage
+}
+
+// New builds the rerankers from the settings, the cross encoder is only there when RERANK_URL is set
+func New(appSettings *settings.AppSettings) (*Rerankers, error) {
+	cfgs := appSettings.Cfgs
+	freshness := &Freshness{Field: cfgs.RerankFreshnessField, HalfLife: cfgs.RerankHalfLife, Now: time.Now}
+	if freshness.Field == "" {
+		freshness.Field = DefaultFreshnessField
+	}
+	if freshness.HalfLife <= 0 {
+		freshness.HalfLife = DefaultHalfLife
+	}
+	popularity := &Popularity{Field: cfgs.RerankPopularityField}
+	if popularity.Field == "" {
+		popularity.Field = DefaultPopularityField
+	}
+	r := &Rerankers{rerankers: map[string]Reranker{}}
+	for _, reranker := range []Reranker{&FieldMatch{}, freshness, popularity} {
+		r.rerankers[reranker.Name()] = reranker
+	}
+	if cfgs.RerankURL != "" {
+		r.rerankers[models.RerankerCrossEncoder] = NewCrossEncoder(cfgs.RerankURL, cfgs.RerankAPIKey, cfgs.RerankModel)
+	}
+	value := cfgs.RerankStages
+	if value == "" {
+		value = DefaultStages
+	}
+	stages, err := ParseStages(value)
+	if err != nil {
+		return nil, fmt.Errorf("RERANK_STAGES: %w", err)
+	}
+	if _, err := r.Stages(stages); err != nil {
+		return nil, fmt.Errorf("RERANK_STAGES: %w", err)
+	}
+	r.stages = stages
+	return r, nil
+}
+
+// Stages resolves the stages of a request, the RERANK_STAGES ones when it has none. A weight left out is 1
+func (r *Rerankers) Stages(requested []models.RerankStage) ([]Stage, error) {
+	if len(requested) == 0 {
+		requested = r.stages
+	}
+	stages := make([]Stage, 0, len(requested))
+	for _, item := range requested {
+		reranker, ok := r.rerankers[item.Name]
+		if !ok {
+			if item.Name == models.RerankerCrossEncoder {
+				return nil, fmt.Errorf("the cross_encoder reranker needs RERANK_URL")
+			}
+			return nil, fmt.Errorf("unknown reranker %q", item.Name)
+		}
+		stage := Stage{Reranker: reranker, Weight: 1, TopN: item.TopN}
+		if item.Weight != nil {
+			stage.Weight = *item.Weight
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// ParseStages reads a comma separated list of name[:weight[:top_n]], e.g. field_match:1,cross_encoder:2:20
+func ParseStages(value string) ([]models.RerankStage, error) {
+	stages := []models.RerankStage{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("stage %q is not name[:weight[:top_n]]", item)
+		}
+		stage := models.RerankStage{Name: strings.TrimSpace(parts[0])}
+		if len(parts) > 1 {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("stage %q has an invalid weight", item)
+			}
+			stage.Weight = &weight
+		}
+		if len(parts) > 2 {
+			topN, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+			if err != nil || topN < 0 {
+				return nil, fmt.Errorf("stage %q has an invalid top_n", item)
+			}
+			stage.TopN = topN
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// scored is a hit going through the pipeline with its running score
+type scored struct {
+	hit   models.SearchHit
+	score models.RerankScore
+}
+
+// Run passes hits, best first, through the stages in order. The score of a hit starts at its retrieval score,
+// 1 for the first hit down to 1/n for the last, and every stage adds its weighted score then sorts the hits it
+// rescored, the TopN first of the previous order; the others keep their place behind them. A stage that fails
+// is skipped, its pass carries the error. The breakdown of every hit is set when debug is
+func Run(query string, schema *models.CollectionSchema, hits []models.SearchHit, stages []Stage, debug bool) ([]models.SearchHit, []models.RerankPass) {
+	items := make([]scored, len(hits))
+	for idx, hit := range hits {
+		retrieval := 1 - float64(idx)/float64(len(hits))
+		items[idx] = scored{hit: hit, score: models.RerankScore{
+			Score:     retrieval,
+			Retrieval: retrieval,
+			FirstRank: idx + 1,
+			Stages:    map[string]float64{},
+		}}
+	}
+	passes := make([]models.RerankPass, 0, len(stages))
+	for _, stage := range stages {
+		window := items
+		if stage.TopN > 0 && stage.TopN < len(window) {
+			window = items[:stage.TopN]
+		}
+		pass := models.RerankPass{Name: stage.Reranker.Name(), Weight: stage.Weight, Hits: len(window)}
+		batch := make([]models.SearchHit, len(window))
+		for idx := range window {
+			batch[idx] = window[idx].hit
+		}
+		start := time.Now()
+		scores, err := stage.Reranker.Score(query, schema, batch)
+		pass.TimeMs = float64(time.Since(start).Microseconds()) / 1000
+		if err == nil && len(scores) != len(batch) {
+			err = fmt.Errorf("%d scores for %d hits", len(scores), len(batch))
+		}
+		if err != nil {
+			pass.Error = err.Error()
+			passes = append(passes, pass)
+			continue
+		}
+		for idx := range window {
+			window[idx].score.Stages[pass.Name] = scores[idx]
+			window[idx].score.Score += stage.Weight * scores[idx]
+		}
+		sort.SliceStable(window, func(i, j int) bool { return window[i].score.Score > window[j].score.Score })
+		passes = append(passes, pass)
+	}
+	reranked := make([]models.SearchHit, len(items))
+	for idx := range items {
+		reranked[idx] = items[idx].hit
+		if debug {
+			score := items[idx].score
+			reranked[idx].Rerank = &score
+		}
+	}
+	return reranked, passes
+}
@@ -0,0 +1,18 @@
+package schemas
+
+import "mine/internal/models"
+
+// Books matches the documents in typesense/books.jsonl
+var Books = &models.CollectionSchema{
+	Name: "books",
+	Fields: []models.CollectionField{
+		{Name: "title", Type: models.FieldTypeString},
+		{Name: "authors", Type: models.FieldTypeStringArray, Facet: true},
+		{Name: "publication_year", Type: models.FieldTypeInt32, Facet: true},
+		{Name: "average_rating", Type: models.FieldTypeFloat, Facet: true},
+		{Name: "image_url", Type: models.FieldTypeString, Optional: true},
+		{Name: "ratings_count", Type: models.FieldTypeInt32},
+	},
+	DefaultSortingField: "ratings_count",
+	QueryBy:             []string{"title", "authors"},
+}
@@ -0,0 +1,101 @@
+package schemas
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"strings"
+)
+
+// MaxChunkSize is in tokens, a chunk longer than it holds too many topics to be retrieved for one
+const MaxChunkSize = 2000
+
+// ChunkCollection is the name of the child collection holding the chunks of schema
+func ChunkCollection(schema *models.CollectionSchema) string {
+	if schema.Chunking.Collection != "" {
+		return schema.Chunking.Collection
+	}
+	return schema.Name + "_chunks"
+}
+
+// VersionChunkCollection is the child collection of collection, schema itself or one of its versions. A version keeps
+// its chunks apart, books_v3 in books_v3_chunks, and the chunk collection is an alias of them once books_v3 is live
+func VersionChunkCollection(schema *models.CollectionSchema, collection string) string {
+	if collection == schema.Name {
+		return ChunkCollection(schema)
+	}
+	return collection + "_chunks"
+}
+
+// ChunkSchema is the child collection of a chunked schema: parent_id, chunk_index, text, the copied fields
+// and, when the parent has one, an embedding field of the same num_dim filled from text
+func ChunkSchema(schema *models.CollectionSchema) *models.CollectionSchema {
+	child := &models.CollectionSchema{
+		Name: ChunkCollection(schema),
+		Fields: []models.CollectionField{
+			{Name: models.ChunkParentField, Type: models.FieldTypeString, Facet: true},
+			{Name: models.ChunkIndexField, Type: models.FieldTypeInt32},
+			{Name: models.ChunkTextField, Type: models.FieldTypeString},
+		},
+		QueryBy: []string{models.ChunkTextField},
+	}
+	for _, name := range schema.Chunking.Copy {
+		if field, ok := schema.GetField(name); ok {
+			copied := *field
+			// a parent without the field still gets chunks
+			copied.Optional = true
+			child.Fields = append(child.Fields, copied)
+		}
+	}
+	if embeddings := schema.EmbeddingFields(); len(embeddings) > 0 {
+		child.Fields = append(child.Fields, models.CollectionField{
+			Name:      embeddings[0].Name,
+			Type:      models.FieldTypeFloatArray,
+			NumDim:    embeddings[0].NumDim,
+			EmbedFrom: []string{models.ChunkTextField},
+		})
+	}
+	return child
+}
+
+// checkChunking checks the chunking of schema, its fields are string fields and the window moves forward
+func checkChunking(schema *models.CollectionSchema) error {
+	chunking := schema.Chunking
+	if len(chunking.Fields) == 0 {
+		return fmt.Errorf("collection %s: chunking needs fields", schema.Name)
+	}
+	for _, name := range chunking.Fields {
+		field, ok := schema.GetField(name)
+		if !ok || !strings.HasPrefix(field.Type, models.FieldTypeString) {
+			return fmt.Errorf("collection %s: chunking field %s must be a string field", schema.Name, name)
+		}
+	}
+	switch chunking.Strategy {
+	case "", models.ChunkStrategyTokens, models.ChunkStrategySentences:
+	default:
+		return fmt.Errorf("collection %s: unknown chunking strategy %q", schema.Name, chunking.Strategy)
+	}
+	size := chunking.Size
+	if size == 0 {
+		size = models.DefaultChunkSize
+	}
+	if size < 0 || size > MaxChunkSize {
+		return fmt.Errorf("collection %s: chunking size must be between 1 and %d", schema.Name, MaxChunkSize)
+	}
+	if chunking.Overlap < 0 || chunking.Overlap >= size {
+		return fmt.Errorf("collection %s: chunking overlap must be less than the size %d", schema.Name, size)
+	}
+	if ChunkCollection(schema) == schema.Name {
+		return fmt.Errorf("collection %s: chunks need their own collection", schema.Name)
+	}
+	for _, name := range chunking.Copy {
+		field, ok := schema.GetField(name)
+		if !ok || field.IsEmbedding() {
+			return fmt.Errorf("collection %s: chunking copies unknown or embedding field %s", schema.Name, name)
+		}
+		switch name {
+		case "id", models.ChunkParentField, models.ChunkIndexField, models.ChunkTextField:
+			return fmt.Errorf("collection %s: chunking cannot copy %s, chunks have their own", schema.Name, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package schemas
+
+import (
+	"fmt"
+	"mine/internal/models"
+	"strconv"
+	"strings"
+)
+
+// DocumentFromRow converts a database row into a document of schema, values are coerced to the
+// field types and columns outside the schema are dropped. string[] fields accept comma separated text,
+// geopoint fields accept "lat,lng" text
+func DocumentFromRow(schema *models.CollectionSchema, row map[string]interface{}) map[string]interface{} {
+	document := map[string]interface{}{}
+	if id, ok := row["id"]; ok && id != nil {
+		document["id"] = toString(id)
+	}
+	for _, field := range schema.Fields {
+		value, ok := row[field.Name]
+		if !ok || value == nil {
+			continue
+		}
+		if converted, ok := coerce(field, value); ok {
+			document[field.Name] = converted
+		}
+	}
+	return document
+}
+
+func toString(value interface{}) string {
+	if content, ok := value.([]byte); ok {
+		return string(content)
+	}
+	return fmt.Sprint(value)
+}
+
+func coerce(field models.CollectionField, value interface{}) (interface{}, bool) {
+	text := toString(value)
+	switch field.Type {
+	case models.FieldTypeString:
+		return text, true
+	case models.FieldTypeStringArray:
+		switch list := value.(type) {
+		case []string:
+			return list, true
+		case []interface{}:
+			parts := make([]string, 0, len(list))
+			for _, item := range list {
+				parts = append(parts, toString(item))
+			}
+			return parts, true
+		}
+		parts := []string{}
+		for _, part := range strings.Split(text, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return parts, true
+	case models.FieldTypeInt32, models.FieldTypeInt64:
+		number, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			floatNumber, errFloat := strconv.ParseFloat(text, 64)
+			if errFloat != nil {
+				return nil, false
+			}
+			number = int64(floatNumber)
+		}
+		return number, true
+	case models.FieldTypeFloat:
+		number, err := strconv.ParseFloat(text, 64)
+		return number, err == nil
+	case models.FieldTypeGeopoint:
+		switch point := value.(type) {
+		case []float64:
+			return point, len(point) == 2
+		case []interface{}:
+			if len(point) != 2 {
+				return nil, false
+			}
+			text = toString(point[0]) + "," + toString(point[1])
+		}
+		parts := strings.Split(text, ",")
+		if len(parts) != 2 {
+			return nil, false
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat != nil || errLng != nil {
+			return nil, false
+		}
+		return []float64{lat, lng}, true
+	case models.FieldTypeBool:
+		switch text {
+		case "1", "true", "TRUE":
+			return true, true
+		case "0", "false", "FALSE":
+			return false, true
+		}
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,216 @@
+package schemas
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCollection is used when a search request does not name a collection
+const DefaultCollection = "books"
+
+var (
+	mu          sync.RWMutex
+	collections = map[string]*models.CollectionSchema{
+		Books.Name:  Books,
+		Stores.Name: Stores,
+	}
+)
+
+var fieldTypes = map[string]bool{
+	"string": true, "string[]": true, "int32": true, "int32[]": true, "int64": true, "int64[]": true,
+	"float": true, "float[]": true, "bool": true, "bool[]": true, "geopoint": true, "geopoint[]": true,
+	"object": true, "object[]": true, "auto": true, "string*": true,
+}
+
+func Get(name string) (*models.CollectionSchema, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	schema, ok := collections[name]
+	return schema, ok
+}
+
+// Resolve returns the schema of a registered collection or of one of its versions, books_v3 is a books
+func Resolve(collection string) (*models.CollectionSchema, bool) {
+	if schema, ok := Get(collection); ok {
+		return schema, true
+	}
+	if idx := strings.LastIndex(collection, "_v"); idx > 0 {
+		if _, err := strconv.Atoi(collection[idx+2:]); err == nil {
+			return Get(collection[:idx])
+		}
+	}
+	return nil, false
+}
+
+func List() []*models.CollectionSchema {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := make([]*models.CollectionSchema, 0, len(collections))
+	for _, schema := range collections {
+		result = append(result, schema)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Register adds or replaces a collection after checking it is a schema Typesense accepts,
+// the child collection of its chunks with it
+func Register(schema *models.CollectionSchema) error {
+	if err := Check(schema); err != nil {
+		return err
+	}
+	var child *models.CollectionSchema
+	if schema.Chunking != nil {
+		child = ChunkSchema(schema)
+		if err := Check(child); err != nil {
+			return fmt.Errorf("chunks of %s: %w", schema.Name, err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	collections[schema.Name] = schema
+	if child != nil {
+		collections[child.Name] = child
+	}
+	return nil
+}
+
+func Check(schema *models.CollectionSchema) error {
+	if strings.TrimSpace(schema.Name) == "" {
+		return errors.New("collection name is required")
+	}
+	if len(schema.Fields) == 0 {
+		return fmt.Errorf("collection %s has no field", schema.Name)
+	}
+	seen := map[string]bool{}
+	for _, field := range schema.Fields {
+		if field.Name == "" {
+			return fmt.Errorf("collection %s has a field without name", schema.Name)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("collection %s declares field %s twice", schema.Name, field.Name)
+		}
+		seen[field.Name] = true
+		if !fieldTypes[field.Type] {
+			return fmt.Errorf("collection %s: field %s has unknown type %q", schema.Name, field.Name, field.Type)
+		}
+		if field.NumDim < 0 || (field.NumDim > 0 && field.Type != models.FieldTypeFloatArray) {
+			return fmt.Errorf("collection %s: field %s with num_dim must be a float[] field", schema.Name, field.Name)
+		}
+	}
+	for _, field := range schema.Fields {
+		if !field.IsEmbedding() {
+			continue
+		}
+		if field.NumDim == 0 {
+			return fmt.Errorf("collection %s: embedding field %s needs num_dim", schema.Name, field.Name)
+		}
+		for _, name := range field.EmbedFrom {
+			source, ok := schema.GetField(name)
+			if !ok || !strings.HasPrefix(source.Type, models.FieldTypeString) {
+				return fmt.Errorf("collection %s: embed_from %s of %s must be a string field", schema.Name, name, field.Name)
+			}
+		}
+	}
+	if len(schema.QueryBy) == 0 {
+		return fmt.Errorf("collection %s has no query_by field", schema.Name)
+	}
+	for _, name := range schema.QueryBy {
+		field, ok := schema.GetField(name)
+		if !ok || !strings.HasPrefix(field.Type, models.FieldTypeString) {
+			return fmt.Errorf("collection %s: query_by %s must be a string field", schema.Name, name)
+		}
+	}
+	if schema.DefaultSortingField != "" {
+		field, ok := schema.GetField(schema.DefaultSortingField)
+		if !ok || !field.IsSortable() {
+			return fmt.Errorf("collection %s: default_sorting_field %s must be a sortable field", schema.Name, schema.DefaultSortingField)
+		}
+	}
+	if schema.Chunking != nil {
+		return checkChunking(schema)
+	}
+	return nil
+}
+
+// LoadDir registers every *.yaml, *.yml file of dir, one collection per file
+func LoadDir(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		schema := &models.CollectionSchema{}
+		if err := yaml.Unmarshal(content, schema); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+		if err := Register(schema); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Diff compares a registered schema with the collection Typesense has, remote is nil when it does not exist
+func Diff(local *models.CollectionSchema, remote *models.CollectionSchema) *models.CollectionDiff {
+	diff := &models.CollectionDiff{
+		Collection: local.Name,
+		Exists:     remote != nil,
+		Added:      []models.CollectionField{},
+		Removed:    []models.CollectionField{},
+		Changed:    []models.CollectionFieldChange{},
+	}
+	if remote == nil {
+		diff.Added = append(diff.Added, local.Fields...)
+		return diff
+	}
+	for _, field := range local.Fields {
+		remoteField, ok := remote.GetField(field.Name)
+		if !ok {
+			diff.Added = append(diff.Added, field)
+			continue
+		}
+		if !sameField(field, *remoteField) {
+			diff.Changed = append(diff.Changed, models.CollectionFieldChange{
+				Name:   field.Name,
+				Local:  field,
+				Remote: *remoteField,
+			})
+		}
+	}
+	for _, field := range remote.Fields {
+		// id and wildcard fields are managed by Typesense itself
+		if field.Name == "id" || strings.Contains(field.Name, "*") {
+			continue
+		}
+		if _, ok := local.GetField(field.Name); !ok {
+			diff.Removed = append(diff.Removed, field)
+		}
+	}
+	return diff
+}
+
+func sameField(local, remote models.CollectionField) bool {
+	return local.Type == remote.Type &&
+		local.Facet == remote.Facet &&
+		local.Optional == remote.Optional &&
+		local.IsIndexed() == remote.IsIndexed() &&
+		local.NumDim == remote.NumDim
+}
@@ -0,0 +1,19 @@
+package schemas
+
+import "mine/internal/models"
+
+// DefaultLocationCollection is used when a location search request does not name a collection
+const DefaultLocationCollection = "stores"
+
+// Stores are searched by distance, location holds [lat, lng]
+var Stores = &models.CollectionSchema{
+	Name: "stores",
+	Fields: []models.CollectionField{
+		{Name: "name", Type: models.FieldTypeString},
+		{Name: "address", Type: models.FieldTypeString},
+		{Name: "city", Type: models.FieldTypeString, Facet: true},
+		{Name: "location", Type: models.FieldTypeGeopoint},
+		{Name: "rating", Type: models.FieldTypeFloat, Facet: true, Optional: true},
+	},
+	QueryBy: []string{"name", "address"},
+}
@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
+	"sort"
+	"sync"
+)
+
+// SearchBackend is a search engine the routes can send a request to, every engine answers
+// with the same SearchResult so clients do not depend on which one ran
+type SearchBackend interface {
+	Name() string
+	Search(dataInput models.Search) (*models.SearchResult, error)
+	// Index upserts documents, each one has an id
+	Index(collection string, documents []map[string]interface{}) error
+	Delete(collection, id string) error
+	Health() error
+	Capabilities() models.BackendCapabilities
+}
+
+// VectorBackend is an engine that also answers nearest neighbor searches on the embedding fields
+type VectorBackend interface {
+	SearchBackend
+	SearchVector(dataInput models.VectorSearch) (*models.SearchResult, error)
+}
+
+// DocumentBackend is an engine that also returns documents by id
+type DocumentBackend interface {
+	SearchBackend
+	FetchDocuments(collection string, ids []string) (map[string]map[string]interface{}, error)
+}
+
+// ErrNoDocuments is returned when the engine asked for documents by id cannot return them
+var ErrNoDocuments = errors.New("backend cannot fetch documents")
+
+// Registry holds the engines by name, DefaultBackend answers requests that do not pick one
+type Registry struct {
+	mu       sync.RWMutex
+	backends map[string]SearchBackend
+	failover *Failover
+}
+
+const DefaultBackend = models.BackendTypeSense
+
+func NewRegistry(backends ...SearchBackend) *Registry {
+	registry := &Registry{backends: map[string]SearchBackend{}}
+	for _, backend := range backends {
+		registry.Register(backend)
+	}
+	return registry
+}
+
+func (r *Registry) Register(backend SearchBackend) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.backends[backend.Name()] = backend
+}
+
+// SetFailover lets the secondaries of failover answer when an engine is unavailable, nil turns it off
+func (r *Registry) SetFailover(failover *Failover) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failover = failover
+}
+
+// Get returns the engine name, or the default one when name is empty
+func (r *Registry) Get(name string) (SearchBackend, error) {
+	if name == "" {
+		name = DefaultBackend
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	backend, ok := r.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown backend %q", query_builder.ErrInvalidQuery, name)
+	}
+	return backend, nil
+}
+
+// Search runs dataInput on the engine name, the backend field of the request is used when name is empty.
+// Features the engine lacks are rejected before it is called. When the engine is unavailable the
+// failover secondaries are tried and their answer is flagged degraded
+func (r *Registry) Search(name string, dataInput models.Search) (*models.SearchResult, error) {
+	if name == "" {
+		name = dataInput.Backend
+	}
+	backend, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := query_builder.CheckCapabilities(backend.Name(), backend.Capabilities(), dataInput); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	failover := r.failover
+	r.mu.RUnlock()
+	result, err := backend.Search(dataInput)
+	if err == nil {
+		result.Backend = backend.Name()
+		if failover != nil {
+			failover.store(backend.Name(), dataInput, result)
+		}
+		return result, nil
+	}
+	reason, ok := FailoverReason(err)
+	if !ok {
+		return nil, err
+	}
+	if failover != nil {
+		if result := r.fallback(failover, backend.Name(), reason, dataInput); result != nil {
+			return result, nil
+		}
+	}
+	return nil, errors.New(internal.SysStatus.SystemError.Msg)
+}
+
+// fallback returns the answer of the first secondary able to serve dataInput, nil when none is
+func (r *Registry) fallback(failover *Failover, primary, reason string, dataInput models.Search) *models.SearchResult {
+	for _, name := range failover.secondaries {
+		if name == primary {
+			continue
+		}
+		var result *models.SearchResult
+		var err error
+		if name == models.FallbackCache {
+			result, err = failover.cached(dataInput)
+		} else {
+			var backend SearchBackend
+			if backend, err = r.Get(name); err == nil {
+				if err = query_builder.CheckCapabilities(backend.Name(), backend.Capabilities(), dataInput); err == nil {
+					result, err = backend.Search(dataInput)
+				}
+			}
+		}
+		failover.log(dataInput, primary, name, reason, err)
+		if err == nil {
+			result.Degraded = true
+			result.Backend = name
+			return result
+		}
+	}
+	return nil
+}
+
+// SearchVector runs a vector request on the engine name, the backend field of the request is used when name is empty.
+// It is not failed over, the engines do not hold the same vectors
+func (r *Registry) SearchVector(name string, dataInput models.VectorSearch) (*models.SearchResult, error) {
+	if name == "" {
+		name = dataInput.Backend
+	}
+	backend, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	vectorBackend, ok := backend.(VectorBackend)
+	if !ok || !backend.Capabilities().Vector {
+		return nil, fmt.Errorf("%w: backend %q has no vector search", query_builder.ErrInvalidQuery, backend.Name())
+	}
+	if err := query_builder.CheckCapabilities(backend.Name(), backend.Capabilities(), dataInput.ToSearch()); err != nil {
+		return nil, err
+	}
+	result, err := vectorBackend.SearchVector(dataInput)
+	if err != nil {
+		if _, ok := FailoverReason(err); ok {
+			return nil, errors.New(internal.SysStatus.SystemError.Msg)
+		}
+		return nil, err
+	}
+	result.Backend = backend.Name()
+	return result, nil
+}
+
+// FetchDocuments returns the documents ids of collection from the engine name, keyed by id. Documents found
+// by a search are fetched from the engine that answered it, the failover cache and the engines without documents
+// by id fail with ErrNoDocuments
+func (r *Registry) FetchDocuments(name, collection string, ids []string) (map[string]map[string]interface{}, error) {
+	backend, err := r.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoDocuments, name)
+	}
+	documentBackend, ok := backend.(DocumentBackend)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoDocuments, name)
+	}
+	return documentBackend.FetchDocuments(collection, ids)
+}
+
+// SearchLocation runs a location request as a search with a geo area, on stores unless a collection is named
+func (r *Registry) SearchLocation(name string, dataInput models.LocationSearch) (*models.SearchResult, error) {
+	if err := query_builder.ValidateLocationSearch(dataInput); err != nil {
+		return nil, err
+	}
+	search := dataInput.ToSearch()
+	if search.Collection == "" {
+		search.Collection = schemas.DefaultLocationCollection
+	}
+	return r.Search(name, search)
+}
+
+// Statuses checks the health of every engine, sorted by name
+func (r *Registry) Statuses() []models.BackendStatus {
+	r.mu.RLock()
+	backends := make([]SearchBackend, 0, len(r.backends))
+	for _, backend := range r.backends {
+		backends = append(backends, backend)
+	}
+	r.mu.RUnlock()
+	sort.Slice(backends, func(i, j int) bool { return backends[i].Name() < backends[j].Name() })
+	result := make([]models.BackendStatus, 0, len(backends))
+	for _, backend := range backends {
+		status := models.BackendStatus{Name: backend.Name(), Healthy: true, Capabilities: backend.Capabilities()}
+		if err := backend.Health(); err != nil {
+			status.Healthy = false
+			status.Error = err.Error()
+		}
+		result = append(result, status)
+	}
+	return result
+}
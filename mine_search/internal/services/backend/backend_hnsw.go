@@ -0,0 +1,452 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/embedding"
+	"mine/internal/engines/bm25"
+	"mine/internal/engines/hnsw"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
+	"mine/internal/settings"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HNSWBackend answers vector searches from in-process HNSW graphs, one per collection with an embedding
+// field. The first embedding field of the schema is indexed, text queries are embedded like at import time
+type HNSWBackend struct {
+	stt         *settings.AppSettings
+	embedder    embedding.Embedder
+	config      hnsw.Config
+	mu          sync.RWMutex
+	collections map[string]*hnswCollection
+}
+
+// hnswCollection is the graph of a collection and its documents without the vector, keyed by id
+type hnswCollection struct {
+	schema    *models.CollectionSchema
+	field     *models.CollectionField
+	index     *hnsw.Index
+	mu        sync.RWMutex
+	documents map[string]map[string]interface{}
+}
+
+// NewHNSWBackend tunes the graphs with HNSW_M, HNSW_EF_CONSTRUCTION, HNSW_EF_SEARCH and HNSW_METRIC
+func NewHNSWBackend(appSettings *settings.AppSettings, embedder embedding.Embedder) *HNSWBackend {
+	cfgs := appSettings.Cfgs
+	return &HNSWBackend{
+		stt:      appSettings,
+		embedder: embedder,
+		config: hnsw.Config{
+			M:              cfgs.HNSWM,
+			EfConstruction: cfgs.HNSWEfConstruction,
+			EfSearch:       cfgs.HNSWEfSearch,
+			Metric:         cfgs.HNSWMetric,
+		},
+		collections: map[string]*hnswCollection{},
+	}
+}
+
+func (b *HNSWBackend) Name() string {
+	return models.BackendHNSW
+}
+
+// collection returns the graph of a registered collection, it is created empty on first use
+func (b *HNSWBackend) collection(name string) (*hnswCollection, error) {
+	if name == "" {
+		name = schemas.DefaultCollection
+	}
+	b.mu.RLock()
+	current, ok := b.collections[name]
+	b.mu.RUnlock()
+	if ok {
+		return current, nil
+	}
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, name)
+	}
+	field, err := query_builder.VectorField(schema, "")
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if current, ok := b.collections[name]; ok {
+		return current, nil
+	}
+	config := b.config
+	config.Dimensions = field.NumDim
+	index, err := hnsw.New(config)
+	if err != nil {
+		return nil, err
+	}
+	current = &hnswCollection{schema: schema, field: field, index: index, documents: map[string]map[string]interface{}{}}
+	b.collections[name] = current
+	return current, nil
+}
+
+// Graph returns the graph of collection
+func (b *HNSWBackend) Graph(collection string) (*hnsw.Index, error) {
+	current, err := b.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	return current.index, nil
+}
+
+func (b *HNSWBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	return b.SearchVector(models.VectorSearch{
+		Collection: dataInput.Collection,
+		Text:       dataInput.Text,
+		Page:       dataInput.Page,
+		PerPage:    dataInput.PerPage,
+	})
+}
+
+// SearchVector returns the neighbors of the vector of the request, or of the embedding of its text. Found is
+// the number of neighbors within k and the distance threshold, they are paged like the hits of any search
+func (b *HNSWBackend) SearchVector(dataInput models.VectorSearch) (*models.SearchResult, error) {
+	start := time.Now()
+	current, err := b.collection(dataInput.Collection)
+	if err != nil {
+		return nil, err
+	}
+	if dataInput.Field != "" && dataInput.Field != current.field.Name {
+		return nil, fmt.Errorf("%w: the hnsw backend indexes the field %q only", query_builder.ErrInvalidQuery, current.field.Name)
+	}
+	vector := dataInput.Vector
+	if len(vector) == 0 {
+		if text := dataInput.Text; text == "" || text == "*" {
+			return nil, fmt.Errorf("%w: the hnsw backend needs a text or a vector", query_builder.ErrInvalidQuery)
+		}
+		vectors, err := b.embed([]string{dataInput.Text})
+		if err != nil {
+			return nil, err
+		}
+		vector = vectors[0]
+	}
+	if err := query_builder.ValidateVector(current.field, vector); err != nil {
+		return nil, err
+	}
+	search := dataInput.ToSearch()
+	page, perPage := query_builder.CurrentPage(search), query_builder.PerPage(search)
+	k := dataInput.K
+	if k == 0 {
+		k = page * perPage
+	}
+	// HNSW_EF_SEARCH also applies to the graphs loaded from disk, 0 keeps the one they were built with
+	neighbors, err := current.index.Search(vector, k, b.config.EfSearch)
+	if err != nil {
+		internal.Log.Error("HNSWBackend.SearchVector -> Search", zap.Any("collection", current.schema.Name), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	if threshold := dataInput.DistanceThreshold; threshold > 0 {
+		kept := neighbors[:0]
+		for _, neighbor := range neighbors {
+			if float64(neighbor.Distance) <= threshold {
+				kept = append(kept, neighbor)
+			}
+		}
+		neighbors = kept
+	}
+	result := &models.SearchResult{
+		Found:   int64(len(neighbors)),
+		Page:    page,
+		PerPage: perPage,
+		Hits:    []models.SearchHit{},
+		Facets:  []models.FacetResult{},
+	}
+	from := (page - 1) * perPage
+	if from > len(neighbors) {
+		from = len(neighbors)
+	}
+	to := from + perPage
+	if to > len(neighbors) {
+		to = len(neighbors)
+	}
+	current.mu.RLock()
+	for _, neighbor := range neighbors[from:to] {
+		document, ok := current.documents[neighbor.ID]
+		if !ok {
+			// the graph was loaded without its jsonl file, the id is all there is
+			document = map[string]interface{}{"id": neighbor.ID}
+		}
+		distance := float64(neighbor.Distance)
+		result.Hits = append(result.Hits, models.SearchHit{Document: document, VectorDistance: &distance})
+	}
+	current.mu.RUnlock()
+	result.SearchTimeMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// Index inserts the vector of every document, the documents without one are embedded from the
+// embed_from fields of the indexed field
+func (b *HNSWBackend) Index(collection string, documents []map[string]interface{}) error {
+	current, err := b.collection(collection)
+	if err != nil {
+		return err
+	}
+	vectors := make([][]float32, len(documents))
+	texts := []string{}
+	missing := []int{}
+	for idx, document := range documents {
+		if vector, ok := toVector(document[current.field.Name]); ok {
+			vectors[idx] = vector
+		} else if text, ok := embedding.DocumentText(document, current.field.EmbedFrom); ok {
+			texts = append(texts, text)
+			missing = append(missing, idx)
+		}
+	}
+	if len(texts) > 0 {
+		embedded, err := b.embed(texts)
+		if err != nil {
+			return err
+		}
+		for idx, position := range missing {
+			vectors[position] = embedded[idx]
+		}
+	}
+	failed := 0
+	var firstError error
+	for idx, document := range documents {
+		err := b.insert(current, document, vectors[idx])
+		if err != nil {
+			if failed == 0 {
+				firstError = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d documents failed, first: %w", failed, len(documents), firstError)
+	}
+	return nil
+}
+
+func (b *HNSWBackend) insert(current *hnswCollection, document map[string]interface{}, vector []float32) error {
+	id, ok := document["id"].(string)
+	if !ok || id == "" {
+		return errors.New("document has no string id")
+	}
+	if vector == nil {
+		return fmt.Errorf("document %s has no %s and nothing to embed it from", id, current.field.Name)
+	}
+	if err := current.index.Insert(id, vector); err != nil {
+		return fmt.Errorf("document %s: %w", id, err)
+	}
+	stored := make(map[string]interface{}, len(document))
+	for name, value := range document {
+		if name != current.field.Name {
+			stored[name] = value
+		}
+	}
+	current.mu.Lock()
+	current.documents[id] = stored
+	current.mu.Unlock()
+	return nil
+}
+
+func (b *HNSWBackend) FetchDocuments(collection string, ids []string) (map[string]map[string]interface{}, error) {
+	current, err := b.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	documents := map[string]map[string]interface{}{}
+	current.mu.RLock()
+	defer current.mu.RUnlock()
+	for _, id := range ids {
+		if document, ok := current.documents[id]; ok {
+			documents[id] = document
+		}
+	}
+	return documents, nil
+}
+
+// Delete tombstones the vector of id, the graph is compacted when it is rebuilt from a jsonl file
+func (b *HNSWBackend) Delete(collection, id string) error {
+	current, err := b.collection(collection)
+	if err != nil {
+		return err
+	}
+	current.index.Delete(id)
+	current.mu.Lock()
+	delete(current.documents, id)
+	current.mu.Unlock()
+	return nil
+}
+
+// Health fails while no graph holds a vector, an empty engine would answer every search with nothing
+func (b *HNSWBackend) Health() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, current := range b.collections {
+		if current.index.Len() > 0 {
+			return nil
+		}
+	}
+	return errors.New("no collection loaded")
+}
+
+func (b *HNSWBackend) Capabilities() models.BackendCapabilities {
+	return models.BackendCapabilities{
+		Text:   true,
+		Vector: true,
+	}
+}
+
+// LoadDir loads, for every collection with an embedding field, the graph <collection>.hnsw from dir and
+// the documents of <collection>.jsonl. Without a graph the jsonl file is indexed, missing files are skipped.
+// It swaps the graphs, so it runs before the backend serves searches
+func (b *HNSWBackend) LoadDir(dir string) error {
+	for _, schema := range schemas.List() {
+		if len(schema.EmbeddingFields()) == 0 {
+			continue
+		}
+		current, err := b.collection(schema.Name)
+		if err != nil {
+			return err
+		}
+		graphPath := filepath.Join(dir, schema.Name+hnsw.SnapshotSuffix)
+		index, err := hnsw.LoadFile(graphPath)
+		if err != nil && !os.IsNotExist(err) {
+			internal.Log.Error("HNSWBackend.LoadDir -> LoadFile", zap.Any("path", graphPath), zap.Error(err))
+			return fmt.Errorf("load %s: %w", graphPath, err)
+		}
+		if index != nil {
+			if index.Config().Dimensions != current.field.NumDim {
+				return fmt.Errorf("load %s: %d dimensions, the field %s has %d", graphPath, index.Config().Dimensions, current.field.Name, current.field.NumDim)
+			}
+			current.index = index
+		}
+		jsonlPath := filepath.Join(dir, schema.Name+bm25.JSONLSuffix)
+		count, err := b.loadJSONL(current, jsonlPath, index == nil)
+		if err != nil {
+			internal.Log.Error("HNSWBackend.LoadDir -> loadJSONL", zap.Any("path", jsonlPath), zap.Error(err))
+			return fmt.Errorf("load %s: %w", jsonlPath, err)
+		}
+		if index != nil || count > 0 {
+			internal.Log.Info("HNSW collection loaded", zap.Any("collection", schema.Name), zap.Any("vectors", current.index.Len()), zap.Any("documents", count))
+		}
+	}
+	return nil
+}
+
+// loadJSONL reads the documents of path, they are indexed when build is set and only kept otherwise.
+// It returns the number of documents read, 0 without error when the file does not exist
+func (b *HNSWBackend) loadJSONL(current *hnswCollection, path string, build bool) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	count := 0
+	batch := []map[string]interface{}{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := b.Index(current.schema.Name, batch)
+		batch = batch[:0]
+		return err
+	}
+	for scanner.Scan() {
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+		document := map[string]interface{}{}
+		if err := json.Unmarshal(content, &document); err != nil {
+			return count, fmt.Errorf("line %d: %w", count+1, err)
+		}
+		count++
+		if build {
+			batch = append(batch, document)
+			if len(batch) >= hnswLoadBatch {
+				if err := flush(); err != nil {
+					return count, err
+				}
+			}
+			continue
+		}
+		if id, ok := document["id"].(string); ok {
+			delete(document, current.field.Name)
+			current.mu.Lock()
+			current.documents[id] = document
+			current.mu.Unlock()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// hnswLoadBatch is the number of documents embedded at once while a jsonl file is indexed
+const hnswLoadBatch = 100
+
+// SaveGraph writes the graph of collection to dir, where LoadDir finds it
+func (b *HNSWBackend) SaveGraph(dir, collection string) (string, error) {
+	current, err := b.collection(collection)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, current.schema.Name+hnsw.SnapshotSuffix)
+	return path, current.index.SaveFile(path)
+}
+
+func (b *HNSWBackend) embed(texts []string) ([][]float32, error) {
+	if b.embedder == nil {
+		internal.Log.Error("HNSWBackend.embed -> embedder", zap.Any("texts", len(texts)))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	vectors, err := b.embedder.Embed(texts)
+	if errors.Is(err, embedding.ErrEmptyText) {
+		return nil, fmt.Errorf("%w: %v", query_builder.ErrInvalidQuery, err)
+	}
+	if err != nil {
+		internal.Log.Error("HNSWBackend.embed -> Embed", zap.Any("texts", len(texts)), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	return vectors, nil
+}
+
+// toVector reads an embedding field as decoded from json or built in Go, ok is false when it is not one
+func toVector(value interface{}) ([]float32, bool) {
+	switch values := value.(type) {
+	case []float32:
+		return values, len(values) > 0
+	case []float64:
+		vector := make([]float32, len(values))
+		for idx, item := range values {
+			vector[idx] = float32(item)
+		}
+		return vector, len(vector) > 0
+	case []interface{}:
+		vector := make([]float32, len(values))
+		for idx, item := range values {
+			number, ok := item.(float64)
+			if !ok {
+				return nil, false
+			}
+			vector[idx] = float32(number)
+		}
+		return vector, len(vector) > 0
+	}
+	return nil, false
+}
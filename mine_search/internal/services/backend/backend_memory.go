@@ -0,0 +1,179 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/engines/bm25"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
+	"mine/internal/settings"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// MemoryBackend answers searches from in-process BM25 indexes, one per registered collection.
+// It needs no server, so it stays available when Typesense and Redis are not
+type MemoryBackend struct {
+	stt     *settings.AppSettings
+	mu      sync.RWMutex
+	indexes map[string]*bm25.Index
+}
+
+func NewMemoryBackend(appSettings *settings.AppSettings) *MemoryBackend {
+	return &MemoryBackend{stt: appSettings, indexes: map[string]*bm25.Index{}}
+}
+
+func (b *MemoryBackend) Name() string {
+	return models.BackendMemory
+}
+
+// Collection returns the index of a registered collection, it is created empty on first use
+func (b *MemoryBackend) Collection(name string) (*bm25.Index, error) {
+	if name == "" {
+		name = schemas.DefaultCollection
+	}
+	b.mu.RLock()
+	index, ok := b.indexes[name]
+	b.mu.RUnlock()
+	if ok {
+		return index, nil
+	}
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, name)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if index, ok := b.indexes[name]; ok {
+		return index, nil
+	}
+	index = bm25.New(schema, bm25.DefaultOptions())
+	b.indexes[name] = index
+	return index, nil
+}
+
+func (b *MemoryBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	index, err := b.Collection(dataInput.Collection)
+	if err != nil {
+		return nil, err
+	}
+	collection := index.Schema().Name
+	cursor, err := query_builder.ApplyCursor(b.stt.Keys.CursorKey, collection, &dataInput)
+	if err != nil {
+		internal.Log.Error("MemoryBackend.Search -> ApplyCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	result, err := index.Search(dataInput)
+	if err != nil {
+		return nil, err
+	}
+	if err := query_builder.CheckCursor(cursor, dataInput, result); err != nil {
+		internal.Log.Error("MemoryBackend.Search -> CheckCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	result.NextCursor = query_builder.NextCursor(b.stt.Keys.CursorKey, collection, dataInput, result)
+	return result, nil
+}
+
+func (b *MemoryBackend) FetchDocuments(collection string, ids []string) (map[string]map[string]interface{}, error) {
+	index, err := b.Collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	documents := map[string]map[string]interface{}{}
+	for _, id := range ids {
+		if document, ok := index.Get(id); ok {
+			documents[id] = document
+		}
+	}
+	return documents, nil
+}
+
+func (b *MemoryBackend) Index(collection string, documents []map[string]interface{}) error {
+	index, err := b.Collection(collection)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	var firstError error
+	for _, document := range documents {
+		if err := index.Upsert(document); err != nil {
+			if failed == 0 {
+				firstError = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d documents failed, first: %w", failed, len(documents), firstError)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Delete(collection, id string) error {
+	index, err := b.Collection(collection)
+	if err != nil {
+		return err
+	}
+	index.Delete(id)
+	return nil
+}
+
+// Health fails while no collection holds a document, an empty engine would answer every search with nothing
+func (b *MemoryBackend) Health() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, index := range b.indexes {
+		if index.Len() > 0 {
+			return nil
+		}
+	}
+	return errors.New("no collection loaded")
+}
+
+func (b *MemoryBackend) Capabilities() models.BackendCapabilities {
+	return models.BackendCapabilities{
+		Text:          true,
+		Conditions:    true,
+		Filter:        true,
+		Facets:        true,
+		Highlights:    true,
+		GeoRadius:     true,
+		GeoBox:        true,
+		GeoPolygon:    true,
+		MaxSortFields: query_builder.MaxSortFields,
+	}
+}
+
+// LoadDir fills every registered collection from its snapshot or jsonl file in dir, missing files are skipped
+func (b *MemoryBackend) LoadDir(dir string) error {
+	for _, schema := range schemas.List() {
+		index, err := b.Collection(schema.Name)
+		if err != nil {
+			return err
+		}
+		path, count, err := index.LoadDir(dir)
+		if err != nil {
+			internal.Log.Error("MemoryBackend.LoadDir -> LoadDir", zap.Any("path", path), zap.Error(err))
+			return fmt.Errorf("load %s: %w", path, err)
+		}
+		if path != "" {
+			internal.Log.Info("Memory collection loaded", zap.Any("path", path), zap.Any("documents", count))
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot writes the snapshot of collection to dir, where LoadDir finds it
+func (b *MemoryBackend) SaveSnapshot(dir, collection string) (string, error) {
+	index, err := b.Collection(collection)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, index.Schema().Name+bm25.SnapshotSuffix)
+	return path, index.SaveSnapshotFile(path)
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/repositories"
+	"mine/internal/schemas"
+	"mine/internal/settings"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// MySQLBackend searches the MySQL table named like the collection with MATCH AGAINST on the query_by
+// columns, or LIKE when the request asks for it and the table has no FULLTEXT index
+type MySQLBackend struct {
+	stt  *settings.AppSettings
+	repo *repositories.Repositories
+}
+
+func NewMySQLBackend(appSettings *settings.AppSettings, repo *repositories.Repositories) *MySQLBackend {
+	return &MySQLBackend{stt: appSettings, repo: repo}
+}
+
+func (b *MySQLBackend) Name() string {
+	return models.BackendMySQL
+}
+
+func (b *MySQLBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	start := time.Now()
+	collection := dataInput.Collection
+	if collection == "" {
+		collection = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, collection)
+	}
+	cursor, err := query_builder.ApplyCursor(b.stt.Keys.CursorKey, collection, &dataInput)
+	if err != nil {
+		internal.Log.Error("MySQL Search -> ApplyCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	query, err := query_builder.MySQLSearch(schema, dataInput)
+	if err != nil {
+		return nil, err
+	}
+	rows, total, err := b.repo.Document.Search(query)
+	if isMissingFullTextIndex(err) && dataInput.TextMode == "" {
+		// a table without FULLTEXT index is still searched, the cursor keeps the request as sent
+		likeInput := dataInput
+		likeInput.TextMode = models.MySQLModeLike
+		internal.Log.Error("MySQL Search -> Search", zap.Any("collection", collection), zap.Any("fallback", models.MySQLModeLike), zap.Error(err))
+		if query, err = query_builder.MySQLSearch(schema, likeInput); err != nil {
+			return nil, err
+		}
+		rows, total, err = b.repo.Document.Search(query)
+	}
+	if err != nil {
+		internal.Log.Error("MySQL Search -> Search", zap.Any("query", query), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	result := &models.SearchResult{
+		Found:   total,
+		Page:    query_builder.CurrentPage(dataInput),
+		PerPage: query.Limit,
+		Hits:    make([]models.SearchHit, 0, len(rows)),
+		Facets:  []models.FacetResult{},
+	}
+	for _, row := range rows {
+		hit := models.SearchHit{
+			Document:   schemas.DocumentFromRow(schema, row),
+			Highlights: map[string][]string{},
+		}
+		hit.TextMatch = mysqlScore(row[models.MySQLScoreColumn])
+		result.Hits = append(result.Hits, hit)
+	}
+	result.SearchTimeMs = time.Since(start).Milliseconds()
+	if err := query_builder.CheckCursor(cursor, dataInput, result); err != nil {
+		internal.Log.Error("MySQL Search -> CheckCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	result.NextCursor = query_builder.NextCursor(b.stt.Keys.CursorKey, collection, dataInput, result)
+	return result, nil
+}
+
+// Index is not supported, the table is owned by the application writing it
+func (b *MySQLBackend) Index(collection string, documents []map[string]interface{}) error {
+	return fmt.Errorf("%s backend is read only", models.BackendMySQL)
+}
+
+func (b *MySQLBackend) Delete(collection, id string) error {
+	return fmt.Errorf("%s backend is read only", models.BackendMySQL)
+}
+
+func (b *MySQLBackend) Health() error {
+	return b.repo.Document.Ping()
+}
+
+func (b *MySQLBackend) Capabilities() models.BackendCapabilities {
+	return models.BackendCapabilities{
+		Text:          true,
+		Conditions:    true,
+		Filter:        true,
+		MaxSortFields: query_builder.MaxSortFields,
+	}
+}
+
+// CreateFullTextIndex adds the FULLTEXT index on the query_by columns of a registered collection,
+// created is false when the index already exists
+func (b *MySQLBackend) CreateFullTextIndex(collection string) (bool, error) {
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return false, fmt.Errorf("collection %q is not registered", collection)
+	}
+	created, err := b.repo.Document.CreateFullTextIndex(schema.Name, query_builder.MySQLFullTextIndexName(schema.Name), schema.QueryBy)
+	if err != nil {
+		internal.Log.Error("CreateFullTextIndex -> CreateFullTextIndex", zap.Any("collection", collection), zap.Error(err))
+		return false, err
+	}
+	return created, nil
+}
+
+// isMissingFullTextIndex reports MySQL error 1191, MATCH on columns no FULLTEXT index covers
+func isMissingFullTextIndex(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1191
+}
+
+// mysqlScore reads the relevance column, the driver returns it as float64 or as text
+func mysqlScore(value interface{}) float64 {
+	switch typed := value.(type) {
+	case float64:
+		return typed
+	case []byte:
+		score, _ := strconv.ParseFloat(string(typed), 64)
+		return score
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"mine/internal/models"
+	"mine/internal/services/redisearch"
+
+	"github.com/go-redis/redis"
+)
+
+// redisBackend answers text searches with RediSearch
+type redisBackend struct {
+	rdb *redis.Client
+	svc redisearch.EventRediSearchService
+}
+
+func NewRedisBackend(rdb *redis.Client, svc redisearch.EventRediSearchService) SearchBackend {
+	return &redisBackend{rdb: rdb, svc: svc}
+}
+
+func (b *redisBackend) Name() string {
+	return models.BackendRedis
+}
+
+func (b *redisBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	return b.svc.SearchTextRedis(dataInput)
+}
+
+func (b *redisBackend) Index(collection string, documents []map[string]interface{}) error {
+	return b.svc.IndexRedisDocuments(collection, documents)
+}
+
+func (b *redisBackend) Delete(collection, id string) error {
+	return b.svc.DeleteRedisDocument(collection, id)
+}
+
+// Health fails when the RediSearch module is not loaded
+func (b *redisBackend) Health() error {
+	return b.rdb.Do("FT._LIST").Err()
+}
+
+func (b *redisBackend) Capabilities() models.BackendCapabilities {
+	return models.BackendCapabilities{
+		Text:          true,
+		Conditions:    true,
+		Filter:        true,
+		Highlights:    true,
+		MaxSortFields: 1,
+	}
+}
+
+// redisGeoBackend answers nearby searches from the geo sets, it shares documents with redisBackend
+type redisGeoBackend struct {
+	rdb *redis.Client
+	svc redisearch.EventRediSearchService
+}
+
+func NewRedisGeoBackend(rdb *redis.Client, svc redisearch.EventRediSearchService) SearchBackend {
+	return &redisGeoBackend{rdb: rdb, svc: svc}
+}
+
+func (b *redisGeoBackend) Name() string {
+	return models.BackendRedisGeo
+}
+
+func (b *redisGeoBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	return b.svc.SearchGeoRedis(dataInput)
+}
+
+func (b *redisGeoBackend) Index(collection string, documents []map[string]interface{}) error {
+	return b.svc.IndexRedisDocuments(collection, documents)
+}
+
+func (b *redisGeoBackend) Delete(collection, id string) error {
+	return b.svc.DeleteRedisDocument(collection, id)
+}
+
+func (b *redisGeoBackend) Health() error {
+	return b.rdb.Ping().Err()
+}
+
+func (b *redisGeoBackend) Capabilities() models.BackendCapabilities {
+	return models.BackendCapabilities{
+		GeoRadius: true,
+		GeoBox:    true,
+	}
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/settings"
+	"mine/internal/utils"
+	utilsCall "mine/internal/utils_call"
+	"net"
+	"testing"
+
+	"github.com/shettyh/threadpool"
+)
+
+// stubBackend answers every search with err, or with one hit when err is nil
+type stubBackend struct {
+	name         string
+	capabilities models.BackendCapabilities
+	err          error
+	calls        int
+}
+
+func (b *stubBackend) Name() string { return b.name }
+
+func (b *stubBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	b.calls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &models.SearchResult{Found: 1, Hits: []models.SearchHit{{Document: map[string]interface{}{"id": "1"}}}}, nil
+}
+
+func (b *stubBackend) Index(collection string, documents []map[string]interface{}) error { return nil }
+func (b *stubBackend) Delete(collection, id string) error                                { return nil }
+func (b *stubBackend) Health() error                                                     { return b.err }
+func (b *stubBackend) Capabilities() models.BackendCapabilities                          { return b.capabilities }
+
+func TestRegistryChecksCapabilities(t *testing.T) {
+	textOnly := models.BackendCapabilities{Text: true}
+	everything := models.BackendCapabilities{Text: true, Conditions: true, Filter: true, Facets: true, GeoRadius: true, GeoBox: true, MaxSortFields: 3}
+	point := &models.GeoSearching{Point: &models.GeoPoint{Lat: 10, Lng: 106}, RadiusKm: 5}
+	tests := []struct {
+		name         string
+		capabilities models.BackendCapabilities
+		search       models.Search
+		want         error
+	}{
+		{"text", textOnly, models.Search{Text: "harry"}, nil},
+		{"conditions", textOnly, models.Search{Text: "harry", Conditions: []models.ConditionSearching{{ConditionName: "title"}}}, query_builder.ErrInvalidQuery},
+		{"filter", textOnly, models.Search{Text: "harry", Filter: &models.FilterSearching{}}, query_builder.ErrInvalidQuery},
+		{"facets", textOnly, models.Search{Text: "harry", FacetBy: []models.FacetSearching{{FacetName: "authors"}}}, query_builder.ErrInvalidQuery},
+		{"sort", textOnly, models.Search{Text: "harry", OrderBys: []models.OrderBySearching{{OrderByName: "ratings_count"}}}, query_builder.ErrInvalidQuery},
+		{"radius", textOnly, models.Search{Text: "*", Geo: point}, query_builder.ErrInvalidQuery},
+		{"polygon", everything, models.Search{Text: "*", Geo: &models.GeoSearching{Polygon: []models.GeoPoint{{}, {}, {}}}}, query_builder.ErrInvalidQuery},
+		{"too many sorts", everything, models.Search{Text: "*", OrderBys: make([]models.OrderBySearching, 4)}, query_builder.ErrInvalidQuery},
+		{"supported", everything, models.Search{Text: "*", Geo: point, OrderBys: make([]models.OrderBySearching, 3)}, nil},
+	}
+	for _, test := range tests {
+		stub := &stubBackend{name: "stub", capabilities: test.capabilities}
+		registry := NewRegistry(stub)
+		_, err := registry.Search("stub", test.search)
+		if !errors.Is(err, test.want) {
+			t.Errorf("Search(%s) = %v, want %v", test.name, err, test.want)
+		}
+		wantCalls := 0
+		if test.want == nil {
+			wantCalls = 1
+		}
+		if stub.calls != wantCalls {
+			t.Errorf("Search(%s) called the engine %d times, want %d", test.name, stub.calls, wantCalls)
+		}
+	}
+}
+
+func TestFailoverReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"5xx", &utilsCall.TypeSenseError{StatusCode: 503}, true},
+		{"wrapped 5xx", fmt.Errorf("search: %w", &utilsCall.TypeSenseError{StatusCode: 500}), true},
+		{"404", &utilsCall.TypeSenseError{StatusCode: 404}, false},
+		{"400", &utilsCall.TypeSenseError{StatusCode: 400}, false},
+		{"refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"invalid query", fmt.Errorf("%w: unknown collection", query_builder.ErrInvalidQuery), false},
+		{"cursor", query_builder.ErrCursorMismatch, false},
+	}
+	for _, test := range tests {
+		if _, got := FailoverReason(test.err); got != test.want {
+			t.Errorf("FailoverReason(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRegistrySearchFailsOver(t *testing.T) {
+	utils.Pool = threadpool.NewThreadPool(1, 100)
+	unavailable := &utilsCall.TypeSenseError{StatusCode: 503}
+	invalid := fmt.Errorf("%w: bad filter", query_builder.ErrInvalidQuery)
+	tests := []struct {
+		name        string
+		primary     error
+		secondaries []string
+		secondary   error
+		wantBackend string
+		wantErr     error
+	}{
+		{"primary answers", nil, []string{"secondary"}, nil, "primary", nil},
+		{"unavailable", unavailable, []string{"secondary"}, nil, "secondary", nil},
+		{"invalid request is not failed over", invalid, []string{"secondary"}, nil, "", query_builder.ErrInvalidQuery},
+		{"secondary unavailable too", unavailable, []string{"secondary"}, unavailable, "", nil},
+		{"unknown secondary is skipped", unavailable, []string{"nowhere", "secondary"}, nil, "secondary", nil},
+		{"no secondary", unavailable, nil, nil, "", nil},
+	}
+	for _, test := range tests {
+		primary := &stubBackend{name: "primary", capabilities: models.BackendCapabilities{Text: true}, err: test.primary}
+		secondary := &stubBackend{name: "secondary", capabilities: models.BackendCapabilities{Text: true}, err: test.secondary}
+		registry := NewRegistry(primary, secondary)
+		registry.SetFailover(&Failover{stt: &settings.AppSettings{}, secondaries: test.secondaries})
+		result, err := registry.Search("primary", models.Search{Text: "harry"})
+		if test.wantBackend == "" {
+			if err == nil {
+				t.Errorf("Search(%s) answered by %s, want an error", test.name, result.Backend)
+			} else if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("Search(%s) = %v, want %v", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Search(%s) = %v, want an answer of %s", test.name, err, test.wantBackend)
+			continue
+		}
+		if result.Backend != test.wantBackend || result.Degraded != (test.wantBackend != "primary") {
+			t.Errorf("Search(%s) answered by %s degraded %v, want %s", test.name, result.Backend, result.Degraded, test.wantBackend)
+		}
+	}
+}
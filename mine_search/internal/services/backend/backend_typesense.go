@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"mine/internal/models"
+	"mine/internal/services/typesense"
+	"mine/internal/settings"
+	utilsCall "mine/internal/utils_call"
+	"strings"
+)
+
+type typeSenseBackend struct {
+	stt *settings.AppSettings
+	svc typesense.EventTypeSenseService
+}
+
+func NewTypeSenseBackend(appSettings *settings.AppSettings, svc typesense.EventTypeSenseService) SearchBackend {
+	return &typeSenseBackend{stt: appSettings, svc: svc}
+}
+
+func (b *typeSenseBackend) Name() string {
+	return models.BackendTypeSense
+}
+
+func (b *typeSenseBackend) Search(dataInput models.Search) (*models.SearchResult, error) {
+	return b.svc.SearchText(dataInput)
+}
+
+func (b *typeSenseBackend) SearchVector(dataInput models.VectorSearch) (*models.SearchResult, error) {
+	return b.svc.SearchVector(dataInput)
+}
+
+func (b *typeSenseBackend) FetchDocuments(collection string, ids []string) (map[string]map[string]interface{}, error) {
+	return b.svc.FetchDocuments(collection, ids)
+}
+
+func (b *typeSenseBackend) Index(collection string, documents []map[string]interface{}) error {
+	lines := make([]string, 0, len(documents))
+	for _, document := range documents {
+		content, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(content))
+	}
+	results, err := utilsCall.TypeSenseImportDocuments(b.stt, collection, models.ImportActionUpsert, []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return err
+	}
+	failed := 0
+	firstError := ""
+	for _, result := range results {
+		if !result.Success {
+			if failed == 0 {
+				firstError = result.Error
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d documents failed, first: %s", failed, len(documents), firstError)
+	}
+	return nil
+}
+
+func (b *typeSenseBackend) Delete(collection, id string) error {
+	return utilsCall.TypeSenseDeleteDocument(b.stt, collection, id)
+}
+
+func (b *typeSenseBackend) Health() error {
+	return utilsCall.TypeSenseHealth(b.stt)
+}
+
+func (b *typeSenseBackend) Capabilities() models.BackendCapabilities {
+	return models.BackendCapabilities{
+		Text:          true,
+		Conditions:    true,
+		Filter:        true,
+		Facets:        true,
+		Highlights:    true,
+		GeoRadius:     true,
+		GeoBox:        true,
+		GeoPolygon:    true,
+		Vector:        true,
+		MaxSortFields: 3,
+	}
+}
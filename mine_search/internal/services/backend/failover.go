@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/settings"
+	"mine/internal/utils"
+	utilsCall "mine/internal/utils_call"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultFailoverSecondaries goes from the closest answer to the coarsest one
+	DefaultFailoverSecondaries = "cache,memory,mysql"
+	DefaultFailoverCacheTTL    = 10 * time.Minute
+	// FailoverNone in FAILOVER_SECONDARIES turns failover off
+	FailoverNone = "none"
+)
+
+// Failover lists the secondaries tried in order when an engine times out, cannot be reached or answers 5xx.
+// A secondary is models.FallbackCache, the last answer to the same request, or the name of a backend
+type Failover struct {
+	stt         *settings.AppSettings
+	rdb         *redis.Client
+	secondaries []string
+	cacheTTL    time.Duration
+}
+
+// NewFailover reads FAILOVER_SECONDARIES, a comma separated list, and FAILOVER_CACHE_TTL in seconds
+func NewFailover(appSettings *settings.AppSettings, rdb *redis.Client) *Failover {
+	value := appSettings.Cfgs.FailoverSecondaries
+	if value == "" {
+		value = DefaultFailoverSecondaries
+	}
+	secondaries := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" && name != FailoverNone {
+			secondaries = append(secondaries, name)
+		}
+	}
+	cacheTTL := DefaultFailoverCacheTTL
+	if appSettings.Cfgs.FailoverCacheTTL > 0 {
+		cacheTTL = time.Duration(appSettings.Cfgs.FailoverCacheTTL) * time.Second
+	}
+	return &Failover{stt: appSettings, rdb: rdb, secondaries: secondaries, cacheTTL: cacheTTL}
+}
+
+// FailoverReason describes err when a secondary may answer instead, invalid requests and cursors
+// would fail the same way on any engine so they are not failed over
+func FailoverReason(err error) (string, bool) {
+	if err == nil || !utilsCall.IsTypeSenseUnavailable(err) {
+		return "", false
+	}
+	return err.Error(), true
+}
+
+// failsOver tells whether the errors of the engine name can be failed over, FailoverReason only knows Typesense ones
+func failsOver(name string) bool {
+	return name == models.BackendTypeSense
+}
+
+func (f *Failover) usesCache() bool {
+	for _, name := range f.secondaries {
+		if name == models.FallbackCache {
+			return true
+		}
+	}
+	return false
+}
+
+// failoverCacheKey covers the whole request, page and cursor included, so a cached page is served as it was
+func failoverCacheKey(dataInput models.Search) string {
+	dataInput.Backend = ""
+	content, _ := json.Marshal(dataInput)
+	sum := sha256.Sum256(content)
+	return "failover:" + hex.EncodeToString(sum[:16])
+}
+
+// store keeps a successful answer of primary for the cache secondary, off the request in the pool
+func (f *Failover) store(primary string, dataInput models.Search, result *models.SearchResult) {
+	if !f.usesCache() || !failsOver(primary) {
+		return
+	}
+	// the caller goes on with result, the task gets its own copy of the hits
+	stored := *result
+	stored.Hits = append([]models.SearchHit{}, result.Hits...)
+	if err := utils.Pool.Execute(&failoverStoreTask{failover: f, dataInput: dataInput, result: &stored}); err != nil {
+		internal.Log.Error("Failover.store -> Execute", zap.Any("collection", dataInput.Collection), zap.Error(err))
+	}
+}
+
+type failoverStoreTask struct {
+	failover  *Failover
+	dataInput models.Search
+	result    *models.SearchResult
+}
+
+func (t *failoverStoreTask) Run() {
+	content, err := json.Marshal(t.result)
+	if err != nil {
+		internal.Log.Error("Failover.store -> Marshal", zap.Error(err))
+		return
+	}
+	if err := t.failover.rdb.Set(failoverCacheKey(t.dataInput), content, t.failover.cacheTTL).Err(); err != nil {
+		internal.Log.Error("Failover.store -> Set", zap.Any("collection", t.dataInput.Collection), zap.Error(err))
+	}
+}
+
+func (f *Failover) cached(dataInput models.Search) (*models.SearchResult, error) {
+	content, err := f.rdb.Get(failoverCacheKey(dataInput)).Bytes()
+	if err == redis.Nil {
+		return nil, errors.New("no cached result")
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &models.SearchResult{}
+	if err := json.Unmarshal(content, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// log records a fallback attempt in the service log and in Kibana, outcome is nil when the secondary answered
+func (f *Failover) log(dataInput models.Search, primary, secondary, reason string, outcome error) {
+	result := "ok"
+	if outcome != nil {
+		result = outcome.Error()
+	}
+	internal.Log.Error("Search failover", zap.Any("collection", dataInput.Collection), zap.Any("primary", primary),
+		zap.Any("secondary", secondary), zap.Any("reason", reason), zap.Any("result", result))
+	utils.Pool.Execute(&utils.SendLogToKibanaTask{
+		BootstrapServer: f.stt.Brokers,
+		TopicName:       f.stt.KafkaTopicName,
+		Message: utils.KibanaMessage{
+			ServiceName: internal.ServiceName,
+			FuncName:    "SearchFailover",
+			Input: map[string]interface{}{
+				"collection": dataInput.Collection,
+				"text":       dataInput.Text,
+				"primary":    primary,
+				"secondary":  secondary,
+			},
+			Output:  fmt.Sprintf("Reason: %s, Result: %s", reason, result),
+			Version: utils.GetTimeUTC7().String(),
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package bloom
+
+import (
+	"mine/internal"
+	"mine/internal/engines/bloom"
+	"mine/internal/models"
+	"mine/internal/settings"
+	"sync"
+
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+)
+
+type EventBloomService interface {
+	CreateBloom(dataInput models.BloomCreate) (*models.BloomStats, error)
+	ListBlooms() ([]models.BloomStats, error)
+	BloomStats(name string) (*models.BloomStats, error)
+	DropBloom(name string) error
+	AddBloom(name string, items []string) (*models.BloomAddResult, error)
+	MightContain(name string, items []string) ([]models.BloomCheck, error)
+	DeleteBloom(name string, items []string) (*models.BloomDeleteResult, error)
+	RebuildBloom(name string) (*models.BloomStats, error)
+	// RebuildBlooms and SnapshotBlooms are run by the scheduler
+	RebuildBlooms()
+	SnapshotBlooms()
+	RestoreBlooms() error
+}
+type eventBloomService struct {
+	stt *settings.AppSettings
+	rdb *redis.Client
+	mu  sync.RWMutex
+	// memory holds the in-process filters, the redis ones are opened by name on every call
+	// so a filter created or dropped by another replica is seen at once
+	memory map[string]bloom.Snapshotter
+	// store persists the in-process filters, nil when BLOOM_SNAPSHOT_STORE is empty
+	store bloom.SnapshotStore
+}
+
+func NewBloomService(
+	appSettings *settings.AppSettings,
+	rdb *redis.Client,
+) EventBloomService {
+	store, err := bloom.NewSnapshotStore(appSettings.Cfgs.BloomSnapshotStore, appSettings.Cfgs.BloomSnapshotDir, rdb)
+	if err != nil {
+		internal.Log.Error("NewBloomService -> NewSnapshotStore", zap.Any("store", appSettings.Cfgs.BloomSnapshotStore), zap.Error(err))
+	}
+	return &eventBloomService{
+		stt:    appSettings,
+		rdb:    rdb,
+		memory: map[string]bloom.Snapshotter{},
+		store:  store,
+	}
+}
@@ -0,0 +1,301 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/engines/bloom"
+	"mine/internal/models"
+	utilsCall "mine/internal/utils_call"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// CreateBloom sizes a new filter, a name is unique across both storages
+func (a *eventBloomService) CreateBloom(dataInput models.BloomCreate) (*models.BloomStats, error) {
+	meta, err := bloom.NewMeta(dataInput)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.memory[meta.Name]; ok {
+		return nil, fmt.Errorf("%w: %q", bloom.ErrExists, meta.Name)
+	}
+	var filter bloom.Filter
+	if meta.Storage == models.BloomStorageMemory {
+		if _, err := bloom.OpenRedisFilter(a.rdb, meta.Name); err == nil {
+			return nil, fmt.Errorf("%w: %q", bloom.ErrExists, meta.Name)
+		}
+		memoryFilter := bloom.NewMemory(meta).(bloom.Snapshotter)
+		a.memory[meta.Name] = memoryFilter
+		filter = memoryFilter
+	} else if filter, err = bloom.CreateRedisFilter(a.rdb, meta); err != nil {
+		if !errors.Is(err, bloom.ErrExists) {
+			internal.Log.Error("CreateBloom -> CreateRedisFilter", zap.Any("meta", meta), zap.Error(err))
+		}
+		return nil, err
+	}
+	internal.Log.Info("Bloom filter created", zap.Any("meta", meta))
+	return filter.Stats()
+}
+
+func (a *eventBloomService) ListBlooms() ([]models.BloomStats, error) {
+	metas, err := bloom.ListRedisFilters(a.rdb)
+	if err != nil {
+		internal.Log.Error("ListBlooms -> ListRedisFilters", zap.Error(err))
+		return nil, err
+	}
+	result := make([]models.BloomStats, 0, len(metas))
+	for _, meta := range metas {
+		filter, err := bloom.OpenRedisFilter(a.rdb, meta.Name)
+		if errors.Is(err, bloom.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			internal.Log.Error("ListBlooms -> OpenRedisFilter", zap.Any("name", meta.Name), zap.Error(err))
+			return nil, err
+		}
+		stats, err := filter.Stats()
+		if err != nil {
+			internal.Log.Error("ListBlooms -> Stats", zap.Any("name", meta.Name), zap.Error(err))
+			return nil, err
+		}
+		result = append(result, *stats)
+	}
+	a.mu.RLock()
+	for _, filter := range a.memory {
+		stats, _ := filter.Stats()
+		result = append(result, *stats)
+	}
+	a.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (a *eventBloomService) BloomStats(name string) (*models.BloomStats, error) {
+	filter, err := a.filter(name)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := filter.Stats()
+	if err != nil {
+		internal.Log.Error("BloomStats -> Stats", zap.Any("name", name), zap.Error(err))
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (a *eventBloomService) DropBloom(name string) error {
+	filter, err := a.filter(name)
+	if err != nil {
+		return err
+	}
+	if err := filter.Drop(); err != nil {
+		internal.Log.Error("DropBloom -> Drop", zap.Any("name", name), zap.Error(err))
+		return err
+	}
+	a.mu.Lock()
+	delete(a.memory, name)
+	a.mu.Unlock()
+	if a.store != nil {
+		if err := a.store.Remove(name); err != nil {
+			internal.Log.Error("DropBloom -> Remove", zap.Any("name", name), zap.Error(err))
+		}
+	}
+	internal.Log.Info("Bloom filter dropped", zap.Any("name", name))
+	return nil
+}
+
+// AddBloom adds items, Added tells how many were not seen before
+func (a *eventBloomService) AddBloom(name string, items []string) (*models.BloomAddResult, error) {
+	filter, err := a.filter(name)
+	if err != nil {
+		return nil, err
+	}
+	added, err := filter.Add(items)
+	if err != nil {
+		internal.Log.Error("AddBloom -> Add", zap.Any("name", name), zap.Any("items", len(items)), zap.Error(err))
+		return nil, err
+	}
+	result := &models.BloomAddResult{Items: len(items)}
+	for _, isNew := range added {
+		if isNew {
+			result.Added++
+		}
+	}
+	return result, nil
+}
+
+// MightContain answers false when an item was surely never added, true may be a false positive
+// so a caller checks its source of truth before acting on it
+func (a *eventBloomService) MightContain(name string, items []string) ([]models.BloomCheck, error) {
+	filter, err := a.filter(name)
+	if err != nil {
+		return nil, err
+	}
+	found, err := filter.MightContain(items)
+	if err != nil {
+		internal.Log.Error("MightContain -> MightContain", zap.Any("name", name), zap.Any("items", len(items)), zap.Error(err))
+		return nil, err
+	}
+	result := make([]models.BloomCheck, 0, len(items))
+	for idx, item := range items {
+		result = append(result, models.BloomCheck{Item: item, MightContain: found[idx]})
+	}
+	return result, nil
+}
+
+// DeleteBloom removes items from a cuckoo filter, a bloom filter answers bloom.ErrNoDelete
+func (a *eventBloomService) DeleteBloom(name string, items []string) (*models.BloomDeleteResult, error) {
+	filter, err := a.filter(name)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := filter.Delete(items)
+	if err != nil {
+		if !errors.Is(err, bloom.ErrNoDelete) {
+			internal.Log.Error("DeleteBloom -> Delete", zap.Any("name", name), zap.Any("items", len(items)), zap.Error(err))
+		}
+		return nil, err
+	}
+	result := &models.BloomDeleteResult{Items: len(items)}
+	for _, found := range deleted {
+		if found {
+			result.Deleted++
+		}
+	}
+	return result, nil
+}
+
+// RebuildBloom fills a new filter of the same size from the export of its collection, then swaps it in.
+// Items added while the export is read are kept only if the export holds them
+func (a *eventBloomService) RebuildBloom(name string) (*models.BloomStats, error) {
+	filter, err := a.filter(name)
+	if err != nil {
+		return nil, err
+	}
+	meta := filter.Meta()
+	if meta.Collection == "" {
+		return nil, fmt.Errorf("%w: %q has no collection to rebuild from", bloom.ErrInvalid, name)
+	}
+	export, err := utilsCall.TypeSenseExportDocuments(a.stt, meta.Collection)
+	if err != nil {
+		internal.Log.Error("RebuildBloom -> TypeSenseExportDocuments", zap.Any("meta", meta), zap.Error(err))
+		return nil, err
+	}
+	defer export.Close()
+	fresh := bloom.NewMemory(meta)
+	items, err := bloom.AddJSONL(fresh, export, meta.Field)
+	if err != nil {
+		internal.Log.Error("RebuildBloom -> AddJSONL", zap.Any("meta", meta), zap.Any("items", items), zap.Error(err))
+		return nil, err
+	}
+	switch current := filter.(type) {
+	case *bloom.RedisFilter:
+		if err := current.Replace(fresh.(*bloom.MemoryFilter)); err != nil {
+			internal.Log.Error("RebuildBloom -> Replace", zap.Any("meta", meta), zap.Error(err))
+			return nil, err
+		}
+		fresh = current
+	default:
+		a.mu.Lock()
+		// a filter dropped during the rebuild stays dropped
+		if _, ok := a.memory[name]; ok {
+			a.memory[name] = fresh.(bloom.Snapshotter)
+		}
+		a.mu.Unlock()
+		a.snapshot(fresh.(bloom.Snapshotter))
+	}
+	internal.Log.Info("Bloom filter rebuilt", zap.Any("name", name), zap.Any("collection", meta.Collection), zap.Any("items", items))
+	return fresh.Stats()
+}
+
+// RebuildBlooms rebuilds every filter created with a collection
+func (a *eventBloomService) RebuildBlooms() {
+	metas, err := bloom.ListRedisFilters(a.rdb)
+	if err != nil {
+		internal.Log.Error("RebuildBlooms -> ListRedisFilters", zap.Error(err))
+	}
+	a.mu.RLock()
+	for _, filter := range a.memory {
+		metas = append(metas, filter.Meta())
+	}
+	a.mu.RUnlock()
+	for _, meta := range metas {
+		if meta.Collection == "" {
+			continue
+		}
+		// errors are logged by RebuildBloom, the other filters are still rebuilt
+		a.RebuildBloom(meta.Name)
+	}
+}
+
+// SnapshotBlooms persists every in-process filter to the snapshot store
+func (a *eventBloomService) SnapshotBlooms() {
+	if a.store == nil {
+		return
+	}
+	a.mu.RLock()
+	filters := make([]bloom.Snapshotter, 0, len(a.memory))
+	for _, filter := range a.memory {
+		filters = append(filters, filter)
+	}
+	a.mu.RUnlock()
+	for _, filter := range filters {
+		a.snapshot(filter)
+	}
+}
+
+// RestoreBlooms loads the in-process filters saved by SnapshotBlooms, a filter created since keeps its content
+func (a *eventBloomService) RestoreBlooms() error {
+	if a.store == nil {
+		return nil
+	}
+	filters, failed, err := a.store.Load()
+	if err != nil {
+		return err
+	}
+	for name, err := range failed {
+		internal.Log.Error("RestoreBlooms -> ReadSnapshot", zap.Any("name", name), zap.Error(err))
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, filter := range filters {
+		if _, ok := a.memory[filter.Meta().Name]; !ok {
+			a.memory[filter.Meta().Name] = filter
+		}
+	}
+	internal.Log.Info("Bloom filters restored", zap.Any("count", len(filters)), zap.Any("failed", len(failed)))
+	return nil
+}
+
+func (a *eventBloomService) snapshot(filter bloom.Snapshotter) {
+	if a.store == nil {
+		return
+	}
+	if err := a.store.Save(filter); err != nil {
+		internal.Log.Error("Bloom snapshot -> Save", zap.Any("name", filter.Meta().Name), zap.Error(err))
+	}
+}
+
+// filter finds name in process first, then in Redis
+func (a *eventBloomService) filter(name string) (bloom.Filter, error) {
+	a.mu.RLock()
+	memoryFilter, ok := a.memory[name]
+	a.mu.RUnlock()
+	if ok {
+		return memoryFilter, nil
+	}
+	filter, err := bloom.OpenRedisFilter(a.rdb, name)
+	if err != nil && !errors.Is(err, bloom.ErrNotFound) {
+		internal.Log.Error("Bloom filter -> OpenRedisFilter", zap.Any("name", name), zap.Error(err))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
@@ -0,0 +1,30 @@
+package chunk
+
+import (
+	"mine/internal/models"
+	backend "mine/internal/services/backend"
+	hybrid "mine/internal/services/hybrid"
+	"mine/internal/settings"
+)
+
+// EventChunkService searches the chunks of a chunked collection and rolls them up to their parent documents
+type EventChunkService interface {
+	SearchChunks(dataInput models.ChunkSearch) (*models.SearchResult, error)
+}
+type eventChunkService struct {
+	stt       *settings.AppSettings
+	backends  *backend.Registry
+	hybridSvc hybrid.EventHybridService
+}
+
+func NewChunkService(
+	appSettings *settings.AppSettings,
+	backends *backend.Registry,
+	hybridSvc hybrid.EventHybridService,
+) EventChunkService {
+	return &eventChunkService{
+		stt:       appSettings,
+		backends:  backends,
+		hybridSvc: hybridSvc,
+	}
+}
@@ -0,0 +1,146 @@
+package chunk
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/ranking"
+	"mine/internal/schemas"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SearchChunks searches the candidates best chunks, groups them by parent and answers the parents of the page
+// ranked by their best chunk, each with its matching chunks
+func (a *eventChunkService) SearchChunks(dataInput models.ChunkSearch) (*models.SearchResult, error) {
+	start := time.Now()
+	collection := dataInput.Collection
+	if collection == "" {
+		collection = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, collection)
+	}
+	if schema.Chunking == nil {
+		return nil, fmt.Errorf("%w: collection %q has no chunking", query_builder.ErrInvalidQuery, collection)
+	}
+	child, ok := schemas.Get(schemas.ChunkCollection(schema))
+	if !ok {
+		internal.Log.Error("SearchChunks -> Get", zap.Any("collection", collection))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	candidates, err := query_builder.Candidates(dataInput.Page, dataInput.PerPage, dataInput.Candidates)
+	if err != nil {
+		return nil, err
+	}
+	if dataInput.Mode == "" {
+		dataInput.Mode = models.RetrievalKeyword
+		if len(child.EmbeddingFields()) > 0 {
+			dataInput.Mode = models.RetrievalHybrid
+		}
+	}
+	chunks, err := a.retrieve(child.Name, dataInput, candidates)
+	if errors.Is(err, query_builder.ErrInvalidQuery) {
+		return nil, err
+	}
+	if err != nil {
+		internal.Log.Error("SearchChunks -> retrieve", zap.Any("collection", child.Name), zap.Any("mode", dataInput.Mode), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	chunksPerDocument := dataInput.ChunksPerDocument
+	if chunksPerDocument == 0 {
+		chunksPerDocument = query_builder.DefaultChunksPerDocument
+	}
+	parents := ranking.RollUp(chunks.Hits, chunksPerDocument)
+	result := &models.SearchResult{
+		// only the candidates are rolled up, found counts the parents among them
+		Found:    int64(len(parents)),
+		Page:     query_builder.CurrentPage(models.Search{Page: dataInput.Page}),
+		PerPage:  query_builder.PerPage(models.Search{PerPage: dataInput.PerPage}),
+		Hits:     []models.SearchHit{},
+		Facets:   []models.FacetResult{},
+		Backend:  chunks.Backend,
+		Degraded: chunks.Degraded,
+	}
+	from, to := query_builder.PageRange(result.Page, result.PerPage, len(parents))
+	ids := make([]string, 0, to-from)
+	for _, parent := range parents[from:to] {
+		ids = append(ids, parent.ID)
+	}
+	// the parents come from the engine that found the chunks, the one of a failover holds the same documents
+	documents, err := a.backends.FetchDocuments(chunks.Backend, schema.Name, ids)
+	if err != nil {
+		internal.Log.Error("SearchChunks -> FetchDocuments", zap.Any("backend", chunks.Backend), zap.Any("ids", ids), zap.Error(err))
+		documents = nil
+		result.Degraded = true
+	}
+	for _, parent := range parents[from:to] {
+		document, ok := documents[parent.ID]
+		if documents == nil {
+			// the parent is only known by its chunks
+			document, ok = chunkParent(schema, parent), true
+		}
+		if !ok {
+			// chunks of a deleted document stay until it is imported again
+			continue
+		}
+		result.Hits = append(result.Hits, models.SearchHit{
+			Document:       document,
+			TextMatch:      parent.Best.TextMatch,
+			Highlights:     map[string][]string{},
+			VectorDistance: parent.Best.VectorDistance,
+			Hybrid:         parent.Best.Hybrid,
+			Chunks:         parent.Chunks,
+		})
+	}
+	result.SearchTimeMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// chunkParent is the document of parent as its best chunk knows it, its id and the copied fields
+func chunkParent(schema *models.CollectionSchema, parent ranking.Parent) map[string]interface{} {
+	document := map[string]interface{}{"id": parent.ID}
+	for _, name := range schema.Chunking.Copy {
+		if value, ok := parent.Best.Document[name]; ok {
+			document[name] = value
+		}
+	}
+	return document
+}
+
+// retrieve runs the chunk search of the mode, the candidates best chunks
+func (a *eventChunkService) retrieve(collection string, dataInput models.ChunkSearch, candidates int) (*models.SearchResult, error) {
+	switch dataInput.Mode {
+	case models.RetrievalVector:
+		return a.backends.SearchVector(models.BackendTypeSense, models.VectorSearch{
+			Collection: collection,
+			Text:       dataInput.Text,
+			K:          candidates,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    candidates,
+		})
+	case models.RetrievalHybrid:
+		return a.hybridSvc.SearchHybrid(models.HybridSearch{
+			Collection: collection,
+			Text:       dataInput.Text,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    candidates,
+			Backend:    models.BackendTypeSense,
+			Fusion:     dataInput.Fusion,
+			Alpha:      dataInput.Alpha,
+		})
+	}
+	return a.backends.Search(models.BackendTypeSense, models.Search{
+		Collection: collection,
+		Text:       dataInput.Text,
+		Conditions: dataInput.Conditions,
+		Filter:     dataInput.Filter,
+		PerPage:    candidates,
+	})
+}
@@ -0,0 +1,31 @@
+package collection
+
+import (
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/settings"
+)
+
+type EventCollectionService interface {
+	ListCollections() ([]models.CollectionStatus, error)
+	DescribeCollection(name string) (*models.CollectionInfo, error)
+	CreateCollection(name string) (*models.CollectionInfo, error)
+	UpdateCollection(name string) (*models.CollectionDiff, error)
+	DiffCollection(name string) (*models.CollectionDiff, error)
+	// DropCollection refuses aliases, live collections and rollback versions unless force
+	DropCollection(name string, force bool) error
+}
+type eventCollectionService struct {
+	stt  *settings.AppSettings
+	repo *repositories.Repositories
+}
+
+func NewCollectionService(
+	appSettings *settings.AppSettings,
+	repo *repositories.Repositories,
+) EventCollectionService {
+	return &eventCollectionService{
+		stt:  appSettings,
+		repo: repo,
+	}
+}
@@ -0,0 +1,224 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/schemas"
+	"mine/internal/services/reindex"
+	utilsCall "mine/internal/utils_call"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownCollection is returned for a collection missing in the registry
+	ErrUnknownCollection = errors.New("collection is not registered")
+	// ErrProtectedCollection is returned when dropping an alias, the collection it serves or a version kept for
+	// rollback without force
+	ErrProtectedCollection = errors.New("collection is protected")
+)
+
+func (a *eventCollectionService) ListCollections() ([]models.CollectionStatus, error) {
+	remotes, err := utilsCall.TypeSenseListCollections(a.stt)
+	if err != nil {
+		internal.Log.Error("ListCollections -> TypeSenseListCollections", zap.Error(err))
+		return nil, err
+	}
+	aliases, err := utilsCall.TypeSenseListAliases(a.stt)
+	if err != nil {
+		internal.Log.Error("ListCollections -> TypeSenseListAliases", zap.Error(err))
+		return nil, err
+	}
+	remoteByName := map[string]*models.CollectionInfo{}
+	for idx := range remotes {
+		remoteByName[remotes[idx].Name] = &remotes[idx]
+	}
+	aliasTargets := map[string]string{}
+	for _, alias := range aliases {
+		aliasTargets[alias.Name] = alias.CollectionName
+	}
+	result := []models.CollectionStatus{}
+	for _, schema := range schemas.List() {
+		status := models.CollectionStatus{Name: schema.Name, Registered: true, LiveCollection: aliasTargets[schema.Name]}
+		target := schema.Name
+		if status.LiveCollection != "" {
+			target = status.LiveCollection
+		}
+		if remote, ok := remoteByName[target]; ok {
+			status.Exists = true
+			status.NumDocuments = remote.NumDocuments
+			status.InSync = schemas.Diff(schema, &remote.CollectionSchema).InSync()
+			delete(remoteByName, target)
+		}
+		result = append(result, status)
+	}
+	for _, remote := range remotes {
+		if _, ok := remoteByName[remote.Name]; ok {
+			result = append(result, models.CollectionStatus{
+				Name:         remote.Name,
+				Exists:       true,
+				NumDocuments: remote.NumDocuments,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (a *eventCollectionService) DescribeCollection(name string) (*models.CollectionInfo, error) {
+	result, err := utilsCall.TypeSenseGetCollection(a.stt, a.resolve(name))
+	if err != nil {
+		internal.Log.Error("DescribeCollection -> TypeSenseGetCollection", zap.Any("name", name), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *eventCollectionService) CreateCollection(name string) (*models.CollectionInfo, error) {
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCollection, name)
+	}
+	result, err := utilsCall.TypeSenseCreateCollection(a.stt, schema)
+	if err != nil {
+		internal.Log.Error("CreateCollection -> TypeSenseCreateCollection", zap.Any("schema", schema), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateCollection applies the diff with the registry: new fields are added, removed fields dropped
+// and a changed field is dropped then added again in the same patch
+func (a *eventCollectionService) UpdateCollection(name string) (*models.CollectionDiff, error) {
+	diff, err := a.DiffCollection(name)
+	if err != nil {
+		return nil, err
+	}
+	if !diff.Exists {
+		return nil, fmt.Errorf("collection %s does not exist on typesense, create it first", name)
+	}
+	if diff.InSync() {
+		return diff, nil
+	}
+	patch := []models.CollectionFieldPatch{}
+	for _, field := range diff.Removed {
+		patch = append(patch, models.CollectionFieldPatch{CollectionField: models.CollectionField{Name: field.Name}, Drop: true})
+	}
+	for _, change := range diff.Changed {
+		patch = append(patch, models.CollectionFieldPatch{CollectionField: models.CollectionField{Name: change.Name}, Drop: true})
+		patch = append(patch, models.CollectionFieldPatch{CollectionField: change.Local})
+	}
+	for _, field := range diff.Added {
+		patch = append(patch, models.CollectionFieldPatch{CollectionField: field})
+	}
+	if err := utilsCall.TypeSenseUpdateCollection(a.stt, a.resolve(name), patch); err != nil {
+		internal.Log.Error("UpdateCollection -> TypeSenseUpdateCollection", zap.Any("name", name), zap.Any("patch", patch), zap.Error(err))
+		return nil, err
+	}
+	return diff, nil
+}
+
+func (a *eventCollectionService) DiffCollection(name string) (*models.CollectionDiff, error) {
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCollection, name)
+	}
+	remote, err := utilsCall.TypeSenseGetCollection(a.stt, a.resolve(name))
+	if utilsCall.IsTypeSenseNotFound(err) {
+		return schemas.Diff(schema, nil), nil
+	}
+	if err != nil {
+		internal.Log.Error("DiffCollection -> TypeSenseGetCollection", zap.Any("name", name), zap.Error(err))
+		return nil, err
+	}
+	return schemas.Diff(schema, &remote.CollectionSchema), nil
+}
+
+// DropCollection drops name and, for a chunked collection or one of its versions, the collection of its chunks.
+// Without force it refuses an alias, the collection an alias serves and the versions kept for rollback
+func (a *eventCollectionService) DropCollection(name string, force bool) error {
+	if !force {
+		if err := a.checkDrop(name); err != nil {
+			return err
+		}
+	}
+	if err := utilsCall.TypeSenseDropCollection(a.stt, name); err != nil {
+		internal.Log.Error("DropCollection -> TypeSenseDropCollection", zap.Any("name", name), zap.Error(err))
+		return err
+	}
+	if schema, ok := schemas.Resolve(name); ok && schema.Chunking != nil {
+		chunks := schemas.VersionChunkCollection(schema, name)
+		if err := utilsCall.TypeSenseDropCollection(a.stt, chunks); err != nil && !utilsCall.IsTypeSenseNotFound(err) {
+			internal.Log.Error("DropCollection -> TypeSenseDropCollection", zap.Any("chunks", chunks), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDrop fails with ErrProtectedCollection when name is an alias, is served by one or is, with its chunks, among
+// the reindex.KeepVersions newest versions of an alias up to the live one
+func (a *eventCollectionService) checkDrop(name string) error {
+	aliases, err := utilsCall.TypeSenseListAliases(a.stt)
+	if err != nil {
+		internal.Log.Error("checkDrop -> TypeSenseListAliases", zap.Error(err))
+		return err
+	}
+	for _, alias := range aliases {
+		if alias.Name == name {
+			return fmt.Errorf("%w: %s is an alias of %s", ErrProtectedCollection, name, alias.CollectionName)
+		}
+		if alias.CollectionName == name {
+			return fmt.Errorf("%w: %s is served by the alias %s", ErrProtectedCollection, name, alias.Name)
+		}
+	}
+	version := strings.TrimSuffix(name, "_chunks")
+	for _, alias := range aliases {
+		number := versionNumber(alias.Name, version)
+		live := versionNumber(alias.Name, alias.CollectionName)
+		if number == 0 || live == 0 || number > live {
+			continue
+		}
+		collections, err := utilsCall.TypeSenseListCollections(a.stt)
+		if err != nil {
+			internal.Log.Error("checkDrop -> TypeSenseListCollections", zap.Error(err))
+			return err
+		}
+		// the versions between name and the live one, both included
+		kept := 0
+		for _, collection := range collections {
+			if version := versionNumber(alias.Name, collection.Name); version >= number && version <= live {
+				kept++
+			}
+		}
+		if kept <= reindex.KeepVersions {
+			return fmt.Errorf("%w: %s is kept to roll %s back", ErrProtectedCollection, name, alias.Name)
+		}
+	}
+	return nil
+}
+
+// versionNumber is N for name_vN, 0 for any other collection
+func versionNumber(name, collection string) int {
+	suffix, ok := strings.CutPrefix(collection, name+"_v")
+	if !ok {
+		return 0
+	}
+	number, err := strconv.Atoi(suffix)
+	if err != nil || number <= 0 {
+		return 0
+	}
+	return number
+}
+
+// resolve returns the collection an alias points at, or name itself when it is not an alias
+func (a *eventCollectionService) resolve(name string) string {
+	alias, err := utilsCall.TypeSenseGetAlias(a.stt, name)
+	if err != nil {
+		return name
+	}
+	return alias.CollectionName
+}
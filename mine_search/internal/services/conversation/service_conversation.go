@@ -0,0 +1,39 @@
+package conversation
+
+import (
+	"mine/internal/conversation"
+	"mine/internal/models"
+	backend "mine/internal/services/backend"
+	hybrid "mine/internal/services/hybrid"
+	"mine/internal/settings"
+)
+
+// EventConversationService searches the messages of a conversation, each customer having its own sessions
+type EventConversationService interface {
+	Converse(customerID string, dataInput models.ConversationMessage) (*models.ConversationReply, error)
+	ConversationHistory(customerID, sessionID string) (*models.ConversationSession, error)
+	ClearConversation(customerID string, dataInput models.ConversationSessionRequest) (*models.ConversationCleared, error)
+}
+type eventConversationService struct {
+	stt       *settings.AppSettings
+	backends  *backend.Registry
+	hybridSvc hybrid.EventHybridService
+	store     *conversation.Store
+	rewriter  conversation.Rewriter
+}
+
+func NewConversationService(
+	appSettings *settings.AppSettings,
+	backends *backend.Registry,
+	hybridSvc hybrid.EventHybridService,
+	store *conversation.Store,
+	rewriter conversation.Rewriter,
+) EventConversationService {
+	return &eventConversationService{
+		stt:       appSettings,
+		backends:  backends,
+		hybridSvc: hybridSvc,
+		store:     store,
+		rewriter:  rewriter,
+	}
+}
@@ -0,0 +1,189 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/conversation"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Converse rewrites the message with the turns of the session, searches the query and keeps the turn.
+// A failed search is not kept so the next message is rewritten from the last query that answered
+func (a *eventConversationService) Converse(customerID string, dataInput models.ConversationMessage) (*models.ConversationReply, error) {
+	sessionID, err := checkSession(customerID, dataInput.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := a.store.Turns(customerID, sessionID)
+	if err != nil {
+		internal.Log.Error("Converse -> Turns", zap.Any("customer_id", customerID), zap.Any("session_id", sessionID), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	query, rewritten, err := a.rewriter.Rewrite(dataInput.Message, history)
+	if err != nil {
+		internal.Log.Error("Converse -> Rewrite", zap.Any("rewriter", a.rewriter.Name()), zap.Any("message", dataInput.Message), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	mode := a.mode(dataInput)
+	if !a.honoursExclusions(dataInput, mode) {
+		// the turn keeps the query searched, the exclusions were not applied
+		query = conversation.WithoutExclusions(query)
+	}
+	result, err := a.search(dataInput, mode, query)
+	if err == nil && !answeredWithExclusions(mode, result) {
+		// a failover answered from an engine that searches -X as X, search again without them
+		if stripped := conversation.WithoutExclusions(query); stripped != query {
+			query = stripped
+			result, err = a.search(dataInput, mode, query)
+		}
+	}
+	if errors.Is(err, query_builder.ErrInvalidQuery) {
+		return nil, err
+	}
+	if err != nil {
+		internal.Log.Error("Converse -> search", zap.Any("query", query), zap.Any("mode", dataInput.Mode), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	turns, err := a.store.Append(customerID, sessionID, models.ConversationTurn{
+		Message: dataInput.Message,
+		Query:   query,
+		Found:   result.Found,
+		At:      time.Now().Unix(),
+	})
+	if err != nil {
+		// the answer is still given, the next message is rewritten without this turn
+		internal.Log.Error("Converse -> Append", zap.Any("customer_id", customerID), zap.Any("session_id", sessionID), zap.Error(err))
+	}
+	return &models.ConversationReply{
+		SessionID: sessionID,
+		Query:     query,
+		Rewritten: rewritten,
+		Rewriter:  a.rewriter.Name(),
+		Turns:     turns,
+		Result:    result,
+	}, nil
+}
+
+func (a *eventConversationService) ConversationHistory(customerID, sessionID string) (*models.ConversationSession, error) {
+	sessionID, err := checkSession(customerID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	turns, err := a.store.Turns(customerID, sessionID)
+	if err != nil {
+		internal.Log.Error("ConversationHistory -> Turns", zap.Any("customer_id", customerID), zap.Any("session_id", sessionID), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	ttl, err := a.store.TTL(customerID, sessionID)
+	if err != nil {
+		internal.Log.Error("ConversationHistory -> TTL", zap.Any("customer_id", customerID), zap.Any("session_id", sessionID), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	return &models.ConversationSession{SessionID: sessionID, Turns: turns, ExpiresIn: int64(ttl.Seconds())}, nil
+}
+
+func (a *eventConversationService) ClearConversation(customerID string, dataInput models.ConversationSessionRequest) (*models.ConversationCleared, error) {
+	sessionID, err := checkSession(customerID, dataInput.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	var sessions int
+	if dataInput.All {
+		sessions, err = a.store.ClearAll(customerID)
+	} else {
+		sessions, err = a.store.Clear(customerID, sessionID)
+	}
+	if err != nil {
+		internal.Log.Error("ClearConversation -> Clear", zap.Any("customer_id", customerID), zap.Any("dataInput", dataInput), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	return &models.ConversationCleared{Sessions: sessions}, nil
+}
+
+// checkSession returns the session id of the request, the default one when it is empty. Sessions belong
+// to the customer of the token, a token without one cannot hold any
+func checkSession(customerID, sessionID string) (string, error) {
+	if customerID == "" {
+		return "", fmt.Errorf("%w: the token has no customer id", query_builder.ErrInvalidQuery)
+	}
+	if sessionID == "" {
+		sessionID = models.DefaultConversationSession
+	}
+	if !conversation.ValidSession(sessionID) {
+		return "", fmt.Errorf("%w: session_id %q", query_builder.ErrInvalidQuery, sessionID)
+	}
+	return sessionID, nil
+}
+
+// mode is the mode of the message, hybrid by default when the collection has an embedding field
+func (a *eventConversationService) mode(dataInput models.ConversationMessage) string {
+	if dataInput.Mode != "" {
+		return dataInput.Mode
+	}
+	collection := dataInput.Collection
+	if collection == "" {
+		collection = schemas.DefaultCollection
+	}
+	if schema, ok := schemas.Get(collection); ok && len(schema.EmbeddingFields()) > 0 {
+		return models.RetrievalHybrid
+	}
+	return models.RetrievalKeyword
+}
+
+// honoursExclusions tells whether the search of the message should read -X as an exclusion, only the Typesense
+// keyword search does. The engine that answers is checked again by answeredWithExclusions
+func (a *eventConversationService) honoursExclusions(dataInput models.ConversationMessage, mode string) bool {
+	if mode != models.RetrievalKeyword {
+		return false
+	}
+	engine, err := a.backends.Get(dataInput.Backend)
+	return err == nil && engine.Name() == models.BackendTypeSense
+}
+
+// answeredWithExclusions tells whether the engine that answered the search read -X as an exclusion, the
+// registry may fail over to another engine than the one honoursExclusions checked
+func answeredWithExclusions(mode string, result *models.SearchResult) bool {
+	return mode == models.RetrievalKeyword && result.Backend == models.BackendTypeSense
+}
+
+// search runs the standalone query with mode
+func (a *eventConversationService) search(dataInput models.ConversationMessage, mode, query string) (*models.SearchResult, error) {
+	switch mode {
+	case models.RetrievalVector:
+		return a.backends.SearchVector(dataInput.Backend, models.VectorSearch{
+			Collection: dataInput.Collection,
+			Text:       query,
+			Field:      dataInput.Field,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			Page:       dataInput.Page,
+			PerPage:    dataInput.PerPage,
+		})
+	case models.RetrievalHybrid:
+		return a.hybridSvc.SearchHybrid(models.HybridSearch{
+			Collection: dataInput.Collection,
+			Text:       query,
+			Field:      dataInput.Field,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			Page:       dataInput.Page,
+			PerPage:    dataInput.PerPage,
+			Backend:    dataInput.Backend,
+		})
+	}
+	return a.backends.Search(dataInput.Backend, models.Search{
+		Collection: dataInput.Collection,
+		Text:       query,
+		Conditions: dataInput.Conditions,
+		Filter:     dataInput.Filter,
+		Page:       dataInput.Page,
+		PerPage:    dataInput.PerPage,
+		Backend:    dataInput.Backend,
+	})
+}
@@ -0,0 +1,29 @@
+package hybrid
+
+import (
+	"mine/internal/models"
+	backend "mine/internal/services/backend"
+	"mine/internal/settings"
+)
+
+// DefaultAlpha weighs the keyword and the vector search the same
+const DefaultAlpha = 0.5
+
+// EventHybridService ranks documents by both their keyword relevance and their embedding distance
+type EventHybridService interface {
+	SearchHybrid(dataInput models.HybridSearch) (*models.SearchResult, error)
+}
+type eventHybridService struct {
+	stt      *settings.AppSettings
+	backends *backend.Registry
+}
+
+func NewHybridService(
+	appSettings *settings.AppSettings,
+	backends *backend.Registry,
+) EventHybridService {
+	return &eventHybridService{
+		stt:      appSettings,
+		backends: backends,
+	}
+}
@@ -0,0 +1,93 @@
+package hybrid
+
+import (
+	"errors"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/ranking"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SearchHybrid runs the keyword and the vector search at once and fuses their candidates. When one of them
+// fails the other one answers alone and the result is flagged degraded, an invalid request fails both ways
+func (a *eventHybridService) SearchHybrid(dataInput models.HybridSearch) (*models.SearchResult, error) {
+	start := time.Now()
+	candidates, err := query_builder.Candidates(dataInput.Page, dataInput.PerPage, dataInput.Candidates)
+	if err != nil {
+		return nil, err
+	}
+	var keyword, vector *models.SearchResult
+	var keywordErr, vectorErr error
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		keyword, keywordErr = a.backends.Search(dataInput.Backend, dataInput.KeywordSearch(candidates))
+	}()
+	go func() {
+		defer wg.Done()
+		vector, vectorErr = a.backends.SearchVector(dataInput.VectorBackend, dataInput.VectorSearch(candidates))
+	}()
+	wg.Wait()
+	for _, err := range []error{keywordErr, vectorErr} {
+		if errors.Is(err, query_builder.ErrInvalidQuery) {
+			return nil, err
+		}
+	}
+	if keywordErr != nil && vectorErr != nil {
+		internal.Log.Error("SearchHybrid -> Search", zap.Any("dataInput", dataInput), zap.NamedError("keyword", keywordErr), zap.NamedError("vector", vectorErr))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	result := &models.SearchResult{
+		Page:    query_builder.CurrentPage(models.Search{Page: dataInput.Page}),
+		PerPage: query_builder.PerPage(models.Search{PerPage: dataInput.PerPage}),
+		Facets:  []models.FacetResult{},
+	}
+	keywordHits, vectorHits := []models.SearchHit{}, []models.SearchHit{}
+	if keywordErr != nil {
+		internal.Log.Error("SearchHybrid -> Backends.Search", zap.Any("dataInput", dataInput), zap.Error(keywordErr))
+		result.Degraded = true
+	} else {
+		keywordHits = keyword.Hits
+		result.Backend, result.Degraded = keyword.Backend, keyword.Degraded
+	}
+	if vectorErr != nil {
+		internal.Log.Error("SearchHybrid -> SearchVector", zap.Any("dataInput", dataInput), zap.Error(vectorErr))
+		result.Degraded = true
+	} else {
+		vectorHits = vector.Hits
+	}
+	hits := ranking.Fuse(keywordHits, vectorHits, a.options(dataInput))
+	// only the candidates are ranked, found counts the distinct documents among them
+	result.Found = int64(len(hits))
+	from, to := query_builder.PageRange(result.Page, result.PerPage, len(hits))
+	result.Hits = hits[from:to]
+	result.SearchTimeMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// options are the fusion of the request, HYBRID_FUSION and HYBRID_ALPHA fill what it leaves out
+func (a *eventHybridService) options(dataInput models.HybridSearch) ranking.Options {
+	options := ranking.Options{
+		Fusion:       dataInput.Fusion,
+		Alpha:        DefaultAlpha,
+		RankConstant: dataInput.RankConstant,
+	}
+	if options.Fusion == "" {
+		options.Fusion = a.stt.Cfgs.HybridFusion
+	}
+	if options.Fusion == "" {
+		options.Fusion = models.FusionRRF
+	}
+	if alpha := a.stt.Cfgs.HybridAlpha; alpha != nil && *alpha >= 0 && *alpha <= 1 {
+		options.Alpha = *alpha
+	}
+	if dataInput.Alpha != nil {
+		options.Alpha = *dataInput.Alpha
+	}
+	return options
+}
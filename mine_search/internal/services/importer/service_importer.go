@@ -0,0 +1,40 @@
+package importer
+
+import (
+	"io"
+	"mine/internal/embedding"
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/settings"
+)
+
+const (
+	DefaultBatchSize = 500
+	MaxBatchSize     = 10000
+	// MaxReportErrors caps the errors kept in ImportReport, the error writer still receives all of them
+	MaxReportErrors = 100
+)
+
+type EventImportService interface {
+	// ImportJSONL streams reader to Typesense batch by batch. Failed lines go to errWriter when it is not nil,
+	// progress is called after every batch with the running report
+	ImportJSONL(reader io.Reader, options models.ImportOptions, errWriter io.Writer, progress func(models.ImportReport)) (*models.ImportReport, error)
+}
+type eventImportService struct {
+	stt  *settings.AppSettings
+	repo *repositories.Repositories
+	// embedder fills the embedding fields of the schema, documents holding a vector keep it
+	embedder embedding.Embedder
+}
+
+func NewImportService(
+	appSettings *settings.AppSettings,
+	repo *repositories.Repositories,
+	embedder embedding.Embedder,
+) EventImportService {
+	return &eventImportService{
+		stt:      appSettings,
+		repo:     repo,
+		embedder: embedder,
+	}
+}
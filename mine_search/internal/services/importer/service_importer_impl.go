@@ -0,0 +1,282 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mine/internal"
+	"mine/internal/chunking"
+	"mine/internal/embedding"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
+	"mine/internal/utils"
+	utilsCall "mine/internal/utils_call"
+
+	"go.uber.org/zap"
+)
+
+type importLine struct {
+	number  int
+	content []byte
+}
+
+func (a *eventImportService) ImportJSONL(reader io.Reader, options models.ImportOptions, errWriter io.Writer, progress func(models.ImportReport)) (*models.ImportReport, error) {
+	if options.Action == "" {
+		options.Action = models.ImportActionUpsert
+	}
+	switch options.Action {
+	case models.ImportActionCreate, models.ImportActionUpsert, models.ImportActionUpdate:
+	default:
+		return nil, fmt.Errorf("unknown import action %q", options.Action)
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.BatchSize > MaxBatchSize {
+		options.BatchSize = MaxBatchSize
+	}
+	report := &models.ImportReport{
+		Collection: options.Collection,
+		Action:     options.Action,
+		LastLine:   options.SkipLines,
+		Errors:     []models.ImportLineError{},
+	}
+	encoder := (*json.Encoder)(nil)
+	if errWriter != nil {
+		encoder = json.NewEncoder(errWriter)
+	}
+	batch := make([]importLine, 0, options.BatchSize)
+	flush := func(lastLine int) error {
+		if len(batch) > 0 {
+			if err := a.importBatch(options, batch, report, encoder); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		report.LastLine = lastLine
+		if progress != nil {
+			progress(*report)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if lineNumber <= options.SkipLines {
+			report.Skipped++
+			continue
+		}
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+		batch = append(batch, importLine{number: lineNumber, content: append([]byte{}, content...)})
+		if len(batch) >= options.BatchSize {
+			if err := flush(lineNumber); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		internal.Log.Error("ImportJSONL -> scanner", zap.Any("line", lineNumber), zap.Error(err))
+		return report, err
+	}
+	if err := flush(lineNumber); err != nil {
+		return report, err
+	}
+	report.Done = true
+	return report, nil
+}
+
+func (a *eventImportService) importBatch(options models.ImportOptions, batch []importLine, report *models.ImportReport, encoder *json.Encoder) error {
+	documents := make([][]byte, 0, len(batch))
+	for _, line := range batch {
+		documents = append(documents, line.content)
+	}
+	if schema, ok := schemas.Resolve(options.Collection); ok && len(schema.EmbeddingFields()) > 0 {
+		if err := a.embed(schema, documents); err != nil {
+			internal.Log.Error("importBatch -> embed", zap.Any("collection", options.Collection),
+				zap.Any("from_line", batch[0].number), zap.Any("to_line", batch[len(batch)-1].number), zap.Error(err))
+			return err
+		}
+	}
+	results, err := utilsCall.TypeSenseImportDocuments(a.stt, options.Collection, options.Action, bytes.Join(documents, []byte("\n")))
+	if err != nil {
+		internal.Log.Error("importBatch -> TypeSenseImportDocuments", zap.Any("collection", options.Collection),
+			zap.Any("from_line", batch[0].number), zap.Any("to_line", batch[len(batch)-1].number), zap.Error(err))
+		return err
+	}
+	if len(results) != len(batch) {
+		return fmt.Errorf("typesense answered %d results for %d documents", len(results), len(batch))
+	}
+	if schema, ok := schemas.Resolve(options.Collection); ok && schema.Chunking != nil {
+		if err := a.importChunks(schema, options.Collection, documents, results, report); err != nil {
+			internal.Log.Error("importBatch -> importChunks", zap.Any("collection", options.Collection),
+				zap.Any("from_line", batch[0].number), zap.Any("to_line", batch[len(batch)-1].number), zap.Error(err))
+			return err
+		}
+	}
+	for idx, result := range results {
+		if result.Success {
+			report.Succeeded++
+			continue
+		}
+		report.Failed++
+		lineError := models.ImportLineError{
+			Line:     batch[idx].number,
+			Error:    result.Error,
+			Document: string(batch[idx].content),
+		}
+		if len(report.Errors) < MaxReportErrors {
+			report.Errors = append(report.Errors, lineError)
+		}
+		if encoder != nil {
+			if err := encoder.Encode(lineError); err != nil {
+				internal.Log.Error("importBatch -> write error report", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// importChunks replaces the chunks of the documents Typesense accepted with the chunks of their new text.
+// A document without id (Typesense makes one up) or without the chunked fields (a partial update) keeps its chunks.
+// The chunks of a version go to the child collection of that version, the live chunks are left to the alias switch
+func (a *eventImportService) importChunks(schema *models.CollectionSchema, collection string, documents [][]byte, results []utilsCall.TypeSenseImportResult, report *models.ImportReport) error {
+	registered, ok := schemas.Get(schemas.ChunkCollection(schema))
+	if !ok {
+		return fmt.Errorf("chunk collection of %s is not registered", schema.Name)
+	}
+	child := *registered
+	child.Name = schemas.VersionChunkCollection(schema, collection)
+	parents, chunks := []string{}, [][]byte{}
+	for idx, content := range documents {
+		if !results[idx].Success {
+			continue
+		}
+		document := map[string]interface{}{}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil || document["id"] == nil {
+			continue
+		}
+		text, ok := embedding.DocumentText(document, schema.Chunking.Fields)
+		if !ok {
+			continue
+		}
+		parentID := fmt.Sprint(document["id"])
+		parents = append(parents, parentID)
+		for index, chunk := range chunking.Split(text, *schema.Chunking) {
+			chunkDocument := map[string]interface{}{
+				"id":                    fmt.Sprintf("%s#%d", parentID, index),
+				models.ChunkParentField: parentID,
+				models.ChunkIndexField:  index,
+				models.ChunkTextField:   chunk,
+			}
+			for _, name := range schema.Chunking.Copy {
+				if value := document[name]; value != nil {
+					chunkDocument[name] = value
+				}
+			}
+			content, err := json.Marshal(chunkDocument)
+			if err != nil {
+				return err
+			}
+			chunks = append(chunks, content)
+		}
+	}
+	if len(parents) == 0 {
+		return nil
+	}
+	// a shorter text leaves fewer chunks, the old ones would outlive it
+	if _, err := utilsCall.TypeSenseDeleteDocuments(a.stt, child.Name, query_builder.TypeSenseInFilter(models.ChunkParentField, parents)); err != nil {
+		return err
+	}
+	for start := 0; start < len(chunks); start += DefaultBatchSize {
+		end := start + DefaultBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		if len(child.EmbeddingFields()) > 0 {
+			if err := a.embed(&child, chunks[start:end]); err != nil {
+				return err
+			}
+		}
+		results, err := utilsCall.TypeSenseImportDocuments(a.stt, child.Name, models.ImportActionUpsert, bytes.Join(chunks[start:end], []byte("\n")))
+		if err != nil {
+			return err
+		}
+		for idx, result := range results {
+			if result.Success {
+				report.Chunks++
+				continue
+			}
+			report.ChunksFailed++
+			internal.Log.Error("importChunks -> TypeSenseImportDocuments", zap.Any("collection", child.Name),
+				zap.Any("chunk", string(chunks[start+idx])), zap.Any("error", result.Error))
+		}
+	}
+	return nil
+}
+
+// embed fills the embedding fields missing in documents with the vector of their embed_from text,
+// a document that is not valid json is left to Typesense to report. Error reports keep the lines as read
+func (a *eventImportService) embed(schema *models.CollectionSchema, documents [][]byte) error {
+	parsed := make([]map[string]interface{}, len(documents))
+	for idx, content := range documents {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		// ids and numbers are sent back as they were read
+		decoder.UseNumber()
+		if err := decoder.Decode(&parsed[idx]); err != nil {
+			parsed[idx] = nil
+		}
+	}
+	changed := make([]bool, len(documents))
+	for _, field := range schema.EmbeddingFields() {
+		if a.embedder == nil {
+			return fmt.Errorf("no embedder for field %s", field.Name)
+		}
+		if a.embedder.Dimensions() != field.NumDim {
+			return fmt.Errorf("field %s has num_dim %d, the %s embedder gives %d", field.Name, field.NumDim, a.embedder.Name(), a.embedder.Dimensions())
+		}
+		texts, targets := []string{}, []int{}
+		for idx, document := range parsed {
+			if document == nil || document[field.Name] != nil {
+				continue
+			}
+			text, ok := embedding.DocumentText(document, field.EmbedFrom)
+			if !ok || len(utils.Tokenize(text)) == 0 {
+				continue
+			}
+			texts, targets = append(texts, text), append(targets, idx)
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		vectors, err := a.embedder.Embed(texts)
+		if err != nil {
+			return err
+		}
+		for position, idx := range targets {
+			parsed[idx][field.Name] = vectors[position]
+			changed[idx] = true
+		}
+	}
+	for idx, document := range parsed {
+		if !changed[idx] {
+			continue
+		}
+		content, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		documents[idx] = content
+	}
+	return nil
+}
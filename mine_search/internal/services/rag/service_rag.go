@@ -0,0 +1,42 @@
+package rag
+
+import (
+	"mine/internal/llm"
+	"mine/internal/models"
+	backend "mine/internal/services/backend"
+	chunk "mine/internal/services/chunk"
+	hybrid "mine/internal/services/hybrid"
+	"mine/internal/settings"
+)
+
+// DefaultK is the number of documents retrieved for a question by default
+const DefaultK = 5
+
+// EventRAGService answers questions in natural language from the documents retrieved for them
+type EventRAGService interface {
+	Ask(dataInput models.RAGAsk) (*models.RAGAnswer, error)
+}
+type eventRAGService struct {
+	stt       *settings.AppSettings
+	backends  *backend.Registry
+	hybridSvc hybrid.EventHybridService
+	chunkSvc  chunk.EventChunkService
+	// provider writes the answers, nil when LLM_PROVIDER is misconfigured
+	provider llm.LLMProvider
+}
+
+func NewRAGService(
+	appSettings *settings.AppSettings,
+	backends *backend.Registry,
+	hybridSvc hybrid.EventHybridService,
+	chunkSvc chunk.EventChunkService,
+	provider llm.LLMProvider,
+) EventRAGService {
+	return &eventRAGService{
+		stt:       appSettings,
+		backends:  backends,
+		hybridSvc: hybridSvc,
+		chunkSvc:  chunkSvc,
+		provider:  provider,
+	}
+}
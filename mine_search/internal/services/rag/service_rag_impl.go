@@ -0,0 +1,183 @@
+package rag
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/llm"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/rag"
+	"mine/internal/schemas"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ask retrieves the documents of the question, gives the model as many of them as the context budget holds
+// and returns its answer with the cited documents
+func (a *eventRAGService) Ask(dataInput models.RAGAsk) (*models.RAGAnswer, error) {
+	collection := dataInput.Collection
+	if collection == "" {
+		collection = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, collection)
+	}
+	dataInput.Collection = collection
+	// a chunked collection is retrieved through its chunks, the model reads the matching chunks
+	// instead of the long fields they were cut from
+	searched := schema
+	if schema.Chunking != nil {
+		if child, ok := schemas.Get(schemas.ChunkCollection(schema)); ok {
+			searched = child
+		}
+	}
+	if dataInput.Mode == "" {
+		dataInput.Mode = models.RetrievalKeyword
+		if len(searched.EmbeddingFields()) > 0 {
+			dataInput.Mode = models.RetrievalHybrid
+		}
+	}
+	if dataInput.K == 0 {
+		dataInput.K = DefaultK
+	}
+	fields := dataInput.Fields
+	if len(fields) == 0 {
+		fields = schema.QueryBy
+		if schema.Chunking != nil {
+			fields = withoutFields(schema.QueryBy, schema.Chunking.Fields)
+		}
+	}
+	for _, name := range fields {
+		if _, ok := schema.GetField(name); !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", query_builder.ErrInvalidQuery, name)
+		}
+	}
+	if a.provider == nil {
+		internal.Log.Error("Ask -> provider", zap.Any("collection", collection))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+
+	start := time.Now()
+	result, err := a.retrieve(dataInput, schema.Chunking != nil)
+	if errors.Is(err, query_builder.ErrInvalidQuery) {
+		return nil, err
+	}
+	if err != nil {
+		internal.Log.Error("Ask -> retrieve", zap.Any("mode", dataInput.Mode), zap.Any("question", dataInput.Question), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	answer := &models.RAGAnswer{
+		Citations:    []models.RAGCitation{},
+		Provider:     a.provider.Name(),
+		Mode:         dataInput.Mode,
+		SearchTimeMs: time.Since(start).Milliseconds(),
+		Backend:      result.Backend,
+		Degraded:     result.Degraded,
+	}
+	budget := dataInput.MaxContextTokens
+	if budget == 0 {
+		budget = a.stt.Cfgs.RAGContextTokens
+	}
+	if budget <= 0 {
+		budget = rag.DefaultContextTokens
+	}
+	documents, tokens := rag.Documents(result.Hits, fields, budget)
+	answer.Documents, answer.ContextTokens = len(documents), tokens
+
+	start = time.Now()
+	completion, err := a.provider.Complete(llm.Prompt{Question: dataInput.Question, Documents: documents})
+	if err != nil {
+		internal.Log.Error("Ask -> Complete", zap.Any("provider", a.provider.Name()), zap.Any("question", dataInput.Question), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	answer.AnswerTimeMs = time.Since(start).Milliseconds()
+	answer.Answer, answer.Model = completion.Text, completion.Model
+
+	ranks := map[string]int{}
+	for idx, hit := range result.Hits {
+		ranks[rag.DocumentID(hit, idx)] = idx
+	}
+	embeddings := schema.EmbeddingFields()
+	for _, id := range rag.Citations(completion.Text, documents) {
+		document := map[string]interface{}{}
+		for key, value := range result.Hits[ranks[id]].Document {
+			document[key] = value
+		}
+		// the keyword hits carry the vectors, they mean nothing to the reader
+		for _, field := range embeddings {
+			delete(document, field.Name)
+		}
+		answer.Citations = append(answer.Citations, models.RAGCitation{ID: id, Rank: ranks[id] + 1, Document: document})
+	}
+	return answer, nil
+}
+
+// retrieve runs the search of the mode, the top K hits of the question. The conditions of a chunked
+// collection apply to its chunks
+func (a *eventRAGService) retrieve(dataInput models.RAGAsk, chunked bool) (*models.SearchResult, error) {
+	if chunked {
+		return a.chunkSvc.SearchChunks(models.ChunkSearch{
+			Collection: dataInput.Collection,
+			Text:       dataInput.Question,
+			Mode:       dataInput.Mode,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    dataInput.K,
+			Fusion:     dataInput.Fusion,
+			Alpha:      dataInput.Alpha,
+		})
+	}
+	switch dataInput.Mode {
+	case models.RetrievalVector:
+		return a.backends.SearchVector(dataInput.Backend, models.VectorSearch{
+			Collection: dataInput.Collection,
+			Text:       dataInput.Question,
+			Field:      dataInput.Field,
+			K:          dataInput.K,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    dataInput.K,
+		})
+	case models.RetrievalHybrid:
+		return a.hybridSvc.SearchHybrid(models.HybridSearch{
+			Collection: dataInput.Collection,
+			Text:       dataInput.Question,
+			Field:      dataInput.Field,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    dataInput.K,
+			Backend:    dataInput.Backend,
+			Fusion:     dataInput.Fusion,
+			Alpha:      dataInput.Alpha,
+		})
+	}
+	return a.backends.Search(dataInput.Backend, models.Search{
+		Collection: dataInput.Collection,
+		Text:       dataInput.Question,
+		Conditions: dataInput.Conditions,
+		Filter:     dataInput.Filter,
+		PerPage:    dataInput.K,
+		Backend:    dataInput.Backend,
+	})
+}
+
+// withoutFields returns fields less the excluded ones
+func withoutFields(fields, excluded []string) []string {
+	result := []string{}
+	for _, field := range fields {
+		keep := true
+		for _, name := range excluded {
+			if name == field {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, field)
+		}
+	}
+	return result
+}
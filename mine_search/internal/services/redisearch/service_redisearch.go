@@ -0,0 +1,37 @@
+package redisearch
+
+import (
+	"io"
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/settings"
+
+	"github.com/go-redis/redis"
+)
+
+type EventRediSearchService interface {
+	SearchTextRedis(dataInput models.Search) (*models.SearchResult, error)
+	SearchGeoRedis(search models.Search) (*models.SearchResult, error)
+	CreateRedisIndex(name string) error
+	DropRedisIndex(name string, deleteDocuments bool) error
+	ImportRedisJSONL(reader io.Reader, collection string, batchSize int) (*models.ImportReport, error)
+	IndexRedisDocuments(collection string, documents []map[string]interface{}) error
+	DeleteRedisDocument(collection, id string) error
+}
+type eventRediSearchService struct {
+	stt  *settings.AppSettings
+	repo *repositories.Repositories
+	rdb  *redis.Client
+}
+
+func NewRediSearchService(
+	appSettings *settings.AppSettings,
+	repo *repositories.Repositories,
+	rdb *redis.Client,
+) EventRediSearchService {
+	return &eventRediSearchService{
+		stt:  appSettings,
+		repo: repo,
+		rdb:  rdb,
+	}
+}
@@ -0,0 +1,460 @@
+package redisearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
+	"mine/internal/services/importer"
+	"mine/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+)
+
+// maxGeoLat is the highest latitude GEOADD accepts
+const maxGeoLat = 85.05112878
+
+// SearchTextRedis answers the same requests as the Typesense search with RediSearch,
+// documents are the hashes written by ImportRedisJSONL
+func (a *eventRediSearchService) SearchTextRedis(dataInput models.Search) (*models.SearchResult, error) {
+	document := dataInput.Collection
+	if document == "" {
+		document = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(document)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, document)
+	}
+	cursor, err := query_builder.ApplyCursor(a.stt.Keys.CursorKey, document, &dataInput)
+	if err != nil {
+		internal.Log.Error("SearchTextRedis -> ApplyCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	args, err := query_builder.RediSearchArgs(schema, dataInput)
+	if err != nil {
+		internal.Log.Error("SearchTextRedis -> RediSearchArgs", zap.Any("dataInput", dataInput), zap.Error(err))
+		return nil, err
+	}
+	startTime := time.Now()
+	reply, err := a.rdb.Do(args...).Result()
+	if err != nil {
+		internal.Log.Error("SearchTextRedis -> FT.SEARCH", zap.Any("args", args), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	result, err := toSearchResult(schema, dataInput, reply)
+	if err != nil {
+		internal.Log.Error("SearchTextRedis -> toSearchResult", zap.Any("reply", reply), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	result.SearchTimeMs = time.Since(startTime).Milliseconds()
+	if err := query_builder.CheckCursor(cursor, dataInput, result); err != nil {
+		internal.Log.Error("SearchTextRedis -> CheckCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	result.NextCursor = query_builder.NextCursor(a.stt.Keys.CursorKey, document, dataInput, result)
+	return result, nil
+}
+
+// SearchGeoRedis finds the documents around a point or inside a box with GEOSEARCH,
+// then reads the documents of the page from their hashes
+func (a *eventRediSearchService) SearchGeoRedis(search models.Search) (*models.SearchResult, error) {
+	if search.Collection == "" {
+		search.Collection = schemas.DefaultLocationCollection
+	}
+	schema, ok := schemas.Get(search.Collection)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, search.Collection)
+	}
+	cursor, err := query_builder.ApplyCursor(a.stt.Keys.CursorKey, search.Collection, &search)
+	if err != nil {
+		internal.Log.Error("SearchGeoRedis -> ApplyCursor", zap.Any("cursor", search.Cursor), zap.Error(err))
+		return nil, err
+	}
+	args, err := query_builder.RedisGeoSearchArgs(schema, search)
+	if err != nil {
+		internal.Log.Error("SearchGeoRedis -> RedisGeoSearchArgs", zap.Any("search", search), zap.Error(err))
+		return nil, err
+	}
+	startTime := time.Now()
+	reply, err := a.rdb.Do(args...).Result()
+	if err != nil {
+		internal.Log.Error("SearchGeoRedis -> GEOSEARCH", zap.Any("args", args), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	members, _ := reply.([]interface{})
+	found := int64(len(members))
+	limit := search.Geo.Limit
+	if limit == 0 {
+		limit = query_builder.MaxGeoLimit
+	}
+	// COUNT may have stopped GEOSEARCH before the end of the area, a point with only a limit is the nearest ones
+	if (search.Geo.RadiusKm > 0 || search.Geo.BoundingBox != nil) && int(found) >= limit {
+		if found, err = a.countGeo(schema, search); err != nil {
+			internal.Log.Error("SearchGeoRedis -> countGeo", zap.Any("search", search), zap.Error(err))
+			return nil, errors.New(internal.SysStatus.SystemError.Msg)
+		}
+	}
+	perPage := query_builder.PerPage(search)
+	result := &models.SearchResult{
+		Found:   found,
+		Page:    query_builder.CurrentPage(search),
+		PerPage: perPage,
+		Hits:    []models.SearchHit{},
+		Facets:  []models.FacetResult{},
+	}
+	offset := (result.Page - 1) * perPage
+	if offset < len(members) {
+		members = members[offset:]
+		if len(members) > perPage {
+			members = members[:perPage]
+		}
+		pipe := a.rdb.Pipeline()
+		prefix := query_builder.RediSearchKeyPrefix(schema.Name)
+		ids := make([]string, 0, len(members))
+		distances := make([]float64, 0, len(members))
+		documents := make([]*redis.StringStringMapCmd, 0, len(members))
+		for _, member := range members {
+			pair, _ := member.([]interface{})
+			if len(pair) != 2 {
+				continue
+			}
+			distanceKm, _ := strconv.ParseFloat(fmt.Sprint(pair[1]), 64)
+			ids = append(ids, fmt.Sprint(pair[0]))
+			distances = append(distances, distanceKm*1000)
+			documents = append(documents, pipe.HGetAll(prefix+fmt.Sprint(pair[0])))
+		}
+		if _, err := pipe.Exec(); err != nil {
+			internal.Log.Error("SearchGeoRedis -> HGETALL", zap.Any("ids", ids), zap.Error(err))
+			return nil, errors.New(internal.SysStatus.SystemError.Msg)
+		}
+		for idx, document := range documents {
+			distance := distances[idx]
+			result.Hits = append(result.Hits, models.SearchHit{
+				Document:       fromHash(schema, ids[idx], document.Val()),
+				Highlights:     map[string][]string{},
+				DistanceMeters: &distance,
+			})
+		}
+	}
+	result.SearchTimeMs = time.Since(startTime).Milliseconds()
+	if err := query_builder.CheckCursor(cursor, search, result); err != nil {
+		internal.Log.Error("SearchGeoRedis -> CheckCursor", zap.Any("cursor", search.Cursor), zap.Error(err))
+		return nil, err
+	}
+	result.NextCursor = query_builder.NextCursor(a.stt.Keys.CursorKey, search.Collection, search, result)
+	return result, nil
+}
+
+// countGeo counts the documents in the area of search, GEOSEARCHSTORE writes them to a key dropped right after
+func (a *eventRediSearchService) countGeo(schema *models.CollectionSchema, search models.Search) (int64, error) {
+	dest := fmt.Sprintf("%s:%d", query_builder.RedisGeoKey(schema.Name, "count"), time.Now().UnixNano())
+	pipe := a.rdb.TxPipeline()
+	count := pipe.Do(query_builder.RedisGeoCountArgs(schema, search, dest)...)
+	pipe.Del(dest)
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+	return count.Int64()
+}
+
+func (a *eventRediSearchService) CreateRedisIndex(name string) error {
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return fmt.Errorf("collection %q is not registered", name)
+	}
+	args := query_builder.RediSearchCreateArgs(schema)
+	if err := a.rdb.Do(args...).Err(); err != nil {
+		internal.Log.Error("CreateRedisIndex -> FT.CREATE", zap.Any("args", args), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DropRedisIndex removes the index, the document hashes as well when deleteDocuments is set
+func (a *eventRediSearchService) DropRedisIndex(name string, deleteDocuments bool) error {
+	args := []interface{}{"FT.DROPINDEX", query_builder.RediSearchIndexName(name)}
+	if deleteDocuments {
+		args = append(args, "DD")
+	}
+	if err := a.rdb.Do(args...).Err(); err != nil {
+		internal.Log.Error("DropRedisIndex -> FT.DROPINDEX", zap.Any("args", args), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ImportRedisJSONL writes every jsonl document of reader as a hash the index of collection picks up and
+// adds its geopoints to the geo sets, a batch is one MULTI so the hash and the geo sets never disagree.
+// An existing document with the same id is replaced
+func (a *eventRediSearchService) ImportRedisJSONL(reader io.Reader, collection string, batchSize int) (*models.ImportReport, error) {
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return nil, fmt.Errorf("collection %q is not registered", collection)
+	}
+	if batchSize <= 0 || batchSize > importer.MaxBatchSize {
+		batchSize = importer.DefaultBatchSize
+	}
+	report := &models.ImportReport{
+		Collection: collection,
+		Action:     models.ImportActionUpsert,
+		Errors:     []models.ImportLineError{},
+	}
+	addError := func(line int, content []byte, err error) {
+		report.Failed++
+		if len(report.Errors) < importer.MaxReportErrors {
+			report.Errors = append(report.Errors, models.ImportLineError{Line: line, Error: err.Error(), Document: string(content)})
+		}
+	}
+	pipe := a.rdb.TxPipeline()
+	pending := 0
+	flush := func(lastLine int) error {
+		if pending > 0 {
+			if _, err := pipe.Exec(); err != nil {
+				internal.Log.Error("ImportRedisJSONL -> Exec", zap.Any("collection", collection), zap.Any("line", lastLine), zap.Error(err))
+				return err
+			}
+			report.Succeeded += pending
+			pending = 0
+		}
+		report.LastLine = lastLine
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+		document := map[string]interface{}{}
+		if err := json.Unmarshal(content, &document); err != nil {
+			addError(lineNumber, content, err)
+			continue
+		}
+		id, ok := document["id"]
+		if !ok || fmt.Sprint(id) == "" {
+			addError(lineNumber, content, errors.New("document has no id"))
+			continue
+		}
+		if err := queueDocument(pipe, schema, fmt.Sprint(id), document); err != nil {
+			addError(lineNumber, content, err)
+			continue
+		}
+		pending++
+		if pending >= batchSize {
+			if err := flush(lineNumber); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		internal.Log.Error("ImportRedisJSONL -> scanner", zap.Any("line", lineNumber), zap.Error(err))
+		return report, err
+	}
+	if err := flush(lineNumber); err != nil {
+		return report, err
+	}
+	report.Done = true
+	return report, nil
+}
+
+// IndexRedisDocuments writes documents the way ImportRedisJSONL does, in one MULTI
+func (a *eventRediSearchService) IndexRedisDocuments(collection string, documents []map[string]interface{}) error {
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return fmt.Errorf("collection %q is not registered", collection)
+	}
+	pipe := a.rdb.TxPipeline()
+	for idx, document := range documents {
+		id, ok := document["id"]
+		if !ok || fmt.Sprint(id) == "" {
+			return fmt.Errorf("document %d has no id", idx)
+		}
+		if err := queueDocument(pipe, schema, fmt.Sprint(id), document); err != nil {
+			return fmt.Errorf("document %v: %w", id, err)
+		}
+	}
+	if _, err := pipe.Exec(); err != nil {
+		internal.Log.Error("IndexRedisDocuments -> Exec", zap.Any("collection", collection), zap.Any("documents", len(documents)), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *eventRediSearchService) DeleteRedisDocument(collection, id string) error {
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return fmt.Errorf("collection %q is not registered", collection)
+	}
+	pipe := a.rdb.TxPipeline()
+	pipe.Del(query_builder.RediSearchKeyPrefix(collection) + id)
+	for _, field := range schema.Fields {
+		if field.IsGeo() {
+			pipe.ZRem(query_builder.RedisGeoKey(collection, field.Name), id)
+		}
+	}
+	if _, err := pipe.Exec(); err != nil {
+		internal.Log.Error("DeleteRedisDocument -> Exec", zap.Any("collection", collection), zap.Any("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// queueDocument replaces the hash of the document and its members of the geo sets,
+// a document without a geopoint leaves the geo set of that field
+func queueDocument(pipe redis.Pipeliner, schema *models.CollectionSchema, id string, document map[string]interface{}) error {
+	locations := map[string]*redis.GeoLocation{}
+	for _, field := range schema.Fields {
+		if !field.IsGeo() {
+			continue
+		}
+		point, ok := document[field.Name].([]interface{})
+		if !ok || len(point) != 2 {
+			locations[field.Name] = nil
+			continue
+		}
+		lat, okLat := point[0].(float64)
+		lng, okLng := point[1].(float64)
+		if !okLat || !okLng || lat < -maxGeoLat || lat > maxGeoLat || lng < -180 || lng > 180 {
+			return fmt.Errorf("%s is not a [lat, lng] Redis can store", field.Name)
+		}
+		locations[field.Name] = &redis.GeoLocation{Name: id, Latitude: lat, Longitude: lng}
+	}
+	key := query_builder.RediSearchKeyPrefix(schema.Name) + id
+	pipe.Del(key)
+	if hash := toHash(schema, document); len(hash) > 0 {
+		pipe.HMSet(key, hash)
+	}
+	for name, location := range locations {
+		geoKey := query_builder.RedisGeoKey(schema.Name, name)
+		if location == nil {
+			pipe.ZRem(geoKey, id)
+			continue
+		}
+		pipe.GeoAdd(geoKey, location)
+	}
+	return nil
+}
+
+// toHash flattens a document into hash fields, string[] are joined by the TAG separator
+// and geopoints are stored lng,lat as GEO attributes expect
+func toHash(schema *models.CollectionSchema, document map[string]interface{}) map[string]interface{} {
+	hash := map[string]interface{}{}
+	for _, field := range schema.Fields {
+		value, ok := document[field.Name]
+		if !ok || value == nil {
+			continue
+		}
+		switch typed := value.(type) {
+		case []interface{}:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, hashValue(item))
+			}
+			if field.IsGeo() && len(items) == 2 {
+				hash[field.Name] = items[1] + "," + items[0]
+				continue
+			}
+			hash[field.Name] = strings.Join(items, "|")
+		default:
+			hash[field.Name] = hashValue(typed)
+		}
+	}
+	return hash
+}
+
+func hashValue(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// toSearchResult reads the FT.SEARCH WITHSCORES reply: total, then key, score, fields for every hit
+func toSearchResult(schema *models.CollectionSchema, dataInput models.Search, reply interface{}) (*models.SearchResult, error) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("unexpected FT.SEARCH reply %T", reply)
+	}
+	total, _ := items[0].(int64)
+	result := &models.SearchResult{
+		Found:   total,
+		Page:    query_builder.CurrentPage(dataInput),
+		PerPage: query_builder.PerPage(dataInput),
+		Hits:    []models.SearchHit{},
+		Facets:  []models.FacetResult{},
+	}
+	tokens := utils.Tokenize(dataInput.Text)
+	prefix := query_builder.RediSearchKeyPrefix(schema.Name)
+	for idx := 1; idx+2 < len(items); idx += 3 {
+		key, _ := items[idx].(string)
+		score, _ := strconv.ParseFloat(fmt.Sprint(items[idx+1]), 64)
+		fields, _ := items[idx+2].([]interface{})
+		row := map[string]string{}
+		for pos := 0; pos+1 < len(fields); pos += 2 {
+			row[fmt.Sprint(fields[pos])] = fmt.Sprint(fields[pos+1])
+		}
+		hit := models.SearchHit{
+			Document:   fromHash(schema, strings.TrimPrefix(key, prefix), row),
+			TextMatch:  score,
+			Highlights: map[string][]string{},
+		}
+		for _, name := range schema.QueryBy {
+			hit.Highlights = highlight(hit.Highlights, name, hit.Document[name], tokens)
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	return result, nil
+}
+
+// fromHash turns hash fields back into a typed document, the reverse of toHash
+func fromHash(schema *models.CollectionSchema, id string, fields map[string]string) map[string]interface{} {
+	row := map[string]interface{}{"id": id}
+	for name, value := range fields {
+		row[name] = value
+	}
+	for _, field := range schema.Fields {
+		text, ok := fields[field.Name]
+		if !ok {
+			continue
+		}
+		switch {
+		case field.Type == models.FieldTypeStringArray:
+			row[field.Name] = strings.Split(text, "|")
+		case field.IsGeo():
+			if lng, lat, found := strings.Cut(text, ","); found {
+				row[field.Name] = lat + "," + lng
+			}
+		}
+	}
+	return schemas.DocumentFromRow(schema, row)
+}
+
+func highlight(highlights map[string][]string, name string, value interface{}, tokens []string) map[string][]string {
+	values := []string{}
+	switch typed := value.(type) {
+	case string:
+		values = append(values, typed)
+	case []string:
+		values = typed
+	}
+	for _, text := range values {
+		if marked, ok := utils.HighlightTokens(text, tokens); ok {
+			highlights[name] = append(highlights[name], marked)
+		}
+	}
+	return highlights
+}
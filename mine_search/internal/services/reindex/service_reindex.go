@@ -0,0 +1,43 @@
+package reindex
+
+import (
+	"mine/internal/models"
+	"mine/internal/repositories"
+	"mine/internal/services/importer"
+	"mine/internal/settings"
+)
+
+const (
+	DefaultMinDocumentRatio = 0.9
+	// KeepVersions counts the live version and the one kept for rollback
+	KeepVersions = 2
+	// adoptAttempts is how many times Adopt sets the alias before restoring the plain collection
+	adoptAttempts = 3
+)
+
+// EventReindexService serves a collection through an alias of the same name pointing at
+// versioned collections (books -> books_v3) so the schema can change without downtime
+type EventReindexService interface {
+	Reindex(options models.ReindexOptions) (*models.ReindexReport, error)
+	// Adopt drops the plain collection name and serves its newest version through the alias, search fails in between
+	Adopt(name string) (*models.CollectionAlias, error)
+	Rollback(name string) (*models.CollectionAlias, error)
+	ListVersions(name string) (*models.CollectionVersions, error)
+}
+type eventReindexService struct {
+	stt       *settings.AppSettings
+	repo      *repositories.Repositories
+	importSvc importer.EventImportService
+}
+
+func NewReindexService(
+	appSettings *settings.AppSettings,
+	repo *repositories.Repositories,
+	importSvc importer.EventImportService,
+) EventReindexService {
+	return &eventReindexService{
+		stt:       appSettings,
+		repo:      repo,
+		importSvc: importSvc,
+	}
+}
@@ -0,0 +1,426 @@
+package reindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/schemas"
+	utilsCall "mine/internal/utils_call"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (a *eventReindexService) Reindex(options models.ReindexOptions) (*models.ReindexReport, error) {
+	schema, ok := schemas.Get(options.Collection)
+	if !ok {
+		return nil, fmt.Errorf("collection %s is not registered", options.Collection)
+	}
+	if options.MinDocumentRatio <= 0 {
+		options.MinDocumentRatio = DefaultMinDocumentRatio
+	}
+	report := &models.ReindexReport{
+		Collection: options.Collection,
+		Checks:     []models.ReindexCheck{},
+		Pruned:     []string{},
+	}
+	live, plain, err := a.liveCollection(options.Collection)
+	if err != nil {
+		return report, err
+	}
+	if plain && !options.Adopt {
+		return report, fmt.Errorf("%s is a plain collection, not an alias: run with adopt to build its first version, then adopt it", options.Collection)
+	}
+	report.Previous = live
+	versions, err := a.versions(options.Collection)
+	if err != nil {
+		return report, err
+	}
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].number + 1
+	}
+	report.Version = versionName(options.Collection, next)
+
+	versionSchema := *schema
+	versionSchema.Name = report.Version
+	if _, err := utilsCall.TypeSenseCreateCollection(a.stt, &versionSchema); err != nil {
+		internal.Log.Error("Reindex -> TypeSenseCreateCollection", zap.Any("version", report.Version), zap.Error(err))
+		return report, err
+	}
+	// the new version is not served yet, drop it whenever it cannot be switched to
+	abort := func(err error) (*models.ReindexReport, error) {
+		a.dropVersion(schema, report.Version)
+		return report, err
+	}
+	if err := a.createChunks(schema, report.Version); err != nil {
+		internal.Log.Error("Reindex -> createChunks", zap.Any("version", report.Version), zap.Error(err))
+		return abort(err)
+	}
+
+	importReport, err := a.fill(schema, report.Version, live, options)
+	report.Import = importReport
+	if err != nil {
+		internal.Log.Error("Reindex -> fill", zap.Any("options", options), zap.Error(err))
+		return abort(err)
+	}
+	if !a.check(schema, report, options) {
+		return abort(errors.New("sanity checks failed, the alias is not moved"))
+	}
+
+	if plain {
+		// the plain collection keeps serving, Adopt replaces it by the alias in the maintenance window
+		internal.Log.Info("Reindex built the version to adopt", zap.Any("collection", options.Collection), zap.Any("version", report.Version))
+		return report, nil
+	}
+	if _, err := a.switchAlias(schema, report.Version, live); err != nil {
+		internal.Log.Error("Reindex -> switchAlias", zap.Any("report", report), zap.Error(err))
+		return report, err
+	}
+	report.Switched = true
+	internal.Log.Info("Reindex switched alias", zap.Any("collection", options.Collection), zap.Any("version", report.Version), zap.Any("previous", live))
+
+	// versions are sorted ascending, everything older than the kept ones goes
+	if len(versions) >= KeepVersions {
+		for _, version := range versions[:len(versions)-KeepVersions+1] {
+			if version.name == live {
+				continue
+			}
+			if !a.dropVersion(schema, version.name) {
+				continue
+			}
+			report.Pruned = append(report.Pruned, version.name)
+		}
+	}
+	return report, nil
+}
+
+// Adopt replaces the plain collection name by an alias of the same name pointing at its newest version, built by
+// a Reindex with adopt. Typesense cannot turn a collection into an alias, search answers 404 from the drop until the
+// alias is set: run it in a maintenance window. When the alias cannot be set the plain collection is recreated from
+// the version, so the name is served again. The chunks of a chunked collection move the same way
+func (a *eventReindexService) Adopt(name string) (*models.CollectionAlias, error) {
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("collection %s is not registered", name)
+	}
+	_, plain, err := a.liveCollection(name)
+	if err != nil {
+		return nil, err
+	}
+	if !plain {
+		return nil, fmt.Errorf("%s is not a plain collection", name)
+	}
+	versions, err := a.versions(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s has no version to adopt, run reindex with adopt first", name)
+	}
+	version := versions[len(versions)-1].name
+	if err := utilsCall.TypeSenseDropCollection(a.stt, name); err != nil {
+		internal.Log.Error("Adopt -> TypeSenseDropCollection", zap.Any("name", name), zap.Error(err))
+		return nil, err
+	}
+	if schema.Chunking != nil {
+		chunks := schemas.ChunkCollection(schema)
+		if err := utilsCall.TypeSenseDropCollection(a.stt, chunks); err != nil && !utilsCall.IsTypeSenseNotFound(err) {
+			internal.Log.Error("Adopt -> TypeSenseDropCollection", zap.Any("name", chunks), zap.Error(err))
+		}
+	}
+	var alias *models.CollectionAlias
+	for attempt := 1; attempt <= adoptAttempts; attempt++ {
+		if alias, err = a.switchAlias(schema, version, ""); err == nil {
+			internal.Log.Info("Adopt switched alias", zap.Any("name", name), zap.Any("version", version))
+			return alias, nil
+		}
+		internal.Log.Error("Adopt -> switchAlias", zap.Any("name", name), zap.Any("version", version), zap.Any("attempt", attempt), zap.Error(err))
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	if errRestore := a.restore(schema, name, version); errRestore != nil {
+		internal.Log.Error("Adopt -> restore", zap.Any("name", name), zap.Any("version", version), zap.Error(errRestore))
+		return nil, fmt.Errorf("the alias of %s is not set and the collection is not restored: %w", name, errRestore)
+	}
+	return nil, fmt.Errorf("the alias of %s is not set, the plain collection is restored from %s: %w", name, version, err)
+}
+
+// restore recreates the plain collection name, and its chunks, as a copy of version
+func (a *eventReindexService) restore(schema *models.CollectionSchema, name, version string) error {
+	if schema.Chunking != nil {
+		// the chunk alias may have been set before the parent one failed
+		if _, err := utilsCall.TypeSenseDeleteAlias(a.stt, schemas.ChunkCollection(schema)); err != nil && !utilsCall.IsTypeSenseNotFound(err) {
+			return err
+		}
+	}
+	plainSchema := *schema
+	plainSchema.Name = name
+	if _, err := utilsCall.TypeSenseCreateCollection(a.stt, &plainSchema); err != nil {
+		return err
+	}
+	if err := a.createChunks(schema, name); err != nil {
+		return err
+	}
+	options := models.ReindexOptions{Collection: name, Source: models.ReindexSourceCollection}
+	_, err := a.fill(schema, name, version, options)
+	return err
+}
+
+// Rollback moves the alias, and the one of the chunks, back to the newest version older than the live one
+func (a *eventReindexService) Rollback(name string) (*models.CollectionAlias, error) {
+	schema, ok := schemas.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("collection %s is not registered", name)
+	}
+	live, plain, err := a.liveCollection(name)
+	if err != nil {
+		return nil, err
+	}
+	if plain || live == "" {
+		return nil, fmt.Errorf("%s is not served through an alias", name)
+	}
+	versions, err := a.versions(name)
+	if err != nil {
+		return nil, err
+	}
+	liveNumber := versionNumber(name, live)
+	for idx := len(versions) - 1; idx >= 0; idx-- {
+		if versions[idx].number < liveNumber {
+			alias, err := a.switchAlias(schema, versions[idx].name, live)
+			if err != nil {
+				internal.Log.Error("Rollback -> switchAlias", zap.Any("name", name), zap.Any("version", versions[idx].name), zap.Error(err))
+				return nil, err
+			}
+			return alias, nil
+		}
+	}
+	return nil, fmt.Errorf("no version of %s older than %s", name, live)
+}
+
+func (a *eventReindexService) ListVersions(name string) (*models.CollectionVersions, error) {
+	live, _, err := a.liveCollection(name)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := utilsCall.TypeSenseListCollections(a.stt)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.CollectionVersions{
+		Collection: name,
+		Live:       live,
+		Versions:   []models.CollectionInfo{},
+	}
+	for _, collection := range collections {
+		if versionNumber(name, collection.Name) > 0 {
+			result.Versions = append(result.Versions, collection)
+		}
+	}
+	sort.Slice(result.Versions, func(i, j int) bool {
+		return versionNumber(name, result.Versions[i].Name) < versionNumber(name, result.Versions[j].Name)
+	})
+	return result, nil
+}
+
+// switchAlias points the alias of schema at version and, for a chunked schema, the alias of the chunks at the chunks
+// of version. The chunks move first and go back to the ones of previous when the parent alias cannot be set
+func (a *eventReindexService) switchAlias(schema *models.CollectionSchema, version, previous string) (*models.CollectionAlias, error) {
+	if schema.Chunking != nil {
+		if _, err := utilsCall.TypeSenseUpsertAlias(a.stt, schemas.ChunkCollection(schema), schemas.VersionChunkCollection(schema, version)); err != nil {
+			return nil, err
+		}
+	}
+	alias, err := utilsCall.TypeSenseUpsertAlias(a.stt, schema.Name, version)
+	if err != nil && schema.Chunking != nil && previous != "" {
+		if _, errBack := utilsCall.TypeSenseUpsertAlias(a.stt, schemas.ChunkCollection(schema), schemas.VersionChunkCollection(schema, previous)); errBack != nil {
+			internal.Log.Error("switchAlias -> move chunks back", zap.Any("previous", previous), zap.Error(errBack))
+		}
+	}
+	return alias, err
+}
+
+// createChunks creates the child collection of collection when schema is chunked
+func (a *eventReindexService) createChunks(schema *models.CollectionSchema, collection string) error {
+	if schema.Chunking == nil {
+		return nil
+	}
+	registered, ok := schemas.Get(schemas.ChunkCollection(schema))
+	if !ok {
+		return fmt.Errorf("chunk collection of %s is not registered", schema.Name)
+	}
+	child := *registered
+	child.Name = schemas.VersionChunkCollection(schema, collection)
+	_, err := utilsCall.TypeSenseCreateCollection(a.stt, &child)
+	return err
+}
+
+// dropVersion drops version and its chunks, it tells whether the version is gone
+func (a *eventReindexService) dropVersion(schema *models.CollectionSchema, version string) bool {
+	if schema.Chunking != nil {
+		chunks := schemas.VersionChunkCollection(schema, version)
+		if err := utilsCall.TypeSenseDropCollection(a.stt, chunks); err != nil && !utilsCall.IsTypeSenseNotFound(err) {
+			internal.Log.Error("dropVersion -> TypeSenseDropCollection", zap.Any("chunks", chunks), zap.Error(err))
+		}
+	}
+	if err := utilsCall.TypeSenseDropCollection(a.stt, version); err != nil {
+		internal.Log.Error("dropVersion -> TypeSenseDropCollection", zap.Any("version", version), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// liveCollection returns the collection searched under name and whether name is a plain collection instead of an alias
+func (a *eventReindexService) liveCollection(name string) (string, bool, error) {
+	alias, err := utilsCall.TypeSenseGetAlias(a.stt, name)
+	if err == nil {
+		return alias.CollectionName, false, nil
+	}
+	if !utilsCall.IsTypeSenseNotFound(err) {
+		return "", false, err
+	}
+	_, err = utilsCall.TypeSenseGetCollection(a.stt, name)
+	if utilsCall.IsTypeSenseNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return name, true, nil
+}
+
+type collectionVersion struct {
+	name   string
+	number int
+}
+
+func (a *eventReindexService) versions(name string) ([]collectionVersion, error) {
+	collections, err := utilsCall.TypeSenseListCollections(a.stt)
+	if err != nil {
+		internal.Log.Error("versions -> TypeSenseListCollections", zap.Error(err))
+		return nil, err
+	}
+	result := []collectionVersion{}
+	for _, collection := range collections {
+		if number := versionNumber(name, collection.Name); number > 0 {
+			result = append(result, collectionVersion{name: collection.Name, number: number})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].number < result[j].number })
+	return result, nil
+}
+
+func versionName(name string, number int) string {
+	return fmt.Sprintf("%s_v%d", name, number)
+}
+
+// versionNumber is N for name_vN, 0 for any other collection
+func versionNumber(name, collection string) int {
+	suffix, ok := strings.CutPrefix(collection, name+"_v")
+	if !ok {
+		return 0
+	}
+	number, err := strconv.Atoi(suffix)
+	if err != nil || number <= 0 {
+		return 0
+	}
+	return number
+}
+
+func (a *eventReindexService) fill(schema *models.CollectionSchema, version, live string, options models.ReindexOptions) (*models.ImportReport, error) {
+	importOptions := models.ImportOptions{
+		Collection: version,
+		Action:     models.ImportActionCreate,
+		BatchSize:  options.BatchSize,
+	}
+	var reader io.Reader
+	switch options.Source {
+	case models.ReindexSourceJSONL:
+		file, err := os.Open(options.File)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	case models.ReindexSourceCollection:
+		if live == "" {
+			return nil, fmt.Errorf("%s has no live collection to copy", options.Collection)
+		}
+		export, err := utilsCall.TypeSenseExportDocuments(a.stt, live)
+		if err != nil {
+			return nil, err
+		}
+		defer export.Close()
+		reader = export
+	case models.ReindexSourceMySQL:
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			encoder := json.NewEncoder(pipeWriter)
+			err := a.repo.Document.StreamRows(options.Table, func(row map[string]interface{}) error {
+				return encoder.Encode(schemas.DocumentFromRow(schema, row))
+			})
+			pipeWriter.CloseWithError(err)
+		}()
+		defer pipeReader.Close()
+		reader = pipeReader
+	default:
+		return nil, fmt.Errorf("unknown reindex source %q", options.Source)
+	}
+	return a.importSvc.ImportJSONL(reader, importOptions, nil, nil)
+}
+
+// check appends the sanity checks to report and tells whether all of them passed
+func (a *eventReindexService) check(schema *models.CollectionSchema, report *models.ReindexReport, options models.ReindexOptions) bool {
+	passed := true
+	addCheck := func(name string, ok bool, detail string) {
+		report.Checks = append(report.Checks, models.ReindexCheck{Name: name, Passed: ok, Detail: detail})
+		passed = passed && ok
+	}
+	info, err := utilsCall.TypeSenseGetCollection(a.stt, report.Version)
+	if err != nil {
+		addCheck("num_documents", false, err.Error())
+		return false
+	}
+	report.NumDocuments = info.NumDocuments
+	addCheck("num_documents", info.NumDocuments > 0, fmt.Sprintf("%d documents", info.NumDocuments))
+	if report.Previous != "" {
+		previous, err := utilsCall.TypeSenseGetCollection(a.stt, report.Previous)
+		if err != nil {
+			addCheck("document_ratio", false, err.Error())
+		} else {
+			minimum := int64(float64(previous.NumDocuments) * options.MinDocumentRatio)
+			addCheck("document_ratio", info.NumDocuments >= minimum,
+				fmt.Sprintf("%d documents, live %s has %d, minimum %d", info.NumDocuments, report.Previous, previous.NumDocuments, minimum))
+		}
+	}
+	if schema.Chunking != nil {
+		chunks, err := utilsCall.TypeSenseGetCollection(a.stt, schemas.VersionChunkCollection(schema, report.Version))
+		switch {
+		case err != nil:
+			addCheck("chunks", false, err.Error())
+		case report.Import != nil:
+			addCheck("chunks", report.Import.ChunksFailed == 0,
+				fmt.Sprintf("%d chunks in %s, %d failed", chunks.NumDocuments, chunks.Name, report.Import.ChunksFailed))
+		}
+	}
+	for _, query := range options.SampleQueries {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("query_by", strings.Join(schema.QueryBy, ","))
+		params.Set("per_page", "1")
+		result, err := utilsCall.TypeSenseSearchText(a.stt, a.repo, report.Version, params.Encode())
+		if err != nil {
+			addCheck("query:"+query, false, err.Error())
+			continue
+		}
+		addCheck("query:"+query, result.Found > 0, fmt.Sprintf("%d found", result.Found))
+	}
+	return passed
+}
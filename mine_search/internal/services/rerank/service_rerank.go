@@ -0,0 +1,34 @@
+package rerank
+
+import (
+	"mine/internal/models"
+	"mine/internal/rerank"
+	backend "mine/internal/services/backend"
+	hybrid "mine/internal/services/hybrid"
+	"mine/internal/settings"
+)
+
+// EventRerankService rescores the first hits of a search with the rerank pipeline before they are paged
+type EventRerankService interface {
+	SearchReranked(dataInput models.RerankSearch) (*models.SearchResult, error)
+}
+type eventRerankService struct {
+	stt       *settings.AppSettings
+	backends  *backend.Registry
+	hybridSvc hybrid.EventHybridService
+	rerankers *rerank.Rerankers
+}
+
+func NewRerankService(
+	appSettings *settings.AppSettings,
+	backends *backend.Registry,
+	hybridSvc hybrid.EventHybridService,
+	rerankers *rerank.Rerankers,
+) EventRerankService {
+	return &eventRerankService{
+		stt:       appSettings,
+		backends:  backends,
+		hybridSvc: hybridSvc,
+		rerankers: rerankers,
+	}
+}
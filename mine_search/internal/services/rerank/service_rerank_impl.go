@@ -0,0 +1,108 @@
+package rerank
+
+import (
+	"errors"
+	"fmt"
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/rerank"
+	"mine/internal/schemas"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SearchReranked retrieves the top_n first hits, runs them through the stages of the request or of RERANK_STAGES
+// and answers the page of the new order. A stage that fails is skipped and the result is flagged degraded
+func (a *eventRerankService) SearchReranked(dataInput models.RerankSearch) (*models.SearchResult, error) {
+	start := time.Now()
+	collection := dataInput.Collection
+	if collection == "" {
+		collection = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(collection)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, collection)
+	}
+	if a.rerankers == nil {
+		internal.Log.Error("SearchReranked -> rerankers", zap.Any("collection", collection))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	stages, err := a.rerankers.Stages(dataInput.Stages)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", query_builder.ErrInvalidQuery, err)
+	}
+	topN := dataInput.TopN
+	if topN == 0 {
+		topN = a.stt.Cfgs.RerankTopN
+	}
+	candidates, err := query_builder.Candidates(dataInput.Page, dataInput.PerPage, topN)
+	if err != nil {
+		return nil, err
+	}
+	first, err := a.retrieve(schema.Name, dataInput, candidates)
+	if errors.Is(err, query_builder.ErrInvalidQuery) {
+		return nil, err
+	}
+	if err != nil {
+		internal.Log.Error("SearchReranked -> retrieve", zap.Any("collection", collection), zap.Any("mode", dataInput.Mode), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	hits, passes := rerank.Run(dataInput.Text, schema, first.Hits, stages, dataInput.Debug)
+	result := &models.SearchResult{
+		// only the candidates are reranked, found counts them
+		Found:    int64(len(hits)),
+		Page:     query_builder.CurrentPage(models.Search{Page: dataInput.Page}),
+		PerPage:  query_builder.PerPage(models.Search{PerPage: dataInput.PerPage}),
+		Facets:   []models.FacetResult{},
+		Backend:  first.Backend,
+		Degraded: first.Degraded,
+		Rerank:   passes,
+	}
+	for idx, pass := range passes {
+		if pass.Error != "" {
+			internal.Log.Error("SearchReranked -> Run", zap.Any("collection", collection), zap.Any("stage", pass.Name), zap.Any("error", pass.Error))
+			// the cause is logged, it may name the endpoint of the cross encoder
+			passes[idx].Error = internal.SysStatus.SystemError.Msg
+			result.Degraded = true
+		}
+	}
+	from, to := query_builder.PageRange(result.Page, result.PerPage, len(hits))
+	result.Hits = hits[from:to]
+	result.SearchTimeMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// retrieve runs the first stage of the mode, the candidates best hits
+func (a *eventRerankService) retrieve(collection string, dataInput models.RerankSearch, candidates int) (*models.SearchResult, error) {
+	switch dataInput.Mode {
+	case models.RetrievalVector:
+		return a.backends.SearchVector("", models.VectorSearch{
+			Collection: collection,
+			Text:       dataInput.Text,
+			K:          candidates,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    candidates,
+		})
+	case models.RetrievalHybrid:
+		return a.hybridSvc.SearchHybrid(models.HybridSearch{
+			Collection: collection,
+			Text:       dataInput.Text,
+			Conditions: dataInput.Conditions,
+			Filter:     dataInput.Filter,
+			PerPage:    candidates,
+			Backend:    dataInput.Backend,
+			Fusion:     dataInput.Fusion,
+			Alpha:      dataInput.Alpha,
+		})
+	}
+	return a.backends.Search(dataInput.Backend, models.Search{
+		Collection: collection,
+		Text:       dataInput.Text,
+		Conditions: dataInput.Conditions,
+		Filter:     dataInput.Filter,
+		PerPage:    candidates,
+	})
+}
@@ -1,18 +1,48 @@
 package services
 
 import (
+	"mine/internal"
+	"mine/internal/conversation"
+	"mine/internal/embedding"
+	"mine/internal/llm"
 	"mine/internal/repositories"
+	"mine/internal/rerank"
+	backend "mine/internal/services/backend"
+	bloom "mine/internal/services/bloom"
+	chunk "mine/internal/services/chunk"
+	collection "mine/internal/services/collection"
+	conversationSvc "mine/internal/services/conversation"
+	hybrid "mine/internal/services/hybrid"
+	importer "mine/internal/services/importer"
+	rag "mine/internal/services/rag"
+	redisearch "mine/internal/services/redisearch"
+	reindex "mine/internal/services/reindex"
+	rerankSvc "mine/internal/services/rerank"
 	sample "mine/internal/services/sample"
 	typesense "mine/internal/services/typesense"
 	"mine/internal/settings"
+	"time"
 
 	"github.com/go-redis/redis"
+	"go.uber.org/zap"
 )
 
 // ! Compose interface
 type AppServices struct {
 	sample.EventSampleService
 	typesense.EventTypeSenseService
+	collection.EventCollectionService
+	importer.EventImportService
+	reindex.EventReindexService
+	redisearch.EventRediSearchService
+	bloom.EventBloomService
+	hybrid.EventHybridService
+	rag.EventRAGService
+	chunk.EventChunkService
+	conversationSvc.EventConversationService
+	rerankSvc.EventRerankService
+	// Backends picks the engine of a search by name
+	Backends *backend.Registry
 }
 
 func NewAppServices(
@@ -20,8 +50,67 @@ func NewAppServices(
 	repo *repositories.Repositories,
 	rdbCache *redis.Client,
 ) *AppServices {
+	embedder, err := embedding.New(appSettings)
+	if err != nil {
+		// vector searches and imports of embedding fields fail until the provider is fixed
+		internal.Log.Error("NewAppServices -> embedding.New", zap.Error(err))
+	}
+	importSvc := importer.NewImportService(appSettings, repo, embedder)
+	typeSenseSvc := typesense.NewTypeSenseService(appSettings, repo, embedder)
+	rediSearchSvc := redisearch.NewRediSearchService(appSettings, repo, rdbCache)
+	memory := backend.NewMemoryBackend(appSettings)
+	if dir := appSettings.Cfgs.MemoryDataDir; dir != "" {
+		// the memory backend stays registered without its data, its health then reports it
+		if err := memory.LoadDir(dir); err != nil {
+			internal.Log.Error("NewAppServices -> LoadDir", zap.Any("dir", dir), zap.Error(err))
+		}
+	}
+	vectors := backend.NewHNSWBackend(appSettings, embedder)
+	if dir := appSettings.Cfgs.HNSWDataDir; dir != "" {
+		if err := vectors.LoadDir(dir); err != nil {
+			internal.Log.Error("NewAppServices -> HNSW LoadDir", zap.Any("dir", dir), zap.Error(err))
+		}
+	}
+	registry := backend.NewRegistry(
+		backend.NewTypeSenseBackend(appSettings, typeSenseSvc),
+		backend.NewRedisBackend(rdbCache, rediSearchSvc),
+		backend.NewRedisGeoBackend(rdbCache, rediSearchSvc),
+		memory,
+		backend.NewMySQLBackend(appSettings, repo),
+		vectors,
+	)
+	registry.SetFailover(backend.NewFailover(appSettings, rdbCache))
+	hybridSvc := hybrid.NewHybridService(appSettings, registry)
+	chunkSvc := chunk.NewChunkService(appSettings, registry, hybridSvc)
+	provider, err := llm.New(appSettings)
+	if err != nil {
+		// answers fail until the provider is fixed, searches are not affected
+		internal.Log.Error("NewAppServices -> llm.New", zap.Error(err))
+	}
+	rerankers, err := rerank.New(appSettings)
+	if err != nil {
+		// reranked searches fail until RERANK_STAGES is fixed
+		internal.Log.Error("NewAppServices -> rerank.New", zap.Error(err))
+	}
+	bloomSvc := bloom.NewBloomService(appSettings, rdbCache)
+	if err := bloomSvc.RestoreBlooms(); err != nil {
+		internal.Log.Error("NewAppServices -> RestoreBlooms", zap.Error(err))
+	}
 	return &AppServices{
 		sample.NewSampleService(appSettings, repo),
-		typesense.NewTypeSenseService(appSettings, repo),
+		typeSenseSvc,
+		collection.NewCollectionService(appSettings, repo),
+		importSvc,
+		reindex.NewReindexService(appSettings, repo, importSvc),
+		rediSearchSvc,
+		bloomSvc,
+		hybridSvc,
+		rag.NewRAGService(appSettings, registry, hybridSvc, chunkSvc, provider),
+		chunkSvc,
+		conversationSvc.NewConversationService(appSettings, registry, hybridSvc,
+			conversation.NewStore(rdbCache, time.Duration(appSettings.Cfgs.ConversationTTL)*time.Second, appSettings.Cfgs.ConversationMaxTurns),
+			conversation.NewRewriter(appSettings.Cfgs.ConversationRewriter, provider)),
+		rerankSvc.NewRerankService(appSettings, registry, hybridSvc, rerankers),
+		registry,
 	}
 }
@@ -1,25 +1,33 @@
 package typesense
 
 import (
+	"mine/internal/embedding"
 	"mine/internal/models"
 	"mine/internal/repositories"
 	"mine/internal/settings"
 )
 
 type EventTypeSenseService interface {
-	SearchText(dataInput models.Search) (interface{}, error)
+	SearchText(dataInput models.Search) (*models.SearchResult, error)
+	SearchVector(dataInput models.VectorSearch) (*models.SearchResult, error)
+	// FetchDocuments returns the documents of ids keyed by id, missing ones are left out
+	FetchDocuments(document string, ids []string) (map[string]map[string]interface{}, error)
 }
 type eventTypeSenseService struct {
 	stt  *settings.AppSettings
 	repo *repositories.Repositories
+	// embedder turns the text of vector searches into the query vector
+	embedder embedding.Embedder
 }
 
 func NewTypeSenseService(
 	appSettings *settings.AppSettings,
 	repo *repositories.Repositories,
+	embedder embedding.Embedder,
 ) EventTypeSenseService {
 	return &eventTypeSenseService{
-		stt:  appSettings,
-		repo: repo,
+		stt:      appSettings,
+		repo:     repo,
+		embedder: embedder,
 	}
 }
@@ -2,19 +2,190 @@ package typesense
 
 import (
 	"errors"
+	"fmt"
 	"mine/internal"
+	"mine/internal/embedding"
 	"mine/internal/models"
+	"mine/internal/query_builder"
+	"mine/internal/schemas"
 	utilsCall "mine/internal/utils_call"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 )
 
-func (a *eventTypeSenseService) SearchText(dataInput models.Search) (interface{}, error) {
-	document, query := "", ""
+// SearchText only accepts registered collection names, which are aliases once a collection
+// has been reindexed, so a search never targets a versioned collection such as books_v3
+func (a *eventTypeSenseService) SearchText(dataInput models.Search) (*models.SearchResult, error) {
+	document := dataInput.Collection
+	if document == "" {
+		document = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(document)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, document)
+	}
+	cursor, err := query_builder.ApplyCursor(a.stt.Keys.CursorKey, document, &dataInput)
+	if err != nil {
+		internal.Log.Error("SearchText -> ApplyCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	params, err := query_builder.TypeSenseSearchParams(schema, dataInput)
+	if err != nil {
+		internal.Log.Error("SearchText -> TypeSenseSearchParams", zap.Any("dataInput", dataInput), zap.Error(err))
+		return nil, err
+	}
+	query := params.Encode()
 	result, err := utilsCall.TypeSenseSearchText(a.stt, a.repo, document, query)
 	if err != nil {
 		internal.Log.Error("SearchText -> TypeSenseSearchText", zap.Any("document", document), zap.Any("query", query), zap.Error(err))
+		if utilsCall.IsTypeSenseUnavailable(err) {
+			// kept as is so the backend registry can fail over
+			return nil, err
+		}
 		return nil, errors.New(internal.SysStatus.SystemError.Msg)
 	}
-	return result, nil
+	searchResult := toSearchResult(result)
+	if err := query_builder.CheckCursor(cursor, dataInput, searchResult); err != nil {
+		internal.Log.Error("SearchText -> CheckCursor", zap.Any("cursor", dataInput.Cursor), zap.Error(err))
+		return nil, err
+	}
+	searchResult.NextCursor = query_builder.NextCursor(a.stt.Keys.CursorKey, document, dataInput, searchResult)
+	return searchResult, nil
+}
+
+// SearchVector runs a nearest neighbor query on an embedding field, the text of the request is embedded
+// with the embedder that filled the field at import time
+func (a *eventTypeSenseService) SearchVector(dataInput models.VectorSearch) (*models.SearchResult, error) {
+	document := dataInput.Collection
+	if document == "" {
+		document = schemas.DefaultCollection
+	}
+	schema, ok := schemas.Get(document)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown collection %q", query_builder.ErrInvalidQuery, document)
+	}
+	field, err := query_builder.VectorField(schema, dataInput.Field)
+	if err != nil {
+		return nil, err
+	}
+	vector := dataInput.Vector
+	if len(vector) == 0 {
+		if a.embedder == nil {
+			internal.Log.Error("SearchVector -> embedder", zap.Any("document", document))
+			return nil, errors.New(internal.SysStatus.SystemError.Msg)
+		}
+		vectors, err := a.embedder.Embed([]string{dataInput.Text})
+		if errors.Is(err, embedding.ErrEmptyText) {
+			return nil, fmt.Errorf("%w: %v", query_builder.ErrInvalidQuery, err)
+		}
+		if err != nil {
+			internal.Log.Error("SearchVector -> Embed", zap.Any("text", dataInput.Text), zap.Error(err))
+			return nil, errors.New(internal.SysStatus.SystemError.Msg)
+		}
+		vector = vectors[0]
+	}
+	params, err := query_builder.TypeSenseVectorParams(schema, dataInput, field, vector)
+	if err != nil {
+		internal.Log.Error("SearchVector -> TypeSenseVectorParams", zap.Any("dataInput", dataInput), zap.Error(err))
+		return nil, err
+	}
+	result, err := utilsCall.TypeSenseMultiSearch(a.stt, document, params)
+	if err != nil {
+		internal.Log.Error("SearchVector -> TypeSenseMultiSearch", zap.Any("document", document), zap.Any("field", field.Name), zap.Error(err))
+		if utilsCall.IsTypeSenseUnavailable(err) {
+			return nil, err
+		}
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	return toSearchResult(result), nil
+}
+
+// FetchDocuments filters the collection on the ids, the embedding fields are left out
+func (a *eventTypeSenseService) FetchDocuments(document string, ids []string) (map[string]map[string]interface{}, error) {
+	documents := map[string]map[string]interface{}{}
+	if len(ids) == 0 {
+		return documents, nil
+	}
+	params := map[string]string{
+		"q":         "*",
+		"filter_by": query_builder.TypeSenseInFilter("id", ids),
+		"per_page":  strconv.Itoa(len(ids)),
+	}
+	if schema, ok := schemas.Resolve(document); ok {
+		excluded := []string{}
+		for _, field := range schema.EmbeddingFields() {
+			excluded = append(excluded, field.Name)
+		}
+		if len(excluded) > 0 {
+			params["exclude_fields"] = strings.Join(excluded, ",")
+		}
+	}
+	result, err := utilsCall.TypeSenseMultiSearch(a.stt, document, params)
+	if err != nil {
+		internal.Log.Error("FetchDocuments -> TypeSenseMultiSearch", zap.Any("document", document), zap.Any("ids", ids), zap.Error(err))
+		return nil, errors.New(internal.SysStatus.SystemError.Msg)
+	}
+	for _, hit := range result.Hits {
+		documents[fmt.Sprint(hit.Document["id"])] = hit.Document
+	}
+	return documents, nil
+}
+
+func toSearchResult(resp *utilsCall.TypeSenseSearchResponse) *models.SearchResult {
+	result := &models.SearchResult{
+		Found:        resp.Found,
+		Page:         resp.Page,
+		PerPage:      resp.RequestParams.PerPage,
+		SearchTimeMs: resp.SearchTimeMs,
+		Hits:         make([]models.SearchHit, 0, len(resp.Hits)),
+		Facets:       make([]models.FacetResult, 0, len(resp.FacetCounts)),
+	}
+	for _, hit := range resp.Hits {
+		highlights := map[string][]string{}
+		for _, highlight := range hit.Highlights {
+			if highlight.Snippet != "" {
+				highlights[highlight.Field] = []string{highlight.Snippet}
+				continue
+			}
+			if len(highlight.Snippets) > 0 {
+				highlights[highlight.Field] = highlight.Snippets
+			}
+		}
+		searchHit := models.SearchHit{
+			Document:       hit.Document,
+			TextMatch:      hit.TextMatch,
+			Highlights:     highlights,
+			VectorDistance: hit.VectorDistance,
+		}
+		// a search sorts by one geopoint at most
+		for _, distance := range hit.GeoDistanceMeters {
+			distance := distance
+			searchHit.DistanceMeters = &distance
+		}
+		result.Hits = append(result.Hits, searchHit)
+	}
+	for _, facetCount := range resp.FacetCounts {
+		facet := models.FacetResult{
+			Field:  facetCount.FieldName,
+			Values: make([]models.FacetValue, 0, len(facetCount.Counts)),
+			Stats: &models.FacetStats{
+				Min:         facetCount.Stats.Min,
+				Max:         facetCount.Stats.Max,
+				Avg:         facetCount.Stats.Avg,
+				Sum:         facetCount.Stats.Sum,
+				TotalValues: facetCount.Stats.TotalValues,
+			},
+		}
+		for _, count := range facetCount.Counts {
+			facet.Values = append(facet.Values, models.FacetValue{
+				Value:       count.Value,
+				Count:       count.Count,
+				Highlighted: count.Highlighted,
+			})
+		}
+		result.Facets = append(result.Facets, facet)
+	}
+	return result
 }
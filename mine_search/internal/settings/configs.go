@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"mine/internal"
 	"mine/internal/models"
+	"mine/internal/schemas"
 	"mine/internal/utils"
 	"strconv"
 	"time"
@@ -29,6 +30,66 @@ type Configs struct {
 	UseProduction bool   `mapstructure:"USE_PRODUCTION"`
 	IsDev         bool   `mapstructure:"IS_DEV"`
 	TypeSenseKey  string `mapstructure:"TYPESENSE_KEY"`
+	SchemaDir     string `mapstructure:"SCHEMA_DIR"`
+	RedisAddr     string `mapstructure:"REDIS_ADDR"`
+	// MemoryDataDir holds the snapshot or jsonl files the memory backend loads at startup
+	MemoryDataDir string `mapstructure:"MEMORY_DATA_DIR"`
+	// FailoverSecondaries is the comma separated list tried when a search engine is unavailable
+	FailoverSecondaries string `mapstructure:"FAILOVER_SECONDARIES"`
+	// FailoverCacheTTL is how long, in seconds, answers are kept for the cache secondary
+	FailoverCacheTTL int `mapstructure:"FAILOVER_CACHE_TTL"`
+	// BloomSnapshotStore is file or redis, in-process filters are not persisted when empty
+	BloomSnapshotStore string `mapstructure:"BLOOM_SNAPSHOT_STORE"`
+	BloomSnapshotDir   string `mapstructure:"BLOOM_SNAPSHOT_DIR"`
+	// BloomSnapshotInterval is in seconds, 300 by default
+	BloomSnapshotInterval int `mapstructure:"BLOOM_SNAPSHOT_INTERVAL"`
+	// BloomRebuildInterval is in seconds, filters are not rebuilt from their collection when 0
+	BloomRebuildInterval int `mapstructure:"BLOOM_REBUILD_INTERVAL"`
+	// EmbeddingProvider is hash (default, local) or http, EmbeddingDim must match num_dim of the embedding fields
+	EmbeddingProvider string `mapstructure:"EMBEDDING_PROVIDER"`
+	EmbeddingURL      string `mapstructure:"EMBEDDING_URL"`
+	EmbeddingAPIKey   string `mapstructure:"EMBEDDING_API_KEY"`
+	EmbeddingModel    string `mapstructure:"EMBEDDING_MODEL"`
+	EmbeddingDim      int    `mapstructure:"EMBEDDING_DIM"`
+	// HybridFusion is rrf (default) or weighted, HybridAlpha the weight of the vector search, 0.5 when unset
+	// and nil only then, 0 ranks by keywords. A hybrid request overrides both
+	HybridFusion string   `mapstructure:"HYBRID_FUSION"`
+	HybridAlpha  *float64 `mapstructure:"HYBRID_ALPHA"`
+	// LLMProvider is template (default, local, no model) or http, LLMMaxTokens bounds the answers
+	LLMProvider  string `mapstructure:"LLM_PROVIDER"`
+	LLMURL       string `mapstructure:"LLM_URL"`
+	LLMAPIKey    string `mapstructure:"LLM_API_KEY"`
+	LLMModel     string `mapstructure:"LLM_MODEL"`
+	LLMMaxTokens int    `mapstructure:"LLM_MAX_TOKENS"`
+	// RAGContextTokens bounds the documents given to the model for one question, 2000 when unset
+	RAGContextTokens int `mapstructure:"RAG_CONTEXT_TOKENS"`
+	// ConversationTTL is in seconds, 1800 by default, ConversationMaxTurns the turns kept per session, 10 by default
+	ConversationTTL      int `mapstructure:"CONVERSATION_TTL"`
+	ConversationMaxTurns int `mapstructure:"CONVERSATION_MAX_TURNS"`
+	// ConversationRewriter is rule (default) or llm, llm needs the http LLM_PROVIDER
+	ConversationRewriter string `mapstructure:"CONVERSATION_REWRITER"`
+	// HNSWDataDir holds the graphs and jsonl files the hnsw backend loads at startup
+	HNSWDataDir string `mapstructure:"HNSW_DATA_DIR"`
+	// HNSWM, HNSWEfConstruction and HNSWEfSearch tune the graphs, 16, 200 and 64 when unset.
+	// HNSWMetric is cosine (default), dot or l2
+	HNSWM              int    `mapstructure:"HNSW_M"`
+	HNSWEfConstruction int    `mapstructure:"HNSW_EF_CONSTRUCTION"`
+	HNSWEfSearch       int    `mapstructure:"HNSW_EF_SEARCH"`
+	HNSWMetric         string `mapstructure:"HNSW_METRIC"`
+	// RerankStages is the default rerank pipeline, name[:weight[:top_n]] comma separated,
+	// RerankTopN the first-stage hits rescored, 50 by default
+	RerankStages string `mapstructure:"RERANK_STAGES"`
+	RerankTopN   int    `mapstructure:"RERANK_TOP_N"`
+	// RerankURL is the /rerank endpoint of the cross_encoder reranker, it is not available without it
+	RerankURL    string `mapstructure:"RERANK_URL"`
+	RerankAPIKey string `mapstructure:"RERANK_API_KEY"`
+	RerankModel  string `mapstructure:"RERANK_MODEL"`
+	// RerankFreshnessField holds the year of a document, RerankHalfLife is in years, RerankPopularityField a count
+	RerankFreshnessField  string  `mapstructure:"RERANK_FRESHNESS_FIELD"`
+	RerankHalfLife        float64 `mapstructure:"RERANK_HALF_LIFE"`
+	RerankPopularityField string  `mapstructure:"RERANK_POPULARITY_FIELD"`
+	// CursorKey signs search cursors, required in production, every instance behind the load balancer must share it
+	CursorKey string `mapstructure:"CURSOR_KEY"`
 }
 type DateTimeLayout struct {
 	YMD     string
@@ -37,6 +98,8 @@ type DateTimeLayout struct {
 }
 type AppKeys struct {
 	AuthClientKey string
+	// CursorKey signs search cursors, every instance behind the load balancer must share it
+	CursorKey string
 }
 type AppSettings struct {
 	Cfgs              *Configs
@@ -136,6 +199,45 @@ func NewAppConfigs() (configs *Configs, err error) {
 		if !check {
 			return nil, errors.New("Config missing variable")
 		}
+		schemaDir, _ := utils.GetDefaultEnv("SCHEMA_DIR", "")
+		redisAddr, _ := utils.GetDefaultEnv("REDIS_ADDR", "")
+		memoryDataDir, _ := utils.GetDefaultEnv("MEMORY_DATA_DIR", "")
+		failoverSecondaries, _ := utils.GetDefaultEnv("FAILOVER_SECONDARIES", "")
+		failoverCacheTTL, _ := utils.GetDefaultEnv("FAILOVER_CACHE_TTL", "0")
+		bloomSnapshotStore, _ := utils.GetDefaultEnv("BLOOM_SNAPSHOT_STORE", "")
+		bloomSnapshotDir, _ := utils.GetDefaultEnv("BLOOM_SNAPSHOT_DIR", "")
+		bloomSnapshotInterval, _ := utils.GetDefaultEnv("BLOOM_SNAPSHOT_INTERVAL", "0")
+		bloomRebuildInterval, _ := utils.GetDefaultEnv("BLOOM_REBUILD_INTERVAL", "0")
+		embeddingProvider, _ := utils.GetDefaultEnv("EMBEDDING_PROVIDER", "")
+		embeddingURL, _ := utils.GetDefaultEnv("EMBEDDING_URL", "")
+		embeddingAPIKey, _ := utils.GetDefaultEnv("EMBEDDING_API_KEY", "")
+		embeddingModel, _ := utils.GetDefaultEnv("EMBEDDING_MODEL", "")
+		embeddingDim, _ := utils.GetDefaultEnv("EMBEDDING_DIM", "0")
+		hybridFusion, _ := utils.GetDefaultEnv("HYBRID_FUSION", "")
+		hybridAlpha, hybridAlphaSet := utils.GetDefaultEnv("HYBRID_ALPHA", "")
+		llmProvider, _ := utils.GetDefaultEnv("LLM_PROVIDER", "")
+		llmURL, _ := utils.GetDefaultEnv("LLM_URL", "")
+		llmAPIKey, _ := utils.GetDefaultEnv("LLM_API_KEY", "")
+		llmModel, _ := utils.GetDefaultEnv("LLM_MODEL", "")
+		llmMaxTokens, _ := utils.GetDefaultEnv("LLM_MAX_TOKENS", "0")
+		ragContextTokens, _ := utils.GetDefaultEnv("RAG_CONTEXT_TOKENS", "0")
+		conversationTTL, _ := utils.GetDefaultEnv("CONVERSATION_TTL", "0")
+		conversationMaxTurns, _ := utils.GetDefaultEnv("CONVERSATION_MAX_TURNS", "0")
+		conversationRewriter, _ := utils.GetDefaultEnv("CONVERSATION_REWRITER", "")
+		hnswDataDir, _ := utils.GetDefaultEnv("HNSW_DATA_DIR", "")
+		hnswM, _ := utils.GetDefaultEnv("HNSW_M", "0")
+		hnswEfConstruction, _ := utils.GetDefaultEnv("HNSW_EF_CONSTRUCTION", "0")
+		hnswEfSearch, _ := utils.GetDefaultEnv("HNSW_EF_SEARCH", "0")
+		hnswMetric, _ := utils.GetDefaultEnv("HNSW_METRIC", "")
+		rerankStages, _ := utils.GetDefaultEnv("RERANK_STAGES", "")
+		rerankTopN, _ := utils.GetDefaultEnv("RERANK_TOP_N", "0")
+		rerankURL, _ := utils.GetDefaultEnv("RERANK_URL", "")
+		rerankAPIKey, _ := utils.GetDefaultEnv("RERANK_API_KEY", "")
+		rerankModel, _ := utils.GetDefaultEnv("RERANK_MODEL", "")
+		rerankFreshnessField, _ := utils.GetDefaultEnv("RERANK_FRESHNESS_FIELD", "")
+		rerankHalfLife, _ := utils.GetDefaultEnv("RERANK_HALF_LIFE", "0")
+		rerankPopularityField, _ := utils.GetDefaultEnv("RERANK_POPULARITY_FIELD", "")
+		cursorKey, _ := utils.GetDefaultEnv("CURSOR_KEY", "")
 		IsDev, check := utils.GetDefaultEnv("IS_DEV", "")
 		if !check {
 			IsDev = "0"
@@ -161,6 +263,47 @@ func NewAppConfigs() (configs *Configs, err error) {
 		configs.User = db_user
 		configs.Password = db_password
 		configs.TypeSenseKey = typesenseKey
+		configs.SchemaDir = schemaDir
+		configs.RedisAddr = redisAddr
+		configs.MemoryDataDir = memoryDataDir
+		configs.FailoverSecondaries = failoverSecondaries
+		configs.FailoverCacheTTL, _ = strconv.Atoi(failoverCacheTTL)
+		configs.BloomSnapshotStore = bloomSnapshotStore
+		configs.BloomSnapshotDir = bloomSnapshotDir
+		configs.BloomSnapshotInterval, _ = strconv.Atoi(bloomSnapshotInterval)
+		configs.BloomRebuildInterval, _ = strconv.Atoi(bloomRebuildInterval)
+		configs.EmbeddingProvider = embeddingProvider
+		configs.EmbeddingURL = embeddingURL
+		configs.EmbeddingAPIKey = embeddingAPIKey
+		configs.EmbeddingModel = embeddingModel
+		configs.EmbeddingDim, _ = strconv.Atoi(embeddingDim)
+		configs.HybridFusion = hybridFusion
+		if alpha, err := strconv.ParseFloat(hybridAlpha, 64); hybridAlphaSet && err == nil {
+			configs.HybridAlpha = &alpha
+		}
+		configs.LLMProvider = llmProvider
+		configs.LLMURL = llmURL
+		configs.LLMAPIKey = llmAPIKey
+		configs.LLMModel = llmModel
+		configs.LLMMaxTokens, _ = strconv.Atoi(llmMaxTokens)
+		configs.RAGContextTokens, _ = strconv.Atoi(ragContextTokens)
+		configs.ConversationTTL, _ = strconv.Atoi(conversationTTL)
+		configs.ConversationMaxTurns, _ = strconv.Atoi(conversationMaxTurns)
+		configs.ConversationRewriter = conversationRewriter
+		configs.HNSWDataDir = hnswDataDir
+		configs.HNSWM, _ = strconv.Atoi(hnswM)
+		configs.HNSWEfConstruction, _ = strconv.Atoi(hnswEfConstruction)
+		configs.HNSWEfSearch, _ = strconv.Atoi(hnswEfSearch)
+		configs.HNSWMetric = hnswMetric
+		configs.RerankStages = rerankStages
+		configs.RerankTopN, _ = strconv.Atoi(rerankTopN)
+		configs.RerankURL = rerankURL
+		configs.RerankAPIKey = rerankAPIKey
+		configs.RerankModel = rerankModel
+		configs.RerankFreshnessField = rerankFreshnessField
+		configs.RerankHalfLife, _ = strconv.ParseFloat(rerankHalfLife, 64)
+		configs.RerankPopularityField = rerankPopularityField
+		configs.CursorKey = cursorKey
 		if use_product == 0 {
 			configs.UseProduction = false
 		} else {
@@ -191,9 +334,13 @@ func NewDateTimeLayout() *DateTimeLayout {
 	}
 }
 
-func NewAppKeys(useProduction bool) *AppKeys {
+func NewAppKeys(useProduction bool, cursorKey string) *AppKeys {
 	result := &AppKeys{
 		AuthClientKey: "Give me the keys",
+		CursorKey:     "Keep my place",
+	}
+	if cursorKey != "" {
+		result.CursorKey = cursorKey
 	}
 	if useProduction {
 	}
@@ -227,6 +374,17 @@ func NewAppSettings() *AppSettings {
 		fmt.Printf("NewAppSettings err: %v\n", err)
 		return nil
 	}
+	if appConfigs.SchemaDir != "" {
+		if err := schemas.LoadDir(appConfigs.SchemaDir); err != nil {
+			fmt.Printf("NewAppSettings load schemas err: %v\n", err)
+			return nil
+		}
+	}
+	if appConfigs.UseProduction && appConfigs.CursorKey == "" {
+		// cursors signed with the default key could be forged by anyone reading this code
+		fmt.Println("NewAppSettings err: CURSOR_KEY is required in production")
+		return nil
+	}
 	models.Validate = validator.New()
 	utils.Pool = threadpool.NewThreadPool(50, 100000)
 	fmt.Println("NewAppSettings SUCCESS")
@@ -236,7 +394,7 @@ func NewAppSettings() *AppSettings {
 		// AppUrls:           NewAppUrls(appConfigs.UseProduction),
 		// Endp:              NewAppEndpoints(appConfigs.UseProduction),
 		// Ports:             NewServicesPorts(),
-		Keys:              NewAppKeys(appConfigs.UseProduction),
+		Keys:              NewAppKeys(appConfigs.UseProduction, appConfigs.CursorKey),
 		Regexs:            NewRegexs(),
 		Log:               internal.TempLog{},
 		Logger:            NewLogger(),
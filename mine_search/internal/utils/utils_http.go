@@ -38,6 +38,32 @@ func Request(url string, isGet bool, headers, param map[string]string, body map[
 	return req.Post(url)
 }
 
+// RequestWithMethod is Request for any http method, body may be any json serializable value
+func RequestWithMethod(method, url string, headers, param map[string]string, body interface{}, timeout int, proxy bool) (*resty.Response, error) {
+	now := GetTimeUTC7()
+	defer func() { fmt.Printf("ExecTime url=%s, dt=%v \n", url, GetTimeUTC7().Sub(now).Milliseconds()) }()
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if param == nil {
+		param = map[string]string{}
+	}
+	_, ok := headers["Content-Type"]
+	if !ok {
+		headers["Content-Type"] = "application/json"
+	}
+	client := resty.New()
+	client.SetTimeout(time.Second * time.Duration(timeout))
+	if proxy {
+		client.SetProxy("http://proxy:80")
+	}
+	req := client.R().SetHeaders(headers).SetQueryParams(param)
+	if body != nil {
+		req.SetBody(body)
+	}
+	return req.Execute(method, url)
+}
+
 func ResponseString(resp *resty.Response) interface{} {
 	if resp == nil {
 		return nil
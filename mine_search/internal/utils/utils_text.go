@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Tokenize splits text into lower cased words, punctuation and spaces separate words
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) })
+	for idx := range words {
+		words[idx] = strings.ToLower(words[idx])
+	}
+	return words
+}
+
+// Fold drops the accents of a lower cased token, Vietnamese đ becomes d
+func Fold(token string) string {
+	var builder strings.Builder
+	for _, r := range norm.NFD.String(token) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r == 'đ' {
+			r = 'd'
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// HighlightTokens wraps every word of text that starts with one of tokens in <mark></mark>,
+// matched reports whether any word was marked
+func HighlightTokens(text string, tokens []string) (string, bool) {
+	if len(tokens) == 0 {
+		return text, false
+	}
+	var builder strings.Builder
+	matched := false
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			builder.WriteRune(runes[start])
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[start:end])
+		lower := strings.ToLower(word)
+		marked := false
+		for _, token := range tokens {
+			if token != "" && strings.HasPrefix(lower, token) {
+				marked = true
+				break
+			}
+		}
+		if marked {
+			matched = true
+			builder.WriteString("<mark>" + word + "</mark>")
+		} else {
+			builder.WriteString(word)
+		}
+		start = end
+	}
+	return builder.String(), matched
+}
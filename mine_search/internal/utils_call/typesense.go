@@ -2,16 +2,64 @@ package utils_call
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"mine/internal"
 	"mine/internal/repositories"
 	"mine/internal/settings"
 	"mine/internal/utils"
+	"net"
 	"strings"
 
 	"go.uber.org/zap"
 )
 
-func TypeSenseSearchText(s *settings.AppSettings, repo *repositories.Repositories, document, query string) (map[string]interface{}, error) {
+type (
+	TypeSenseFacetCount struct {
+		FieldName string `json:"field_name"`
+		Counts    []struct {
+			Count       int64  `json:"count"`
+			Highlighted string `json:"highlighted"`
+			Value       string `json:"value"`
+		} `json:"counts"`
+		Stats struct {
+			Min         *float64 `json:"min"`
+			Max         *float64 `json:"max"`
+			Avg         *float64 `json:"avg"`
+			Sum         *float64 `json:"sum"`
+			TotalValues int64    `json:"total_values"`
+		} `json:"stats"`
+	}
+	// TypeSenseHighlight carries Snippet for string fields and Snippets for string[] fields
+	TypeSenseHighlight struct {
+		Field    string   `json:"field"`
+		Snippet  string   `json:"snippet"`
+		Snippets []string `json:"snippets"`
+	}
+	TypeSenseHit struct {
+		Document   map[string]interface{} `json:"document"`
+		TextMatch  float64                `json:"text_match"`
+		Highlights []TypeSenseHighlight   `json:"highlights"`
+		// GeoDistanceMeters is keyed by geopoint field when sort_by has a geo sort
+		GeoDistanceMeters map[string]float64 `json:"geo_distance_meters"`
+		// VectorDistance is set by vector queries
+		VectorDistance *float64 `json:"vector_distance"`
+	}
+	// TypeSenseSearchResponse is the answer of /documents/search
+	TypeSenseSearchResponse struct {
+		Found         int64                 `json:"found"`
+		OutOf         int64                 `json:"out_of"`
+		Page          int                   `json:"page"`
+		SearchTimeMs  int64                 `json:"search_time_ms"`
+		Hits          []TypeSenseHit        `json:"hits"`
+		FacetCounts   []TypeSenseFacetCount `json:"facet_counts"`
+		RequestParams struct {
+			PerPage int `json:"per_page"`
+		} `json:"request_params"`
+	}
+)
+
+func TypeSenseSearchText(s *settings.AppSettings, repo *repositories.Repositories, document, query string) (*TypeSenseSearchResponse, error) {
 	url := internal.Domains.TypeSense + strings.ReplaceAll(internal.Endpoints.TypeSense.TextSearch, "{{document}}", document) + "?" + query
 	headers := map[string]string{
 		"Content-type":        "application/json",
@@ -26,13 +74,103 @@ func TypeSenseSearchText(s *settings.AppSettings, repo *repositories.Repositorie
 	}
 	internal.Log.Info("Response", zap.Any("url", url), zap.Any("header", headers), zap.Any("input", input), zap.Any("response", resp.String()))
 	if resp.StatusCode() != 200 {
-		return nil, err
+		return nil, &TypeSenseError{StatusCode: resp.StatusCode(), Message: "search " + document}
 	}
-	res := map[string]interface{}{}
-	err = json.Unmarshal([]byte(resp.String()), &res)
+	res := &TypeSenseSearchResponse{}
+	err = json.Unmarshal([]byte(resp.String()), res)
 	if err != nil {
 		internal.Log.Error("Error Unmarshal", zap.Any("res", res), zap.Error(err))
 		return nil, err
 	}
 	return res, nil
 }
+
+// typeSenseMultiSearchResult is a search answer, or the error of that search in Code and Error
+type typeSenseMultiSearchResult struct {
+	TypeSenseSearchResponse
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+}
+
+// TypeSenseMultiSearch sends the parameters of one search in the body of /multi_search, for vector queries
+// too long for the query string of /documents/search
+func TypeSenseMultiSearch(s *settings.AppSettings, document string, params map[string]string) (*TypeSenseSearchResponse, error) {
+	search := map[string]string{"collection": document}
+	for key, value := range params {
+		search[key] = value
+	}
+	output := struct {
+		Results []typeSenseMultiSearchResult `json:"results"`
+	}{}
+	body := map[string]interface{}{"searches": []map[string]string{search}}
+	if err := typeSenseCall(s, internal.RequestMethod.POST, internal.Endpoints.TypeSense.MultiSearch, body, &output); err != nil {
+		return nil, err
+	}
+	if len(output.Results) != 1 {
+		return nil, fmt.Errorf("typesense answered %d results for one search", len(output.Results))
+	}
+	if result := output.Results[0]; result.Code != 0 && result.Code != 200 {
+		return nil, &TypeSenseError{StatusCode: result.Code, Message: result.Error}
+	}
+	return &output.Results[0].TypeSenseSearchResponse, nil
+}
+
+// TypeSenseError is returned when Typesense answers with a non 2xx status
+type TypeSenseError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *TypeSenseError) Error() string {
+	return fmt.Sprintf("typesense http status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsTypeSenseUnavailable reports whether err is a timeout, a refused connection or a 5xx answer,
+// the failures another engine can answer instead of Typesense
+func IsTypeSenseUnavailable(err error) bool {
+	var tsErr *TypeSenseError
+	if errors.As(err, &tsErr) {
+		return tsErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func IsTypeSenseNotFound(err error) bool {
+	var tsErr *TypeSenseError
+	return errors.As(err, &tsErr) && tsErr.StatusCode == 404
+}
+
+// typeSenseCall sends body as json to path and decodes the json answer into output when it is not nil
+func typeSenseCall(s *settings.AppSettings, method, path string, body interface{}, output interface{}) error {
+	url := internal.Domains.TypeSense + path
+	headers := map[string]string{
+		"Content-type":        "application/json",
+		"X-TYPESENSE-API-KEY": s.Cfgs.TypeSenseKey,
+	}
+	internal.Log.Info("Call "+url, zap.Any("method", method), zap.Any("input", body))
+	resp, err := utils.RequestWithMethod(method, url, headers, nil, body, 30, false)
+	if err != nil {
+		internal.Log.Error("Call "+url, zap.Any("method", method), zap.Any("input", body), zap.Error(err))
+		return err
+	}
+	internal.Log.Info("Response", zap.Any("url", url), zap.Any("method", method), zap.Any("response", resp.String()))
+	if resp.StatusCode() < 200 || resp.StatusCode() > 299 {
+		tsErr := &TypeSenseError{StatusCode: resp.StatusCode()}
+		message := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(resp.Body(), &message) == nil {
+			tsErr.Message = message.Message
+		}
+		return tsErr
+	}
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body(), output); err != nil {
+		internal.Log.Error("Error Unmarshal", zap.Any("resp", resp.String()), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package utils_call
+
+import (
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/settings"
+	"strings"
+)
+
+func typeSenseAliasPath(name string) string {
+	return strings.ReplaceAll(internal.Endpoints.TypeSense.Alias, "{{alias}}", name)
+}
+
+func TypeSenseGetAlias(s *settings.AppSettings, name string) (*models.CollectionAlias, error) {
+	result := &models.CollectionAlias{}
+	if err := typeSenseCall(s, internal.RequestMethod.GET, typeSenseAliasPath(name), nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func TypeSenseListAliases(s *settings.AppSettings) ([]models.CollectionAlias, error) {
+	result := struct {
+		Aliases []models.CollectionAlias `json:"aliases"`
+	}{}
+	err := typeSenseCall(s, internal.RequestMethod.GET, internal.Endpoints.TypeSense.Aliases, nil, &result)
+	return result.Aliases, err
+}
+
+// TypeSenseUpsertAlias points alias to collection, Typesense switches it atomically
+func TypeSenseUpsertAlias(s *settings.AppSettings, name, collection string) (*models.CollectionAlias, error) {
+	body := map[string]interface{}{
+		"collection_name": collection,
+	}
+	result := &models.CollectionAlias{}
+	if err := typeSenseCall(s, internal.RequestMethod.PUT, typeSenseAliasPath(name), body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func TypeSenseDeleteAlias(s *settings.AppSettings, name string) (*models.CollectionAlias, error) {
+	result := &models.CollectionAlias{}
+	if err := typeSenseCall(s, internal.RequestMethod.DELETE, typeSenseAliasPath(name), nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
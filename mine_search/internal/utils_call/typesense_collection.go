@@ -0,0 +1,46 @@
+package utils_call
+
+import (
+	"mine/internal"
+	"mine/internal/models"
+	"mine/internal/settings"
+	"strings"
+)
+
+func typeSenseCollectionPath(name string) string {
+	return strings.ReplaceAll(internal.Endpoints.TypeSense.Collection, "{{document}}", name)
+}
+
+func TypeSenseListCollections(s *settings.AppSettings) ([]models.CollectionInfo, error) {
+	result := []models.CollectionInfo{}
+	err := typeSenseCall(s, internal.RequestMethod.GET, internal.Endpoints.TypeSense.Collections, nil, &result)
+	return result, err
+}
+
+func TypeSenseGetCollection(s *settings.AppSettings, name string) (*models.CollectionInfo, error) {
+	result := &models.CollectionInfo{}
+	if err := typeSenseCall(s, internal.RequestMethod.GET, typeSenseCollectionPath(name), nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func TypeSenseCreateCollection(s *settings.AppSettings, schema *models.CollectionSchema) (*models.CollectionInfo, error) {
+	result := &models.CollectionInfo{}
+	if err := typeSenseCall(s, internal.RequestMethod.POST, internal.Endpoints.TypeSense.Collections, schema, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TypeSenseUpdateCollection patches the field list, a field with Drop set is removed
+func TypeSenseUpdateCollection(s *settings.AppSettings, name string, fields []models.CollectionFieldPatch) error {
+	body := map[string]interface{}{
+		"fields": fields,
+	}
+	return typeSenseCall(s, internal.RequestMethod.PATCH, typeSenseCollectionPath(name), body, nil)
+}
+
+func TypeSenseDropCollection(s *settings.AppSettings, name string) error {
+	return typeSenseCall(s, internal.RequestMethod.DELETE, typeSenseCollectionPath(name), nil, nil)
+}
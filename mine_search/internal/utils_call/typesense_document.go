@@ -0,0 +1,110 @@
+package utils_call
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mine/internal"
+	"mine/internal/settings"
+	"mine/internal/utils"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+type TypeSenseImportResult struct {
+	Success  bool   `json:"success"`
+	Error    string `json:"error"`
+	Document string `json:"document"`
+}
+
+// TypeSenseImportDocuments sends jsonl documents, Typesense answers one result per line in the same order
+func TypeSenseImportDocuments(s *settings.AppSettings, collection, action string, documents []byte) ([]TypeSenseImportResult, error) {
+	url := internal.Domains.TypeSense + strings.ReplaceAll(internal.Endpoints.TypeSense.Import, "{{document}}", collection)
+	headers := map[string]string{
+		"Content-Type":        "text/plain",
+		"X-TYPESENSE-API-KEY": s.Cfgs.TypeSenseKey,
+	}
+	param := map[string]string{
+		"action": action,
+	}
+	internal.Log.Info("Call "+url, zap.Any("param", param), zap.Any("bytes", len(documents)))
+	resp, err := utils.RequestWithMethod(internal.RequestMethod.POST, url, headers, param, documents, 120, false)
+	if err != nil {
+		internal.Log.Error("Call "+url, zap.Any("param", param), zap.Error(err))
+		return nil, err
+	}
+	if resp.StatusCode() != 200 {
+		internal.Log.Error("Response", zap.Any("url", url), zap.Any("response", resp.String()))
+		return nil, &TypeSenseError{StatusCode: resp.StatusCode(), Message: resp.String()}
+	}
+	results := []TypeSenseImportResult{}
+	scanner := bufio.NewScanner(bytes.NewReader(resp.Body()))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		result := TypeSenseImportResult{}
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return nil, fmt.Errorf("typesense import answer: %w", err)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+// TypeSenseExportDocuments streams every document of collection as jsonl, the caller closes the reader
+func TypeSenseExportDocuments(s *settings.AppSettings, collection string) (io.ReadCloser, error) {
+	url := internal.Domains.TypeSense + strings.ReplaceAll(internal.Endpoints.TypeSense.Export, "{{document}}", collection)
+	internal.Log.Info("Call " + url)
+	resp, err := resty.New().R().
+		SetHeader("X-TYPESENSE-API-KEY", s.Cfgs.TypeSenseKey).
+		SetDoNotParseResponse(true).
+		Get(url)
+	if err != nil {
+		internal.Log.Error("Call "+url, zap.Error(err))
+		return nil, err
+	}
+	if resp.StatusCode() != 200 {
+		body := resp.RawBody()
+		defer body.Close()
+		message, _ := io.ReadAll(io.LimitReader(body, 4096))
+		return nil, &TypeSenseError{StatusCode: resp.StatusCode(), Message: string(message)}
+	}
+	return resp.RawBody(), nil
+}
+
+// TypeSenseDeleteDocument removes one document, a missing document answers a 404 TypeSenseError
+func TypeSenseDeleteDocument(s *settings.AppSettings, collection, id string) error {
+	path := strings.ReplaceAll(internal.Endpoints.TypeSense.Document, "{{document}}", collection)
+	path = strings.ReplaceAll(path, "{{id}}", url.PathEscape(id))
+	return typeSenseCall(s, internal.RequestMethod.DELETE, path, nil, nil)
+}
+
+// TypeSenseDeleteDocuments removes every document matching filterBy, it returns how many were removed
+func TypeSenseDeleteDocuments(s *settings.AppSettings, collection, filterBy string) (int, error) {
+	path := strings.ReplaceAll(internal.Endpoints.TypeSense.Documents, "{{document}}", collection)
+	result := struct {
+		NumDeleted int `json:"num_deleted"`
+	}{}
+	if err := typeSenseCall(s, internal.RequestMethod.DELETE, path+"?filter_by="+url.QueryEscape(filterBy), nil, &result); err != nil {
+		return 0, err
+	}
+	return result.NumDeleted, nil
+}
+
+func TypeSenseHealth(s *settings.AppSettings) error {
+	result := struct {
+		Ok bool `json:"ok"`
+	}{}
+	if err := typeSenseCall(s, internal.RequestMethod.GET, internal.Endpoints.TypeSense.Health, nil, &result); err != nil {
+		return err
+	}
+	if !result.Ok {
+		return errors.New("typesense is not ready")
+	}
+	return nil
+}